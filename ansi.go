@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Set once flags are parsed: useColor is off with -no-color or $NO_COLOR,
+// and renderAnswers formats Markdown answers for the terminal with -render
+var (
+	useColor      = true
+	renderAnswers = false
+)
+
+const (
+	ansiReset     = "\033[0m"
+	ansiBold      = "\033[1m"
+	ansiDim       = "\033[2m"
+	ansiItalic    = "\033[3m"
+	ansiUnderline = "\033[4m"
+	ansiNoBold    = "\033[22m"
+	ansiNoItalic  = "\033[23m"
+	ansiYellow    = "\033[33m"
+	ansiBlue      = "\033[34m"
+	ansiCyan      = "\033[36m"
+)
+
+var (
+	ansiBullet   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	ansiNumbered = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	ansiQuote    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	ansiRule     = regexp.MustCompile(`^\s*([-*_]\s*){3,}$`)
+)
+
+// answerText returns a Markdown answer as it should be printed: rendered
+// with -render, otherwise unchanged
+func answerText(text string) string {
+	if !renderAnswers {
+		return text
+	}
+	return renderMarkdownANSI(text)
+}
+
+// renderMarkdownANSI formats the Markdown the model writes for a terminal:
+// bold headings, colored code, bullets indented by nesting, and links
+// followed by their URL
+func renderMarkdownANSI(text string) string {
+	var b strings.Builder
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			b.WriteString("    " + ansiYellow + line + ansiReset + "\n")
+			continue
+		}
+
+		switch {
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			style := ansiBold + ansiCyan
+			if len(m[1]) == 1 {
+				style += ansiUnderline
+			}
+			b.WriteString(style + ansiInline(m[2]) + ansiReset + "\n")
+		case ansiRule.MatchString(line):
+			b.WriteString(ansiDim + strings.Repeat("─", 40) + ansiReset + "\n")
+		case ansiBullet.MatchString(line):
+			m := ansiBullet.FindStringSubmatch(line)
+			fmt.Fprintf(&b, "%s• %s\n", listIndent(m[1]), ansiInline(m[2]))
+		case ansiNumbered.MatchString(line):
+			m := ansiNumbered.FindStringSubmatch(line)
+			fmt.Fprintf(&b, "%s%s %s\n", listIndent(m[1]), m[2], ansiInline(m[3]))
+		case ansiQuote.MatchString(line):
+			b.WriteString(ansiDim + "│ " + ansiReset + ansiInline(ansiQuote.FindStringSubmatch(line)[1]) + "\n")
+		default:
+			b.WriteString(ansiInline(line) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// listIndent indents a list item two spaces per nesting level, counting
+// the two or more spaces the item was indented by as one level
+func listIndent(leading string) string {
+	level := len(strings.ReplaceAll(leading, "\t", "  ")) / 2
+	return strings.Repeat("  ", level+1)
+}
+
+// ansiInline formats the links, code spans and emphasis of a line. Links
+// go first, since the escape codes added later contain brackets.
+func ansiInline(line string) string {
+	line = mdLink.ReplaceAllString(line, ansiUnderline+"$1"+ansiReset+" ("+ansiBlue+"$2"+ansiReset+")")
+	line = mdCode.ReplaceAllString(line, ansiYellow+"$1"+ansiReset)
+	line = mdBold.ReplaceAllString(line, ansiBold+"$1"+ansiNoBold)
+	line = mdItalic.ReplaceAllString(line, "$1"+ansiItalic+"$2"+ansiNoItalic)
+	return line
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Key rotation strategies for -key-rotation
+const (
+	rotationFailover   = "failover"
+	rotationRoundRobin = "round-robin"
+)
+
+// Header the genai client sends the Gemini API key in
+const geminiKeyHeader = "x-goog-api-key"
+
+// keyRing hands out Gemini API keys. With failover every request uses the
+// current key until it hits its quota; with round-robin each request takes
+// the next key in turn.
+type keyRing struct {
+	keys     []string
+	rotation string
+
+	mu      sync.Mutex
+	current int
+}
+
+func newKeyRing(keys []string, rotation string) *keyRing {
+	return &keyRing{keys: keys, rotation: rotation}
+}
+
+// pick returns the key for a new request and its index
+func (r *keyRing) pick() (int, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.current
+	if r.rotation == rotationRoundRobin {
+		r.current = (r.current + 1) % len(r.keys)
+	}
+	return i, r.keys[i]
+}
+
+// exhausted records that key i hit its quota and returns the key to try
+// next. Failover moves every later request off key i as well.
+func (r *keyRing) exhausted(i int) (int, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := (i + 1) % len(r.keys)
+	if r.rotation == rotationFailover && r.current == i {
+		r.current = next
+	}
+	return next, r.keys[next]
+}
+
+// configuredAPIKeys returns the -api-key keys, or those listed in
+// GOSEARCH_API_KEYS separated by commas
+func configuredAPIKeys(config *Config) []string {
+	if len(config.apiKeys) > 0 {
+		return config.apiKeys
+	}
+	var keys []string
+	for _, key := range strings.Split(os.Getenv("GOSEARCH_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// maskKey keeps only the ends of a key, enough to tell keys apart in logs
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "..." + key[len(key)-4:]
+}
+
+// keyTransport sends each Gemini request with a key from the ring and
+// retries a request rejected with 429 on the remaining keys
+type keyTransport struct {
+	keys *keyRing
+	base http.RoundTripper
+}
+
+func (t *keyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Only Gemini requests carry the key header
+	if req.Header.Get(geminiKeyHeader) == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	i, key := t.keys.pick()
+	for tries := 1; ; tries++ {
+		attempt := req.Clone(req.Context())
+		attempt.Header.Set(geminiKeyHeader, key)
+		if tries > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attempt)
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || tries == len(t.keys.keys) || !replayable {
+			return resp, err
+		}
+		resp.Body.Close()
+		slog.Info("API key hit its quota, trying the next key", "key", maskKey(key))
+		i, key = t.keys.exhausted(i)
+	}
+}
+
+// runAuthCheck verifies every configured Gemini API key with a model
+// lookup, which costs no tokens
+func runAuthCheck(config *Config) error {
+	if config.provider != search.ProviderGemini {
+		return fmt.Errorf("auth check supports the gemini provider only")
+	}
+	keys := configuredAPIKeys(config)
+	if len(keys) == 0 {
+		for _, name := range []string{"GOOGLE_API_KEY", "GEMINI_API_KEY"} {
+			if key := os.Getenv(name); key != "" {
+				keys = []string{key}
+				break
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%w: use -api-key, GOSEARCH_API_KEYS, GOOGLE_API_KEY or GEMINI_API_KEY", search.ErrNoAPIKey)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.timeout)
+	defer cancel()
+
+	// Check each key as given rather than through the rotation
+	direct := *config
+	direct.keyRing = nil
+	httpClient := newHTTPClient(&direct)
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSTATUS")
+	for _, key := range keys {
+		status := "ok"
+		if err := search.CheckAPIKey(ctx, httpClient, key, config.model); err != nil {
+			status = err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\n", maskKey(key), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d API keys failed", failed, len(keys))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"google.golang.org/genai"
+)
+
+// Patterns always redacted from the audit log, each replaced by
+// [REDACTED:<name>]
+var defaultAuditRedactions = []auditRedaction{
+	{"api_key", regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`)},
+	{"api_key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"token", regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`)},
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+}
+
+type auditRedaction struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// auditEntry is one line of the audit log: a request as sent, or the
+// response that came back for it
+type auditEntry struct {
+	Type              string                                      `json:"type"`
+	ID                string                                      `json:"id"`
+	Kind              string                                      `json:"kind"`
+	Attempt           int                                         `json:"attempt"`
+	Timestamp         time.Time                                   `json:"timestamp"`
+	Model             string                                      `json:"model"`
+	SystemInstruction string                                      `json:"system_instruction,omitempty"`
+	Contents          []*genai.Content                            `json:"contents,omitempty"`
+	Config            *genai.GenerateContentConfig                `json:"config,omitempty"`
+	Text              string                                      `json:"text,omitempty"`
+	Usage             *genai.GenerateContentResponseUsageMetadata `json:"usage,omitempty"`
+	Error             string                                      `json:"error,omitempty"`
+}
+
+// auditLogger appends every request and response to a JSONL file, with
+// secrets and personal data redacted from every string before it is
+// written. The file is only ever appended to.
+type auditLogger struct {
+	mu         sync.Mutex
+	file       *os.File
+	redactions []auditRedaction
+}
+
+// Set from -audit-log; nil disables the audit log
+var auditLog *auditLogger
+
+// openAuditLog opens path for appending, redacting patterns as well as the
+// defaults
+func openAuditLog(path string, patterns []string) (*auditLogger, error) {
+	redactions := append([]auditRedaction{}, defaultAuditRedactions...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit redaction %q: %w", pattern, err)
+		}
+		redactions = append(redactions, auditRedaction{"custom", re})
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &auditLogger{file: file, redactions: redactions}, nil
+}
+
+// request is used as search.Options.OnRequest
+func (l *auditLogger) request(ctx context.Context, req search.Request) {
+	if l == nil {
+		return
+	}
+	entry := auditEntry{
+		Type:      "request",
+		ID:        req.ID,
+		Kind:      req.Kind,
+		Attempt:   req.Attempt,
+		Timestamp: time.Now(),
+		Model:     req.Model,
+		Contents:  req.Contents,
+	}
+	entry.SystemInstruction, entry.Config = splitSystemInstruction(req.Config)
+	l.write(entry)
+}
+
+// response is used as search.Options.OnResponse
+func (l *auditLogger) response(ctx context.Context, resp search.Response) {
+	if l == nil {
+		return
+	}
+	entry := auditEntry{
+		Type:      "response",
+		ID:        resp.ID,
+		Kind:      resp.Kind,
+		Attempt:   resp.Attempt,
+		Timestamp: time.Now(),
+		Model:     resp.Model,
+		Text:      resp.Text,
+		Usage:     resp.Usage,
+	}
+	if resp.Err != nil {
+		entry.Error = resp.Err.Error()
+	}
+	l.write(entry)
+}
+
+// write redacts every string of entry and appends it as one line
+func (l *auditLogger) write(entry auditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+		return
+	}
+	// Round-trip through a generic value so redaction only touches
+	// strings, never the JSON around them
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+		return
+	}
+	if data, err = json.Marshal(l.redact(value)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+func (l *auditLogger) redact(value any) any {
+	switch v := value.(type) {
+	case string:
+		for _, r := range l.redactions {
+			v = r.pattern.ReplaceAllString(v, "[REDACTED:"+r.name+"]")
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = l.redact(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = l.redact(v[key])
+		}
+	}
+	return value
+}
+
+func (l *auditLogger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
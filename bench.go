@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// BenchRun is one search of a benchmark
+type BenchRun struct {
+	Duration       time.Duration    `json:"duration"`
+	Success        bool             `json:"success"`
+	Error          string           `json:"error,omitempty"`
+	ErrorCode      search.ErrorCode `json:"error_code,omitempty"`
+	PromptTokens   int32            `json:"prompt_tokens,omitempty"`
+	OutputTokens   int32            `json:"output_tokens,omitempty"`
+	ThinkingTokens int32            `json:"thinking_tokens,omitempty"`
+}
+
+// BenchStats summarizes the values of one measure across the successful
+// runs of a benchmark
+type BenchStats struct {
+	Min  float64 `json:"min"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// BenchSimilarity compares the answers of a benchmark pairwise
+type BenchSimilarity struct {
+	Mean   float64 `json:"mean"`
+	Min    float64 `json:"min"`
+	StdDev float64 `json:"stddev"`
+}
+
+type BenchResult struct {
+	Query          string  `json:"query"`
+	Model          string  `json:"model"`
+	ThinkingBudget int32   `json:"thinking_budget"`
+	Runs           int     `json:"runs"`
+	Failures       int     `json:"failures"`
+	FailureRate    float64 `json:"failure_rate"`
+	// Latency in milliseconds
+	Latency        BenchStats       `json:"latency_ms"`
+	PromptTokens   BenchStats       `json:"prompt_tokens"`
+	OutputTokens   BenchStats       `json:"output_tokens"`
+	ThinkingTokens BenchStats       `json:"thinking_tokens"`
+	Similarity     *BenchSimilarity `json:"similarity,omitempty"`
+	CostUSD        float64          `json:"cost_usd,omitempty"`
+	TotalTime      time.Duration    `json:"total_time"`
+	Results        []BenchRun       `json:"results"`
+	// Set when every run failed
+	ErrorCode search.ErrorCode `json:"error_code,omitempty"`
+}
+
+// runBench searches the query -n times, bypassing the response cache, and
+// summarizes latency, token usage and failures across the runs
+func runBench(ctx context.Context, config *Config, client *search.Client) *BenchResult {
+	startTime := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, config.timeout)
+	defer cancel()
+
+	uncached := client.WithoutCache()
+	results := runConcurrently(ctx, config.benchRuns, config.workers, config.timeoutGrace, false, func(ctx context.Context, index int) search.Result {
+		result, err := uncached.Search(ctx, config.query)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		slog.Info("Bench run completed", "run", index+1, "success", result.Success, "duration", result.Duration)
+		return *result
+	}, func(index int, started bool) search.Result {
+		return cancelledResult(ctx, config.query, started)
+	})
+
+	bench := &BenchResult{
+		Query:          config.query,
+		Model:          client.Options().Model,
+		ThinkingBudget: client.Options().ThinkingBudget,
+		Runs:           len(results),
+		TotalTime:      time.Since(startTime),
+	}
+	var latency, prompt, output, thinking []float64
+	var responses []string
+	for _, r := range results {
+		bench.Results = append(bench.Results, BenchRun{
+			Duration:       r.Duration,
+			Success:        r.Success,
+			Error:          r.Error,
+			ErrorCode:      r.ErrorCode,
+			PromptTokens:   r.PromptTokens,
+			OutputTokens:   r.OutputTokens,
+			ThinkingTokens: r.ThinkingTokens,
+		})
+		bench.CostUSD += r.CostUSD
+		if !r.Success {
+			bench.Failures++
+			continue
+		}
+		latency = append(latency, float64(r.Duration)/float64(time.Millisecond))
+		prompt = append(prompt, float64(r.PromptTokens))
+		output = append(output, float64(r.OutputTokens))
+		thinking = append(thinking, float64(r.ThinkingTokens))
+		responses = append(responses, r.Response)
+	}
+	if bench.Runs > 0 {
+		bench.FailureRate = float64(bench.Failures) / float64(bench.Runs)
+	}
+	if bench.Failures == bench.Runs {
+		bench.ErrorCode = batchCode(results)
+	}
+	bench.Latency = benchStats(latency)
+	bench.PromptTokens = benchStats(prompt)
+	bench.OutputTokens = benchStats(output)
+	bench.ThinkingTokens = benchStats(thinking)
+	if config.benchSimilarity {
+		bench.Similarity = answerSimilarity(responses)
+	}
+	return bench
+}
+
+// benchStats uses nearest-rank percentiles, so every value reported was
+// observed
+func benchStats(values []float64) BenchStats {
+	if len(values) == 0 {
+		return BenchStats{}
+	}
+	sorted := slices.Sorted(slices.Values(values))
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	return BenchStats{
+		Min:  sorted[0],
+		P50:  rank(0.5),
+		P95:  rank(0.95),
+		Max:  sorted[len(sorted)-1],
+		Mean: sum / float64(len(sorted)),
+	}
+}
+
+// answerSimilarity compares every pair of answers with the measure -watch
+// uses; nil with fewer than two answers
+func answerSimilarity(responses []string) *BenchSimilarity {
+	var scores []float64
+	for i := range responses {
+		for j := i + 1; j < len(responses); j++ {
+			scores = append(scores, responseSimilarity(responses[i], responses[j]))
+		}
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+	similarity := &BenchSimilarity{Min: 1}
+	for _, s := range scores {
+		similarity.Mean += s
+		similarity.Min = min(similarity.Min, s)
+	}
+	similarity.Mean /= float64(len(scores))
+	var variance float64
+	for _, s := range scores {
+		variance += (s - similarity.Mean) * (s - similarity.Mean)
+	}
+	similarity.StdDev = math.Sqrt(variance / float64(len(scores)))
+	return similarity
+}
+
+// printBench writes the benchmark as a table, or encoded with the
+// machine-readable formats
+func printBench(config *Config, b *BenchResult) error {
+	switch config.format {
+	case formatJSON:
+		return encodeJSON(b)
+	case formatJSONL:
+		return json.NewEncoder(os.Stdout).Encode(b)
+	case formatYAML:
+		return encodeYAML(b)
+	}
+
+	fmt.Printf("## BENCHMARK\n")
+	fmt.Printf("Query: %s\n", b.Query)
+	fmt.Printf("Model: %s (thinking budget %d)\n", b.Model, b.ThinkingBudget)
+	fmt.Printf("Runs: %d, failed: %d (%.1f%%), total time: %s\n", b.Runs, b.Failures, 100*b.FailureRate, b.TotalTime.Round(time.Millisecond))
+
+	if b.Failures < b.Runs {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tMIN\tP50\tP95\tMAX\tMEAN")
+		row := func(name string, s BenchStats) {
+			fmt.Fprintf(w, "%s\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\n", name, s.Min, s.P50, s.P95, s.Max, s.Mean)
+		}
+		row("latency (ms)", b.Latency)
+		row("prompt tokens", b.PromptTokens)
+		row("output tokens", b.OutputTokens)
+		row("thinking tokens", b.ThinkingTokens)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if b.Similarity != nil {
+		fmt.Printf("\nAnswer similarity: mean %.2f, min %.2f, stddev %.3f\n", b.Similarity.Mean, b.Similarity.Min, b.Similarity.StdDev)
+	}
+	if b.CostUSD > 0 {
+		fmt.Printf("\nEstimated cost: $%.4f\n", b.CostUSD)
+	}
+
+	var failures []string
+	for _, r := range b.Results {
+		if !r.Success && !slices.Contains(failures, r.Error) {
+			failures = append(failures, r.Error)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Printf("\nErrors:\n")
+		for _, e := range failures {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// runCacheCommand handles the "cache" subcommand
+func runCacheCommand(args []string) error {
+	if len(args) != 1 || args[0] != "clear" {
+		return fmt.Errorf("usage: cache clear")
+	}
+
+	dir, err := search.DefaultCacheDir()
+	if err != nil {
+		return err
+	}
+	// TTL only matters for reads
+	removed, err := search.NewDiskCache(dir, 0).Clear()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d cached responses from %s\n", removed, dir)
+	return nil
+}
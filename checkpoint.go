@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// checkpointFile is the JSON written by -checkpoint. Results are keyed by
+// checkpointKey of their query.
+type checkpointFile struct {
+	Model   string                   `json:"model"`
+	Updated time.Time                `json:"updated"`
+	Results map[string]search.Result `json:"results"`
+}
+
+// checkpointInterval is the longest a recorded result waits before the
+// checkpoint is rewritten
+const checkpointInterval = 2 * time.Second
+
+// checkpoint records the queries of a batch as they succeed, so an
+// interrupted run can skip them with -resume-from. Writes are batched: the
+// first result after a write schedules the next one, and flush writes
+// whatever is left when the batch ends. A nil checkpoint records and
+// restores nothing.
+type checkpoint struct {
+	path string
+	mu   sync.Mutex
+	file checkpointFile
+	// Results recorded since the last write
+	dirty   bool
+	pending *time.Timer
+}
+
+func checkpointKey(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// openCheckpoint loads the results saved at resumeFrom, if set, and writes
+// progress to path, or back to resumeFrom when path is empty
+func openCheckpoint(path, resumeFrom, model string) (*checkpoint, error) {
+	if path == "" {
+		path = resumeFrom
+	}
+	c := &checkpoint{path: path, file: checkpointFile{Model: model, Results: make(map[string]search.Result)}}
+	if resumeFrom == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(resumeFrom)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("checkpoint %s does not exist", resumeFrom)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var saved checkpointFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", resumeFrom, err)
+	}
+	if saved.Model != "" && saved.Model != model {
+		fmt.Fprintf(os.Stderr, "Warning: %s was written by a %s run; its results are reused with %s\n", resumeFrom, saved.Model, model)
+	}
+	for key, result := range saved.Results {
+		c.file.Results[key] = result
+	}
+	return c, nil
+}
+
+// completed counts how many of queries the checkpoint already holds
+func (c *checkpoint) completed(queries []string) int {
+	n := 0
+	for _, query := range queries {
+		if _, ok := c.lookup(query); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// lookup returns the saved result for query
+func (c *checkpoint) lookup(query string) (search.Result, bool) {
+	if c == nil {
+		return search.Result{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.file.Results[checkpointKey(query)]
+	return result, ok
+}
+
+// record saves a successful result, to be written within
+// checkpointInterval. Failed queries are left out so a resumed run tries
+// them again.
+func (c *checkpoint) record(result search.Result) {
+	if c == nil || !result.Success {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.file.Results[checkpointKey(result.Query)] = result
+	c.file.Updated = time.Now()
+	c.dirty = true
+	if c.pending == nil {
+		c.pending = time.AfterFunc(checkpointInterval, c.flush)
+	}
+}
+
+// flush writes the results recorded since the last write. Write errors are
+// logged rather than returned so a checkpoint never breaks a batch.
+func (c *checkpoint) flush() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending != nil {
+		c.pending.Stop()
+		c.pending = nil
+	}
+	if !c.dirty {
+		return
+	}
+	if err := c.write(); err != nil {
+		slog.Error("Failed to write checkpoint", "path", c.path, "error", err)
+		return
+	}
+	c.dirty = false
+}
+
+// write replaces the checkpoint through a temporary file, so a crash
+// mid-write leaves the previous one intact
+func (c *checkpoint) write() error {
+	data, err := json.MarshalIndent(c.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
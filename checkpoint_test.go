@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+func TestCheckpointBatchesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	c, err := openCheckpoint(path, "", "gemini-2.5-flash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 50 {
+		c.record(search.Result{Query: fmt.Sprintf("query %d", i), Success: true, Response: "answer"})
+	}
+	c.record(search.Result{Query: "failed", Error: "boom"})
+
+	// Nothing is written until the interval passes or the batch ends
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint written before flush: %v", err)
+	}
+	c.flush()
+
+	resumed, err := openCheckpoint("", path, "gemini-2.5-flash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 50 {
+		query := fmt.Sprintf("query %d", i)
+		if result, ok := resumed.lookup(query); !ok || result.Response != "answer" {
+			t.Errorf("lookup(%q) = %v, %v, want the saved answer", query, result, ok)
+		}
+	}
+	if _, ok := resumed.lookup("failed"); ok {
+		t.Error("failed query was saved")
+	}
+}
+
+func TestCheckpointFlushWithoutResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	c, err := openCheckpoint(path, "", "gemini-2.5-flash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.flush()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("empty checkpoint was written: %v", err)
+	}
+	var none *checkpoint
+	none.flush()
+}
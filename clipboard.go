@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// clipboardTool is a command that reads or writes the system clipboard
+type clipboardTool struct {
+	paste []string
+	copy  []string
+}
+
+// clipboardTools lists the commands tried in order on this platform
+func clipboardTools() []clipboardTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardTool{{paste: []string{"pbpaste"}, copy: []string{"pbcopy"}}}
+	case "windows":
+		return []clipboardTool{{
+			paste: []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+			copy:  []string{"powershell.exe", "-NoProfile", "-Command", "$input | Set-Clipboard"},
+		}}
+	}
+	var tools []clipboardTool
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		tools = append(tools, clipboardTool{paste: []string{"wl-paste", "--no-newline"}, copy: []string{"wl-copy"}})
+	}
+	return append(tools,
+		clipboardTool{paste: []string{"xclip", "-selection", "clipboard", "-o"}, copy: []string{"xclip", "-selection", "clipboard", "-i"}},
+		clipboardTool{paste: []string{"xsel", "--clipboard", "--output"}, copy: []string{"xsel", "--clipboard", "--input"}},
+	)
+}
+
+// clipboardCommand returns the first of the commands picked by args that is
+// installed
+func clipboardCommand(args func(clipboardTool) []string) ([]string, error) {
+	var names []string
+	for _, tool := range clipboardTools() {
+		command := args(tool)
+		if _, err := exec.LookPath(command[0]); err == nil {
+			return command, nil
+		}
+		names = append(names, command[0])
+	}
+	return nil, fmt.Errorf("no clipboard tool found (install one of: %s)", strings.Join(names, ", "))
+}
+
+// readClipboard returns the text on the clipboard
+func readClipboard() (string, error) {
+	command, err := clipboardCommand(func(t clipboardTool) []string { return t.paste })
+	if err != nil {
+		return "", err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// writeClipboard replaces the clipboard contents with text
+func writeClipboard(text string) error {
+	command, err := clipboardCommand(func(t clipboardTool) []string { return t.copy })
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// queryFromClipboard sets the query for -from-clipboard
+func queryFromClipboard(config *Config) error {
+	if config.query != "" || len(config.queries) > 0 || config.queriesFile != "" || len(config.urls) > 0 {
+		return fmt.Errorf("-from-clipboard cannot be combined with a query, -q, -queries-file, or URLs")
+	}
+	text, err := readClipboard()
+	if err != nil {
+		return fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return errors.New("the clipboard is empty")
+	}
+	config.query = strings.TrimSpace(text)
+	return nil
+}
+
+// copyResult puts the answer, or the summary when one was generated, on
+// the clipboard for -to-clipboard
+func copyResult(r *search.Result) error {
+	text := r.Response
+	if r.Summary != "" && r.Summary != search.SummaryFailedText {
+		text = r.Summary
+	}
+	if err := writeClipboard(text); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	slog.Info("Copied result to clipboard", "bytes", len(text))
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+type CompareRun struct {
+	Model  string        `json:"model"`
+	Result search.Result `json:"result"`
+}
+
+type CompareResult struct {
+	Query     string           `json:"query"`
+	Runs      []CompareRun     `json:"runs"`
+	TotalTime time.Duration    `json:"total_time"`
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`
+	ErrorCode search.ErrorCode `json:"error_code,omitempty"`
+}
+
+// runComparison searches query once per -compare model, in parallel
+func runComparison(ctx context.Context, query string, config *Config, client *search.Client) *CompareResult {
+	startTime := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, config.timeout)
+	defer cancel()
+
+	runs := runConcurrently(ctx, len(config.compareModels), config.workers, config.timeoutGrace, false, func(ctx context.Context, index int) CompareRun {
+		model := config.compareModels[index]
+		modelClient := client.WithModel(model)
+		result, err := modelClient.Search(ctx, query)
+		if err != nil {
+			result.Error = err.Error()
+		} else if config.includeSummary {
+			modelClient.AddSummary(ctx, result)
+		}
+
+		slog.Info("Comparison run completed", "model", model, "success", result.Success, "duration", result.Duration)
+		return CompareRun{Model: model, Result: *result}
+	}, func(index int, started bool) CompareRun {
+		return CompareRun{
+			Model:  config.compareModels[index],
+			Result: cancelledResult(ctx, query, started),
+		}
+	})
+
+	successCount := 0
+	for i, run := range runs {
+		history.record(run.Model, &runs[i].Result)
+		if run.Result.Success {
+			successCount++
+		}
+	}
+
+	comparison := &CompareResult{
+		Query:     query,
+		Runs:      runs,
+		TotalTime: time.Since(startTime),
+		Success:   successCount == len(runs),
+	}
+	if !comparison.Success {
+		comparison.Error = fmt.Sprintf("Completed %d/%d model runs successfully", successCount, len(runs))
+		results := make([]search.Result, len(runs))
+		for i, run := range runs {
+			results[i] = run.Result
+		}
+		comparison.ErrorCode = batchCode(results)
+	}
+	return comparison
+}
+
+// compareRunStatus is the STATUS column of comparison tables
+func compareRunStatus(run CompareRun) string {
+	if run.Result.Success {
+		return "ok"
+	}
+	return "failed"
+}
+
+// formatCost prints a cost estimate, or "-" when the model has no known price
+func formatCost(usd float64) string {
+	if usd == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("$%.4f", usd)
+}
+
+func (textRenderer) compare(c *CompareResult) error {
+	fmt.Printf("## MODEL COMPARISON\n")
+	fmt.Printf("Query: %s\n\n", c.Query)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tSTATUS\tLATENCY\tPROMPT\tTHINKING\tOUTPUT\tCOST")
+	for _, run := range c.Runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			run.Model,
+			compareRunStatus(run),
+			run.Result.Duration.Round(time.Millisecond),
+			run.Result.PromptTokens,
+			run.Result.ThinkingTokens,
+			run.Result.OutputTokens,
+			formatCost(run.Result.CostUSD))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	printCompareResponses(c)
+	return nil
+}
+
+// printCompareResponses writes each model's full answer after a table
+func printCompareResponses(c *CompareResult) {
+	fmt.Printf("\n## RESPONSES\n\n")
+
+	for _, run := range c.Runs {
+		fmt.Printf("=== %s ===\n", run.Model)
+		if !run.Result.Success {
+			fmt.Printf("Status: FAILED - %s\n\n", run.Result.Error)
+			continue
+		}
+		if run.Result.Summary != "" {
+			fmt.Printf("Summary: %s\n\n", run.Result.Summary)
+		}
+		fmt.Printf("%s\n", answerText(run.Result.Response))
+		printSources(run.Result.Sources)
+		fmt.Printf("\n")
+	}
+}
+
+// Runes of each answer shown in the preview row of -format table
+const comparePreviewLength = 40
+
+// tableRenderer prints a comparison with one column per model and one row
+// per measure. It is only used with -compare; other output is text.
+type tableRenderer struct {
+	textRenderer
+}
+
+func (tableRenderer) compare(c *CompareResult) error {
+	fmt.Printf("## MODEL COMPARISON\n")
+	fmt.Printf("Query: %s\n\n", c.Query)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, run := range c.Runs {
+		fmt.Fprintf(w, "\t%s", run.Model)
+	}
+	fmt.Fprintln(w)
+	row := func(name string, value func(run CompareRun) string) {
+		fmt.Fprint(w, name)
+		for _, run := range c.Runs {
+			fmt.Fprintf(w, "\t%s", value(run))
+		}
+		fmt.Fprintln(w)
+	}
+	row("status", compareRunStatus)
+	row("latency", func(run CompareRun) string { return run.Result.Duration.Round(time.Millisecond).String() })
+	row("prompt tokens", func(run CompareRun) string { return fmt.Sprint(run.Result.PromptTokens) })
+	row("thinking tokens", func(run CompareRun) string { return fmt.Sprint(run.Result.ThinkingTokens) })
+	row("output tokens", func(run CompareRun) string { return fmt.Sprint(run.Result.OutputTokens) })
+	row("response length", func(run CompareRun) string { return fmt.Sprintf("%d chars", len([]rune(run.Result.Response))) })
+	row("cost", func(run CompareRun) string { return formatCost(run.Result.CostUSD) })
+	row("preview", func(run CompareRun) string {
+		if !run.Result.Success {
+			return "-"
+		}
+		return truncateQuery(run.Result.Response, comparePreviewLength)
+	})
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	printCompareResponses(c)
+	return nil
+}
+
+func (markdownRenderer) compare(c *CompareResult) error {
+	fmt.Printf("# Model comparison: %s\n\n", c.Query)
+	fmt.Println("| Model | Status | Latency | Prompt | Thinking | Output | Cost |")
+	fmt.Println("|-------|--------|--------:|-------:|---------:|-------:|-----:|")
+	for _, run := range c.Runs {
+		fmt.Printf("| %s | %s | %s | %d | %d | %d | %s |\n", run.Model, compareRunStatus(run),
+			run.Result.Duration.Round(time.Millisecond), run.Result.PromptTokens,
+			run.Result.ThinkingTokens, run.Result.OutputTokens, formatCost(run.Result.CostUSD))
+	}
+	for _, run := range c.Runs {
+		fmt.Printf("\n## %s\n\n", run.Model)
+		if run.Result.Success {
+			fmt.Println(strings.TrimSpace(run.Result.Response))
+		} else {
+			fmt.Printf("**Failed:** %s\n", run.Result.Error)
+		}
+	}
+	return nil
+}
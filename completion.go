@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"gopkg.in/yaml.v3"
+)
+
+var subcommands = []string{"auth", "bench", "cache", "completion", "daemon", "diff", "history", "mcp", "recall", "sessions", "url"}
+
+// Flags whose value is a path, completed with file names
+var fileFlags = map[string]bool{
+	"ca-cert":        true,
+	"checkpoint":     true,
+	"config":         true,
+	"file":           true,
+	"history-db":     true,
+	"image":          true,
+	"out":            true,
+	"out-dir":        true,
+	"prompt-log":     true,
+	"report":         true,
+	"resume-from":    true,
+	"queries-file":   true,
+	"schema":         true,
+	"summary-prompt": true,
+	"system-prompt":  true,
+	"vars":           true,
+}
+
+// flagChoices lists the values completed after flags that take one of a
+// fixed set. Profiles are read from the config file when completing.
+func flagChoices() map[string][]string {
+	providers := make([]string, 0, len(search.ProviderDefaultModels))
+	for name := range search.ProviderDefaultModels {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	return map[string][]string{
+		"dedupe":          dedupeModes,
+		"format":          outputFormats,
+		"log-format":      {logFormatJSON, logFormatText},
+		"model":           search.KnownModels,
+		"provider":        providers,
+		"safety":          {search.SafetyBlockNone, search.SafetyDefault, search.SafetyStrict},
+		"stream-summary":  {streamSummaryAfter, streamSummaryEarly},
+		"backend":         {backendGemini, backendVertex},
+		"key-rotation":    {rotationFailover, rotationRoundRobin},
+		"style":           search.Styles,
+		"tls-min-version": {"1.2", "1.3"},
+		"tools":           append(append([]string{}, search.Tools...), "none"),
+	}
+}
+
+// completionFlag is the metadata a completion script needs about one flag
+type completionFlag struct {
+	name    string
+	usage   string
+	boolean bool
+}
+
+func completionFlags() []completionFlag {
+	registerFlags()
+	var flags []completionFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:    f.Name,
+			usage:   f.Usage,
+			boolean: ok && boolFlag.IsBoolFlag(),
+		})
+	})
+	return flags
+}
+
+// runCompletionCommand handles "completion bash|zsh|fish". The hidden
+// "completion profiles" lists profile names for the generated scripts.
+func runCompletionCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s completion bash|zsh|fish", os.Args[0])
+	}
+
+	prog := filepath.Base(os.Args[0])
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(prog, completionFlags()))
+	case "zsh":
+		fmt.Print(zshCompletion(prog, completionFlags()))
+	case "fish":
+		fmt.Print(fishCompletion(prog, completionFlags()))
+	case "profiles":
+		for _, name := range profileNames(defaultConfigPath()) {
+			fmt.Println(name)
+		}
+	default:
+		return fmt.Errorf("unknown shell %q (known: bash, zsh, fish)", args[0])
+	}
+	return nil
+}
+
+// profileNames returns the profiles defined in the config file at path,
+// or nothing if it cannot be read
+func profileNames(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var file configFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// completionFunc names the shell function for prog
+func completionFunc(prog string) string {
+	return "_" + nonIdentifier.ReplaceAllString(prog, "_")
+}
+
+func bashCompletion(prog string, flags []completionFlag) string {
+	choices := flagChoices()
+	var names, files, values []string
+	for _, f := range flags {
+		names = append(names, "-"+f.name)
+		switch {
+		case f.boolean || f.name == "profile" || choices[f.name] != nil:
+		case fileFlags[f.name]:
+			files = append(files, "-"+f.name)
+		default:
+			values = append(values, "-"+f.name)
+		}
+	}
+
+	var b strings.Builder
+	fn := completionFunc(prog)
+	fmt.Fprintf(&b, "# bash completion for %s\n", prog)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("    case \"$prev\" in\n")
+	for _, name := range sortedKeys(choices) {
+		fmt.Fprintf(&b, "        -%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", name, strings.Join(choices[name], " "))
+	}
+	fmt.Fprintf(&b, "        -profile) COMPREPLY=($(compgen -W \"$(%s completion profiles 2>/dev/null)\" -- \"$cur\")); return ;;\n", prog)
+	fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(files, "|"))
+	fmt.Fprintf(&b, "        %s) COMPREPLY=(); return ;;\n", strings.Join(values, "|"))
+	b.WriteString("    esac\n")
+	b.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("    elif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(subcommands, " "))
+	b.WriteString("    fi\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, prog)
+	return b.String()
+}
+
+func zshCompletion(prog string, flags []completionFlag) string {
+	choices := flagChoices()
+	escape := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`)
+
+	var b strings.Builder
+	fn := completionFunc(prog)
+	fmt.Fprintf(&b, "#compdef %s\n\n", prog)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    _arguments \\\n")
+	for _, f := range flags {
+		spec := fmt.Sprintf("-%s[%s]", f.name, escape.Replace(f.usage))
+		switch {
+		case f.boolean:
+		case f.name == "profile":
+			spec += fmt.Sprintf(":profile:($(%s completion profiles 2>/dev/null))", prog)
+		case choices[f.name] != nil:
+			spec += fmt.Sprintf(":%s:(%s)", f.name, strings.Join(choices[f.name], " "))
+		case fileFlags[f.name]:
+			spec += ":file:_files"
+		default:
+			spec += ":value: "
+		}
+		fmt.Fprintf(&b, "        '%s' \\\n", spec)
+	}
+	fmt.Fprintf(&b, "        '1::command:(%s)' \\\n", strings.Join(subcommands, " "))
+	b.WriteString("        '*::query: '\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "if [ \"$funcstack[1]\" = \"%s\" ]; then\n    %s \"$@\"\nelse\n    compdef %s %s\nfi\n", fn, fn, fn, prog)
+	return b.String()
+}
+
+func fishCompletion(prog string, flags []completionFlag) string {
+	choices := flagChoices()
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", prog)
+	fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -x -a '%s'\n", prog, strings.Join(subcommands, " "))
+	for _, f := range flags {
+		line := fmt.Sprintf("complete -c %s -o %s -d '%s'", prog, f.name, escape.Replace(f.usage))
+		switch {
+		case f.boolean:
+		case f.name == "profile":
+			line += fmt.Sprintf(" -x -a '(%s completion profiles 2>/dev/null)'", prog)
+		case choices[f.name] != nil:
+			line += fmt.Sprintf(" -x -a '%s'", strings.Join(choices[f.name], " "))
+		case fileFlags[f.name]:
+			line += " -r -F"
+		default:
+			line += " -x"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
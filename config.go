@@ -1,52 +1,291 @@
 package main
 
 import (
+	"crypto/x509"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+const (
+	streamSummaryAfter = "after"
+	streamSummaryEarly = "early"
+)
+
+// Backends for the gemini provider
+const (
+	backendGemini = "gemini"
+	backendVertex = "vertex"
 )
 
 type Config struct {
-	query                 string
-	queries               []string
-	outputJSON            bool
-	verbose               bool
-	stream                bool
-	workers               int
-	timeout               time.Duration
-	includeSummary        bool
+	query   string
+	queries []string
+	// -tag tags for every result, and tags from queries file lines
+	tags      map[string]string
+	queryTags map[string]map[string]string
+	// Queries file lines marked !nocache
+	noCacheQueries     map[string]bool
+	format             string
+	csvColumns         []string
+	csvColumnsExplicit bool
+	dedupe             string
+	failFast           bool
+	continueOnError    bool
+	priorityOrder      bool
+	checkpointPath     string
+	resumeFrom         string
+	checkpoint         *checkpoint
+	verify             bool
+	confidence         bool
+	suggest            bool
+	notify             bool
+	quiet              bool
+	render             bool
+	renderExplicit     bool
+	noColor            bool
+	verbose            bool
+	stream             bool
+	workers            int
+	timeout            time.Duration
+	timeoutGrace       time.Duration
+	queryTimeout       time.Duration
+	attemptTimeout     time.Duration
+	retryDelay         time.Duration
+	// Turns configuration warnings into errors
+	strict                 bool
+	rpm                    int
+	style                  string
+	maxWords               int
+	maxContinuations       int
+	tools                  []string
+	toolsSet               bool
+	enableLocalTools       bool
+	localTools             localToolsConfig
+	retryBudget            int
+	includeSummary         bool
 	includeSummaryExplicit bool
+	headers                http.Header
+	proxy                  *url.URL
+	caCertFile             string
+	caCerts                *x509.CertPool
+	tlsMinVersion          string
+	backend                string
+	project                string
+	location               string
+	apiKeys                []string
+	keyRotation            string
+	keyRing                *keyRing
+	authCheck              bool
+	sweepThinking          bool
+	sweepBudgets           []int32
+	compareModels          []string
+	inlineCitations        bool
+	outputOnError          bool
+	streamSummary          string
+	promptLog              string
+	auditLog               string
+	auditRedact            []string
+	logFile                string
+	logFormat              string
+	concat                 bool
+	delimiter              string
+	onlySucceeded          bool
+	tui                    bool
+	webhook                string
+	webhookSecret          string
+	slackWebhook           string
+	email                  []string
+	smtp                   smtpConfig
+	watch                  time.Duration
+	summaryWorkers         int
+	lang                   string
+	safety                 string
+	safetyOverrides        map[string]string
+	sites                  search.SiteFilter
+	imagePaths             []string
+	images                 []search.Image
+	documentPaths          []string
+	notePaths              []string
+	noteChunks             int
+	notes                  []search.Document
+	documents              []search.Document
+	fromClipboard          bool
+	queryTemplate          string
+	templateVars           string
+	postHook               string
+	toClipboard            bool
+	watchThreshold         float64
+	diffWith               string
+	dryRun                 bool
+	wordDiff               bool
+	summaryFallback        bool
+	classify               bool
+	model                  string
+	modelAllowAny          bool
+	interactive            bool
+	noCache                bool
+	cacheTTL               time.Duration
+	useContextCache        bool
+	contextCacheTTL        time.Duration
+	serve                  string
+	grpc                   string
+	daemon                 bool
+	useDaemon              bool
+	socket                 string
+	queriesFile            string
+	maxRetries             int
+	showCost               bool
+	schemaFile             string
+	templateFile           string
+	outputTemplate         *template.Template
+	schema                 any
+	systemPromptFile       string
+	summaryPromptFile      string
+	systemPrompt           string
+	summaryPrompt          string
+	configPath             string
+	profile                string
+	noHistory              bool
+	session                string
+	resume                 bool
+	historyDB              string
+	out                    string
+	outDir                 string
+	outputFile             string
+	compress               bool
+	report                 string
+	provider               string
+	followUp               bool
+	mcp                    bool
+	deep                   bool
+	thinkingBudget         int32
+	showThinking           bool
+	autoRewrite            bool
+	urlMode                bool
+	urls                   []string
+	deepQuestions          int
+	samples                int
+	recall                 bool
+	recallThreshold        float64
+	recallLimit            int
+	bench                  bool
+	freshness              time.Duration
+	autoModel              string
+	simpleModel            string
+	complexModel           string
+	benchRuns              int
+	benchSimilarity        bool
 }
 
-type SearchResult struct {
-	Query     string        `json:"query"`
-	Response  string        `json:"response"`
-	Summary   string        `json:"summary,omitempty"`
-	Success   bool          `json:"success"`
-	Error     string        `json:"error,omitempty"`
-	Duration  time.Duration `json:"duration"`
-	Timestamp time.Time     `json:"timestamp"`
+// BatchTimings aggregates phase durations across all queries in a batch
+type BatchTimings struct {
+	Construction time.Duration `json:"construction"`
+	Generation   time.Duration `json:"generation"`
+	Summary      time.Duration `json:"summary"`
 }
 
 type MultiSearchResult struct {
-	Results   []SearchResult `json:"results"`
-	TotalTime time.Duration  `json:"total_time"`
-	Success   bool           `json:"success"`
-	Error     string         `json:"error,omitempty"`
+	Results        []search.Result `json:"results"`
+	TotalTime      time.Duration   `json:"total_time"`
+	Timings        BatchTimings    `json:"timings"`
+	PromptTokens   int32           `json:"prompt_tokens,omitempty"`
+	OutputTokens   int32           `json:"output_tokens,omitempty"`
+	ThinkingTokens int32           `json:"thinking_tokens,omitempty"`
+	CostUSD        float64         `json:"cost_usd,omitempty"`
+	Success        bool            `json:"success"`
+	Error          string          `json:"error,omitempty"`
+	// partial_failure when some queries succeeded, else the failures' shared code
+	ErrorCode search.ErrorCode `json:"error_code,omitempty"`
+	// Set when Ctrl-C stopped the batch before every query finished
+	Interrupted bool       `json:"interrupted,omitempty"`
+	Stats       BatchStats `json:"stats"`
 }
 
-func parseFlags() *Config {
-	config := &Config{}
+// registerFlags defines every command-line flag on the default flag set
+// and returns the config they write to
+func registerFlags() *Config {
+	config := &Config{
+		headers:        http.Header{},
+		sweepBudgets:   []int32{0, 256, 512, 1024},
+		csvColumns:     defaultCSVColumns,
+		thinkingBudget: search.DefaultThinkingBudget,
+		tools:          search.DefaultTools,
+	}
 
+	flag.StringVar(&config.configPath, "config", "", "Config file with default options (default ~/.config/go-search/config.yaml)")
+	flag.StringVar(&config.profile, "profile", "", "Named profile from the config file to apply on top of its defaults")
 	flag.StringVar(&config.query, "query", "", "Single search query")
-	flag.BoolVar(&config.outputJSON, "json", false, "Output in JSON format")
+	flag.StringVar(&config.format, "format", formatText, "Output format: "+strings.Join(outputFormats, ", "))
+	flag.BoolFunc("json", "Output in JSON format (shorthand for -format json)", func(value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			config.format = formatJSON
+		}
+		return nil
+	})
+	config.dedupe = dedupeExact
+	flag.Func("dedupe", "Merge duplicate queries of a batch before searching: off, exact (ignoring case, spacing and trailing punctuation), or fuzzy (near-duplicates too)", func(value string) error {
+		config.dedupe = value
+		return validateDedupe(value)
+	})
+	flag.Func("csv-columns", "Comma-separated columns for -format csv and tsv (default "+strings.Join(defaultCSVColumns, ",")+")", func(value string) error {
+		columns, err := parseCSVColumns(value)
+		config.csvColumns = columns
+		config.csvColumnsExplicit = true
+		return err
+	})
+	flag.BoolFunc("render", "Format Markdown answers for the terminal: bold headings, colored code, indented bullets (default on when stdout is a terminal)", func(value string) error {
+		render, err := strconv.ParseBool(value)
+		config.render = render
+		config.renderExplicit = true
+		return err
+	})
+	flag.BoolVar(&config.noColor, "no-color", false, "Print plain text without colors or terminal formatting (also set by $NO_COLOR)")
+	flag.BoolVar(&config.quiet, "quiet", false, "Leave out headers, separator lines and banners so text output pipes cleanly")
 	flag.BoolVar(&config.verbose, "verbose", false, "Enable verbose logging")
 	flag.BoolVar(&config.verbose, "v", false, "Enable verbose logging (shorthand)")
 	flag.BoolVar(&config.stream, "stream", false, "Stream results as they complete")
+	flag.StringVar(&config.provider, "provider", search.ProviderGemini, "Model provider: gemini, openai, anthropic, or ollama")
+	flag.StringVar(&config.model, "model", "", fmt.Sprintf("Model to use (default $GOSEARCH_MODEL or the provider's default, %s for gemini)", search.DefaultModel))
+	flag.BoolVar(&config.modelAllowAny, "model-allow-any", false, "Allow model names not in the known list")
 	flag.IntVar(&config.workers, "workers", 3, "Max concurrent queries (1-5)")
+	flag.IntVar(&config.summaryWorkers, "summary-workers", 0, "Max concurrent summaries in multi-query mode (1-5, default -workers)")
 	flag.DurationVar(&config.timeout, "timeout", 180*time.Second, "Total operation timeout")
+	flag.IntVar(&config.maxRetries, "max-retries", 1, "Retries per API call on rate limits (429) and server errors (5xx), with exponential backoff")
+	flag.IntVar(&config.retryBudget, "retry-budget", 0, "Maximum retries across all queries of a multi-query run; later queries fail fast once used up (0 for no limit)")
+	flag.DurationVar(&config.queryTimeout, "query-timeout", 0, "Timeout for each query and its summary, including retries (0 for none)")
+	flag.DurationVar(&config.attemptTimeout, "attempt-timeout", 0, "How long each API request waits for the response to start, after which it is retried like a network failure (0 for none)")
+	flag.DurationVar(&config.retryDelay, "retry-delay", search.DefaultRetryPolicy().BaseDelay, "Wait before the first retry, doubling for each one after")
+	flag.BoolVar(&config.strict, "strict", false, "Fail on configuration warnings, such as retries that cannot fit in -timeout")
+	flag.IntVar(&config.rpm, "rpm", 0, "Maximum queries started per minute across all workers in multi-query mode (0 for no limit)")
+	flag.BoolVar(&config.failFast, "fail-fast", false, "Stop starting new queries of a batch after the first failure")
+	flag.BoolVar(&config.continueOnError, "continue-on-error", true, "Keep running a batch after a query fails; false cancels the rest, including queries in flight")
+	flag.BoolVar(&config.priorityOrder, "priority-order", false, "Start the queries of a batch in the order given, even with several workers")
+	flag.BoolVar(&config.notify, "notify", false, "Show a desktop notification when the search or batch finishes")
+	flag.BoolVar(&config.confidence, "confidence", false, fmt.Sprintf("Rate how far each answer can be trusted, list its caveats, and flag ratings below %.1f", search.LowConfidence))
+	flag.BoolVar(&config.suggest, "suggest", false, fmt.Sprintf("Propose up to %d follow-up queries after each answer; in -interactive mode, type a number to run one", search.MaxSuggestions))
+	flag.BoolVar(&config.verify, "verify", false, fmt.Sprintf("Fact-check each answer: extract up to %d claims, search for each, and flag the unsupported ones", search.MaxVerifyClaims))
+	flag.StringVar(&config.checkpointPath, "checkpoint", "", "Save each completed query of a batch to this JSON file, for -resume-from")
+	flag.StringVar(&config.resumeFrom, "resume-from", "", "Skip the queries already completed in this checkpoint file and keep it up to date (or -checkpoint, if set)")
+	flag.DurationVar(&config.timeoutGrace, "timeout-grace", 5*time.Second, "How long to wait for in-flight queries to finish after the timeout before printing partial results")
 
 	// Custom flag for include-summary to track explicit setting
 	flag.Func("include-summary", "Include AI-generated summaries (default: off for single query, on for multi-query)", func(value string) error {
@@ -59,14 +298,269 @@ func parseFlags() *Config {
 		return nil
 	})
 
+	// Custom flag for extra request headers
+	flag.Func("header", "Extra HTTP header as key=value sent with API requests (can be repeated)", func(value string) error {
+		key, val, err := parseHeader(value)
+		if err != nil {
+			return err
+		}
+		config.headers.Add(key, val)
+		return nil
+	})
+
+	flag.Func("proxy", "Proxy URL for API requests, e.g. http://proxy.corp:3128 (default $HTTPS_PROXY)", func(value string) error {
+		proxy, err := parseProxy(value)
+		config.proxy = proxy
+		return err
+	})
+	flag.StringVar(&config.caCertFile, "ca-cert", "", "PEM file of extra CA certificates to trust for API requests")
+	flag.Func("tls-min-version", "Minimum TLS version for API requests: 1.2 or 1.3", func(value string) error {
+		if _, ok := tlsVersions[value]; !ok {
+			return fmt.Errorf("unsupported TLS version %q (expected 1.2 or 1.3)", value)
+		}
+		config.tlsMinVersion = value
+		return nil
+	})
+	flag.Func("backend", "Backend for the gemini provider: gemini (Gemini API) or vertex (Vertex AI) (default vertex when $GOOGLE_GENAI_USE_VERTEXAI is set)", func(value string) error {
+		if value != backendGemini && value != backendVertex {
+			return fmt.Errorf("unknown backend %q (expected gemini or vertex)", value)
+		}
+		config.backend = value
+		return nil
+	})
+	flag.StringVar(&config.project, "project", "", "Google Cloud project for -backend vertex (default $GOOGLE_CLOUD_PROJECT)")
+	flag.StringVar(&config.location, "location", "", "Google Cloud region for -backend vertex (default $GOOGLE_CLOUD_LOCATION or "+search.DefaultVertexLocation+")")
+	flag.Func("api-key", "Gemini API key to rotate through (can be repeated; default $GOSEARCH_API_KEYS)", func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("empty API key")
+		}
+		config.apiKeys = append(config.apiKeys, strings.TrimSpace(value))
+		return nil
+	})
+	config.keyRotation = rotationFailover
+	flag.Func("key-rotation", "How requests use several API keys: failover (switch when a key hits its quota) or round-robin", func(value string) error {
+		if value != rotationFailover && value != rotationRoundRobin {
+			return fmt.Errorf("unknown key rotation %q (expected failover or round-robin)", value)
+		}
+		config.keyRotation = value
+		return nil
+	})
+	flag.StringVar(&config.out, "out", "", "Also write the result to this Markdown file with YAML front matter (single query)")
+	flag.StringVar(&config.outDir, "out-dir", "", "Also write each result to its own Markdown file in this directory")
+	flag.StringVar(&config.outputFile, "output-file", "", "Write the output to this file instead of stdout; gzipped when the name ends in .gz")
+	flag.BoolVar(&config.compress, "compress", false, "Gzip -output-file, adding .gz to its name")
+	flag.StringVar(&config.report, "report", "", "Also write a multi-query run to this standalone HTML report")
+	flag.Func("email", "Email the HTML report of a multi-query run to these comma-separated addresses, through the smtp settings of the config file (can be repeated)", func(value string) error {
+		for _, address := range strings.Split(value, ",") {
+			address = strings.TrimSpace(address)
+			if _, err := mail.ParseAddress(address); err != nil {
+				return fmt.Errorf("invalid address %q: %w", address, err)
+			}
+			config.email = append(config.email, address)
+		}
+		return nil
+	})
+	flag.BoolVar(&config.noHistory, "no-history", false, "Do not record searches in the history database")
+	flag.Func("session", "Record searches under this named session", func(value string) error {
+		config.session = value
+		return validateSessionName(value)
+	})
+	flag.Func("resume", "Continue this named session with its earlier answers as context (with a query or -interactive)", func(value string) error {
+		config.session = value
+		config.resume = true
+		return validateSessionName(value)
+	})
+	flag.StringVar(&config.historyDB, "history-db", "", "History database (default ~/.local/share/go-search/history.db)")
+	flag.BoolVar(&config.noCache, "no-cache", false, "Always call the API instead of reusing cached responses")
+	flag.DurationVar(&config.cacheTTL, "cache-ttl", time.Hour, "How long cached responses are reused")
+	flag.BoolVar(&config.useContextCache, "use-context-cache", false, "Cache the system prompt and attachments with Gemini context caching, so each query sends only itself")
+	flag.DurationVar(&config.contextCacheTTL, "context-cache-ttl", time.Hour, "How long the -use-context-cache cache lives")
+	flag.BoolVar(&config.fromClipboard, "from-clipboard", false, "Use the clipboard contents as the query")
+	flag.BoolVar(&config.toClipboard, "to-clipboard", false, "Copy the answer, or the summary with -include-summary, to the clipboard (single query)")
+	flag.StringVar(&config.queryTemplate, "template", "", "Query template such as \"latest release of {{.name}}\", expanded once per -vars row")
+	flag.StringVar(&config.templateVars, "vars", "", "CSV file with a header line or JSON array of objects supplying -template variables (- for stdin)")
+	flag.StringVar(&config.queriesFile, "queries-file", "", "Read newline-delimited queries from this file (- for stdin)")
+	flag.StringVar(&config.serve, "serve", "", "Serve the search API over HTTP on this address (e.g. :8080) instead of running a query")
+	flag.StringVar(&config.grpc, "grpc", "", "Serve the search API over gRPC on this address (e.g. :9090) instead of running a query")
+	flag.BoolVar(&config.useDaemon, "use-daemon", false, "Send the queries to a running go-search daemon instead of calling the API")
+	flag.StringVar(&config.socket, "socket", "", "Unix socket of the daemon (default $XDG_RUNTIME_DIR/go-search.sock)")
+	flag.Func("follow-up", "Ask this query as a follow-up to the most recent search in history", func(value string) error {
+		config.followUp = true
+		config.query = value
+		return nil
+	})
+	flag.Func("thinking-budget", fmt.Sprintf("Thinking budget in tokens, 0 to disable thinking and -1 to let the model decide (default %d)", search.DefaultThinkingBudget), func(value string) error {
+		budget, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || budget < -1 {
+			return fmt.Errorf("expected an integer >= -1")
+		}
+		config.thinkingBudget = int32(budget)
+		return nil
+	})
+	flag.BoolVar(&config.showThinking, "show-thinking", false, "Print the model's reasoning, dimmed, before the answer in -stream and -interactive mode")
+	flag.BoolVar(&config.deep, "deep", false, "Research the query in depth: plan sub-questions, search them concurrently, and write a cited report")
+	flag.IntVar(&config.deepQuestions, "deep-questions", search.DefaultDeepQuestions, "Maximum sub-questions planned with -deep")
+	flag.IntVar(&config.samples, "samples", 1, "Search a single query this many times concurrently and keep the best answer, or a merge")
+	flag.BoolVar(&config.interactive, "interactive", false, "Start an interactive session that keeps earlier answers as context")
+	flag.StringVar(&config.templateFile, "template-file", "", "Go text/template file that renders the results with -format template")
+	flag.StringVar(&config.schemaFile, "schema", "", "JSON Schema file; return the answer as validated JSON matching it")
+	flag.StringVar(&config.systemPromptFile, "system-prompt", "", "File replacing the search system prompt (default ~/.config/go-search/prompts/system.txt if present)")
+	flag.StringVar(&config.summaryPromptFile, "summary-prompt", "", "File replacing the summary prompt (default ~/.config/go-search/prompts/summary.txt if present)")
+	flag.BoolVar(&config.showCost, "show-cost", false, "Print token usage and estimated cost after the results")
+	flag.BoolVar(&config.classify, "classify", false, "Label each query with an intent category and confidence")
+	flag.BoolVar(&config.autoRewrite, "auto-rewrite", false, "When a search stays empty after retries, have the model rephrase the query and search once more")
+	flag.BoolVar(&config.summaryFallback, "summary-fallback", false, "Retry a failed summary once with a simpler prompt and shorter input")
+	flag.StringVar(&config.streamSummary, "stream-summary", streamSummaryAfter, "When to summarize in -stream mode: after (full response, once the stream closes) or early (start on the partial response while streaming)")
+	flag.BoolVar(&config.concat, "concat", false, "Output only the raw multi-query responses joined by -delimiter")
+	flag.StringVar(&config.delimiter, "delimiter", `\n\n---\n\n`, "Separator placed between responses with -concat (supports \\n and \\t)")
+	flag.BoolVar(&config.onlySucceeded, "only-succeeded", false, "Skip failed queries in -concat output")
+	flag.BoolVar(&config.tui, "tui", false, "Show live per-query progress for -q queries, then page through the results")
+	flag.DurationVar(&config.watch, "watch", 0, "Re-run the query on this interval and print only answers that changed (e.g. 1h)")
+	flag.Float64Var(&config.watchThreshold, "watch-threshold", 0.8, "Similarity (0-1) below which a -watch answer counts as changed")
+	flag.Float64Var(&config.recallThreshold, "recall-threshold", 0.8, "Similarity (0-1) an earlier answer needs for recall to use it instead of searching")
+	flag.IntVar(&config.recallLimit, "recall-limit", 3, "Maximum number of earlier answers recall shows")
+	flag.BoolFunc("auto-model", "Send each query to -simple-model or -complex-model by how complex a cheap model call rates it (heuristic to rate by wording alone)", func(value string) error {
+		mode, err := parseAutoModel(value)
+		config.autoModel = mode
+		return err
+	})
+	flag.StringVar(&config.simpleModel, "simple-model", search.DefaultModel, "Model -auto-model uses for simple factual queries")
+	flag.StringVar(&config.complexModel, "complex-model", "gemini-2.5-pro", "Model -auto-model uses for complex analytical queries")
+	flag.DurationVar(&config.freshness, "freshness", 0, "Reuse the latest answer to the same query from history when it is newer than this (e.g. 24h), otherwise search again")
+	flag.IntVar(&config.benchRuns, "n", 10, "Number of times bench runs the query")
+	flag.BoolVar(&config.benchSimilarity, "bench-similarity", false, "Also report how similar the answers of bench runs are to each other")
+	flag.BoolVar(&config.dryRun, "dry-run", false, "Print the search request for each query as JSON instead of calling the API")
+	flag.StringVar(&config.diffWith, "diff-with", "", "After the answer, print a diff against this earlier search from history")
+	flag.BoolVar(&config.wordDiff, "word-diff", false, "Diff word by word instead of line by line with -diff-with")
+	flag.StringVar(&config.safety, "safety", search.SafetyDefault, "Gemini safety filter preset: block_none, default, or strict")
+	flag.Func("safety-category", "Safety threshold for one category as category=threshold, applied on top of -safety (can be repeated)", func(value string) error {
+		category, threshold, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("expected category=threshold, got %q", value)
+		}
+		if config.safetyOverrides == nil {
+			config.safetyOverrides = map[string]string{}
+		}
+		config.safetyOverrides[strings.TrimSpace(category)] = strings.TrimSpace(threshold)
+		return nil
+	})
+	flag.Func("image", "Attach this image (PNG, JPEG, WebP, HEIC or HEIF) to the query (can be repeated)", func(value string) error {
+		config.imagePaths = append(config.imagePaths, value)
+		return nil
+	})
+	flag.Func("file", "Attach this document (PDF or text) to the query as context (can be repeated)", func(value string) error {
+		config.documentPaths = append(config.documentPaths, value)
+		return nil
+	})
+	flag.Func("docs", "Search this directory or file of Markdown, text and PDF notes, and send the most relevant excerpts with each query (can be repeated)", func(value string) error {
+		config.notePaths = append(config.notePaths, value)
+		return nil
+	})
+	flag.IntVar(&config.noteChunks, "docs-chunks", search.DefaultNoteChunks, "Note excerpts sent with each query with -docs")
+	flag.Func("site", "Limit sources by domain as include:<domain> or exclude:<domain>, comma-separated (can be repeated)", config.sites.Add)
+	flag.Func("style", "Answer style: "+strings.Join(search.Styles, ", "), func(value string) error {
+		config.style = value
+		return search.ValidateStyle(value)
+	})
+	// Later values add to the list, so the config file can give one per item
+	flag.Func("tools", "Comma-separated tools the model may use: "+strings.Join(search.Tools, ", ")+", or none to answer without searching (default "+strings.Join(search.DefaultTools, ",")+")", func(value string) error {
+		if !config.toolsSet {
+			config.tools = []string{}
+			config.toolsSet = true
+		}
+		tools := strings.Split(value, ",")
+		for _, tool := range tools {
+			tool = strings.TrimSpace(tool)
+			if tool == "none" && len(tools) > 1 {
+				return fmt.Errorf("none cannot be combined with other tools")
+			}
+			if tool == "" || tool == "none" {
+				continue
+			}
+			if err := search.ValidateTools([]string{tool}); err != nil {
+				return err
+			}
+			if !slices.Contains(config.tools, tool) {
+				config.tools = append(config.tools, tool)
+			}
+		}
+		return nil
+	})
+	flag.BoolVar(&config.enableLocalTools, "enable-local-tools", false, "Let the model run the commands declared under local-tools in the config file")
+	flag.IntVar(&config.maxContinuations, "max-continuations", search.DefaultMaxContinuations, "Requests allowed to continue an answer cut off at the output token limit (0 to keep it truncated)")
+	flag.IntVar(&config.maxWords, "max-words", 0, "Ask for answers under this many words and cap output tokens to match (0 for no limit)")
+	flag.StringVar(&config.lang, "lang", "", "Language code for answers and text output headings, e.g. es or pt-BR (default $GOSEARCH_LANG)")
+	flag.StringVar(&config.webhook, "webhook", "", "POST the JSON result to this URL when the run completes")
+	flag.StringVar(&config.webhookSecret, "webhook-secret", "", "Sign -webhook deliveries with HMAC-SHA256 using this secret (default $GOSEARCH_WEBHOOK_SECRET)")
+	flag.StringVar(&config.slackWebhook, "slack-webhook", "", "Post the result to Slack through this incoming webhook URL when the run completes")
+	flag.StringVar(&config.postHook, "post-hook", "", "Run this command after each query with the JSON result on stdin")
+	flag.StringVar(&config.promptLog, "prompt-log", "", "Append every prompt sent to the API to this JSONL file")
+	flag.StringVar(&config.auditLog, "audit-log", "", "Append every request and response to this JSONL file, with API keys, tokens and email addresses redacted")
+	flag.Func("audit-redact", "Also redact matches of this regular expression from the audit log (can be repeated)", func(value string) error {
+		if _, err := regexp.Compile(value); err != nil {
+			return err
+		}
+		config.auditRedact = append(config.auditRedact, value)
+		return nil
+	})
+	flag.StringVar(&config.logFile, "log-file", "", "Write logs to this file instead of stderr, rotated at 10MB (includes debug records with -v)")
+	flag.StringVar(&config.logFormat, "log-format", logFormatText, "Log format: text or json")
+	flag.BoolVar(&config.outputOnError, "output-on-error", false, "Write a JSON error object to stdout when the run fails")
+	flag.BoolVar(&config.inlineCitations, "inline-citations", false, "Cite sources with numbered footnote markers and a trailing sources list")
+	flag.BoolVar(&config.sweepThinking, "sweep-thinking", false, "Run the query at several thinking budgets and compare latency, tokens and responses")
+	flag.Func("sweep-budgets", "Comma-separated thinking budgets used by -sweep-thinking (default 0,256,512,1024)", func(value string) error {
+		budgets, err := parseBudgets(value)
+		if err != nil {
+			return err
+		}
+		config.sweepBudgets = budgets
+		return nil
+	})
+
+	flag.Func("compare", "Comma-separated models to run the query against in parallel, e.g. gemini-2.5-flash,gemini-2.5-pro", func(value string) error {
+		config.compareModels = nil
+		for _, model := range strings.Split(value, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				config.compareModels = append(config.compareModels, model)
+			}
+		}
+		if len(config.compareModels) < 2 {
+			return fmt.Errorf("need at least two models to compare")
+		}
+		return nil
+	})
+
 	// Custom flag for multiple queries
 	flag.Func("q", "Search query (can be repeated)", func(value string) error {
 		config.queries = append(config.queries, value)
 		return nil
 	})
+	flag.Func("tag", "Tag results with key=value, kept in JSON and CSV output (can be repeated)", func(value string) error {
+		key, val, err := parseTag(value)
+		if err != nil {
+			return err
+		}
+		if config.tags == nil {
+			config.tags = make(map[string]string)
+		}
+		config.tags[key] = val
+		return nil
+	})
+	flag.Func("u", "Page to summarize instead of searching (can be repeated)", func(value string) error {
+		config.urls = append(config.urls, value)
+		return nil
+	})
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [query]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [query]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s auth check [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s cache clear\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s completion bash|zsh|fish\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff [-word-diff] <history-id-1> <history-id-2>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s history [search <term> | show <id>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s sessions [list | show <name>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s mcp [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s url [options] <url>...\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "A CLI search engine powered by Gemini AI\n\n")
 		fmt.Fprintf(os.Stderr, "Note: When using positional arguments, flags must come BEFORE the query.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
@@ -77,15 +571,84 @@ func parseFlags() *Config {
 		fmt.Fprintf(os.Stderr, "  %s -q \"Go\" -q \"Python\" -q \"Rust\"\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -q \"Go\" -q \"Python\" -include-summary=false\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -stream \"What is Go programming?\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -queries-file queries.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  cat queries.txt | %s -json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -schema companies.json \"Largest EV makers by 2025 sales\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -profile work \"What is Go programming?\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -provider openai \"What is Go programming?\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -follow-up \"And how does it compare to Rust?\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s url https://go.dev/blog/go1.24\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -interactive\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -serve :8080\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -grpc :9090\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -sweep-thinking \"What is Go programming?\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -compare gemini-2.5-flash,gemini-2.5-pro \"What is Go programming?\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -header \"X-Gateway-Route=search\" \"What is Go programming?\"\n", os.Args[0])
 	}
+	return config
+}
 
+func parseFlags() *Config {
+	config := registerFlags()
 	flag.Parse()
 
+	// Config file values fill in anything not given on the command line
+	configPath, explicit := config.configPath, config.configPath != ""
+	if !explicit {
+		configPath = defaultConfigPath()
+	}
+	file, err := applyConfigFile(configPath, explicit, config.profile)
+	if err != nil {
+		handleConfigError(err, "Invalid config file")
+	}
+	config.localTools = file.LocalTools
+	config.smtp = file.SMTP
+
 	// Handle positional argument
 	if config.query == "" && len(config.queries) == 0 && len(flag.Args()) > 0 {
 		config.query = flag.Args()[0]
 	}
 
+	// Model precedence: flag, then environment, then the built-in default
+	if config.model == "" {
+		config.model = os.Getenv("GOSEARCH_MODEL")
+	}
+	if config.model == "" {
+		config.model = search.DefaultModelFor(config.provider)
+	}
+
+	// genai's own switch to Vertex AI, unless -backend says otherwise
+	if config.backend == "" {
+		config.backend = backendGemini
+		if v := strings.ToLower(os.Getenv("GOOGLE_GENAI_USE_VERTEXAI")); config.provider == search.ProviderGemini && (v == "1" || v == "true") {
+			config.backend = backendVertex
+		}
+	}
+
+	if config.summaryWorkers == 0 {
+		config.summaryWorkers = config.workers
+	}
+	if !config.continueOnError {
+		config.failFast = true
+	}
+	if !config.renderExplicit {
+		config.render = isTerminal(os.Stdout) && config.outputFile == ""
+	}
+	if config.lang == "" {
+		config.lang = os.Getenv("GOSEARCH_LANG")
+	}
+	if config.webhookSecret == "" {
+		config.webhookSecret = os.Getenv("GOSEARCH_WEBHOOK_SECRET")
+	}
+
+	config.delimiter = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(config.delimiter)
+
+	return config
+}
+
+// applySummaryDefault sets the includeSummary smart default once every
+// query source has been read
+func applySummaryDefault(config *Config) {
 	// Set smart defaults for includeSummary if not explicitly set by user
 	if !config.includeSummaryExplicit {
 		totalQueries := 0
@@ -93,7 +656,7 @@ func parseFlags() *Config {
 			totalQueries++
 		}
 		totalQueries += len(config.queries)
-		
+
 		if totalQueries == 1 {
 			// Single query (either positional or single -q): summary OFF by default
 			config.includeSummary = false
@@ -102,43 +665,365 @@ func parseFlags() *Config {
 			config.includeSummary = true
 		}
 	}
-
-	return config
 }
 
 func validateConfig(config *Config) error {
 	hasQuery := config.query != ""
 	hasQueries := len(config.queries) > 0
 
-	if !hasQuery && !hasQueries {
-		return fmt.Errorf("search query is required (use -query, -q, or positional argument)")
+	if config.backend == backendVertex {
+		if config.provider != search.ProviderGemini {
+			return fmt.Errorf("-backend vertex requires the gemini provider")
+		}
+		if config.project == "" && os.Getenv("GOOGLE_CLOUD_PROJECT") == "" {
+			return fmt.Errorf("-backend vertex requires -project or GOOGLE_CLOUD_PROJECT")
+		}
+		if config.authCheck {
+			return fmt.Errorf("auth check verifies Gemini API keys; Vertex AI uses Application Default Credentials")
+		}
+	} else if config.project != "" || config.location != "" {
+		return fmt.Errorf("-project and -location require -backend vertex")
+	}
+	if config.urlMode && len(config.urls) == 0 {
+		return fmt.Errorf("url requires at least one URL")
+	}
+	if len(config.urls) > 0 {
+		if len(config.sites.Include) > 0 || len(config.sites.Exclude) > 0 {
+			return fmt.Errorf("-site only applies to searches, not URL summaries")
+		}
+		for _, u := range config.urls {
+			if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("invalid URL %q: must be an absolute http or https URL", u)
+			}
+		}
+	}
+	if !hasQuery && !hasQueries && len(config.urls) == 0 && !config.interactive && config.serve == "" && config.grpc == "" && !config.mcp && !config.daemon && !config.authCheck {
+		return fmt.Errorf("search query is required (use -query, -q, -queries-file, stdin, or positional argument)")
 	}
 	if hasQuery && hasQueries {
 		return fmt.Errorf("cannot use both -query and -q flags simultaneously")
 	}
+	if err := checkFlagConflicts(config); err != nil {
+		return err
+	}
+	if _, ok := search.ProviderDefaultModels[config.provider]; !ok {
+		return fmt.Errorf("unknown provider %q (known: gemini, openai, anthropic, ollama)", config.provider)
+	}
+	// Only Gemini model names are checked; other providers accept any name
+	if config.provider == search.ProviderGemini && !config.modelAllowAny && !search.IsKnownModel(config.model) {
+		return fmt.Errorf("unknown model %q (known: %s; use -model-allow-any to override)", config.model, strings.Join(search.KnownModels, ", "))
+	}
+	if config.maxRetries < 0 {
+		return fmt.Errorf("max-retries cannot be negative")
+	}
+	if config.timeoutGrace < 0 {
+		return fmt.Errorf("timeout-grace cannot be negative")
+	}
+	if config.queryTimeout < 0 {
+		return fmt.Errorf("query-timeout cannot be negative")
+	}
+	if config.attemptTimeout < 0 {
+		return fmt.Errorf("attempt-timeout cannot be negative")
+	}
+	if config.retryDelay < 0 {
+		return fmt.Errorf("retry-delay cannot be negative")
+	}
+	if problem := retryBudgetProblem(config); problem != "" {
+		if config.strict {
+			return fmt.Errorf("%s", problem)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", problem)
+	}
+	if config.rpm < 0 {
+		return fmt.Errorf("rpm cannot be negative")
+	}
+	if config.enableLocalTools {
+		if len(config.localTools.Tools) == 0 {
+			return fmt.Errorf("-enable-local-tools requires tools under local-tools in the config file")
+		}
+		if config.provider != search.ProviderGemini {
+			return fmt.Errorf("-enable-local-tools is only supported by the gemini provider")
+		}
+		if _, err := config.localTools.searchTools(config.quiet); err != nil {
+			return err
+		}
+	}
+	if config.maxContinuations < 0 {
+		return fmt.Errorf("max-continuations cannot be negative")
+	}
+	if config.maxWords < 0 {
+		return fmt.Errorf("max-words cannot be negative")
+	}
+	if config.retryBudget < 0 {
+		return fmt.Errorf("retry-budget cannot be negative")
+	}
+	if config.cacheTTL <= 0 {
+		return fmt.Errorf("cache-ttl must be positive")
+	}
+	if config.useContextCache && config.provider != search.ProviderGemini {
+		return fmt.Errorf("-use-context-cache requires the gemini provider")
+	}
+	if config.contextCacheTTL <= 0 {
+		return fmt.Errorf("context-cache-ttl must be positive")
+	}
 	if config.workers < 1 || config.workers > 5 {
 		return fmt.Errorf("workers must be between 1 and 5")
 	}
-	if config.stream && hasQueries {
-		return fmt.Errorf("streaming mode is not supported for multiple queries (use single query only)")
+	if config.summaryWorkers < 1 || config.summaryWorkers > 5 {
+		return fmt.Errorf("summary-workers must be between 1 and 5")
+	}
+	if config.streamSummary != streamSummaryAfter && config.streamSummary != streamSummaryEarly {
+		return fmt.Errorf("stream-summary must be %q or %q", streamSummaryAfter, streamSummaryEarly)
+	}
+	if !slices.Contains(outputFormats, config.format) {
+		return fmt.Errorf("unknown format %q (known: %s)", config.format, strings.Join(outputFormats, ", "))
+	}
+	if config.logFormat != logFormatText && config.logFormat != logFormatJSON {
+		return fmt.Errorf("unknown log format %q (known: %s, %s)", config.logFormat, logFormatJSON, logFormatText)
+	}
+	if (config.format == formatTemplate) != (config.templateFile != "") {
+		return fmt.Errorf("-format template and -template-file must be used together")
+	}
+	if config.stream && config.format != formatText && config.format != formatJSON && config.format != formatJSONL {
+		return fmt.Errorf("-stream supports -format text, or json and jsonl for NDJSON events")
+	}
+	if config.watch != 0 && config.watch < time.Second {
+		return fmt.Errorf("-watch interval must be at least 1s")
+	}
+	if _, err := search.SafetySettings(config.safety, config.safetyOverrides); err != nil {
+		return err
+	}
+	if (config.safety != search.SafetyDefault || len(config.safetyOverrides) > 0) && config.provider != search.ProviderGemini {
+		return fmt.Errorf("-safety and -safety-category only apply to the gemini provider")
+	}
+	if len(config.imagePaths) > 0 && config.provider != search.ProviderGemini {
+		return fmt.Errorf("-image only applies to the gemini provider")
+	}
+	if len(config.documentPaths) > 0 && config.provider != search.ProviderGemini {
+		return fmt.Errorf("-file only applies to the gemini provider")
+	}
+	if len(config.notePaths) > 0 && config.provider != search.ProviderGemini {
+		return fmt.Errorf("-docs uses Gemini embeddings and requires the gemini provider")
+	}
+	if config.noteChunks < 1 {
+		return fmt.Errorf("docs-chunks must be at least 1")
+	}
+	if config.diffWith != "" && config.format != formatText {
+		return fmt.Errorf("-diff-with only supports text output")
+	}
+	if config.watchThreshold < 0 || config.watchThreshold > 1 {
+		return fmt.Errorf("watch-threshold must be between 0 and 1")
+	}
+	if config.recall && config.provider != search.ProviderGemini {
+		return fmt.Errorf("recall uses Gemini embeddings and requires the gemini provider")
+	}
+	if config.autoModel != "" {
+		if config.provider != search.ProviderGemini {
+			return fmt.Errorf("-auto-model routes between Gemini models and requires the gemini provider")
+		}
+		for _, model := range []string{config.simpleModel, config.complexModel} {
+			if !config.modelAllowAny && !search.IsKnownModel(model) {
+				return fmt.Errorf("unknown model %q for -auto-model (known: %s; use -model-allow-any to override)", model, strings.Join(search.KnownModels, ", "))
+			}
+		}
+	}
+	if config.freshness < 0 {
+		return fmt.Errorf("freshness must not be negative")
+	}
+	if config.bench && config.format != formatText && config.format != formatJSON && config.format != formatJSONL && config.format != formatYAML {
+		return fmt.Errorf("bench prints text, json, jsonl or yaml, not %s", config.format)
+	}
+	if config.benchRuns < 1 {
+		return fmt.Errorf("n must be at least 1")
+	}
+	if config.recallThreshold < 0 || config.recallThreshold > 1 {
+		return fmt.Errorf("recall-threshold must be between 0 and 1")
+	}
+	if config.recallLimit < 1 {
+		return fmt.Errorf("recall-limit must be at least 1")
+	}
+	if config.webhook != "" {
+		if u, err := url.Parse(config.webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("-webhook must be an http or https URL, got %q", config.webhook)
+		}
+	}
+	if config.slackWebhook != "" {
+		if u, err := url.Parse(config.slackWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("-slack-webhook must be an http or https URL")
+		}
+	}
+	if config.tui && !isTerminal(os.Stdout) {
+		return fmt.Errorf("-tui requires a terminal on stdout")
+	}
+	if config.showThinking && (!(config.stream || config.interactive) || config.thinkingBudget == 0) {
+		return fmt.Errorf("-show-thinking requires -stream or -interactive and a nonzero -thinking-budget")
+	}
+	if config.deepQuestions < 1 {
+		return fmt.Errorf("deep-questions must be at least 1")
+	}
+	if len(config.email) > 0 {
+		if err := config.smtp.validate(); err != nil {
+			return err
+		}
+	}
+	if config.samples < 1 || config.samples > maxSamples {
+		return fmt.Errorf("samples must be between 1 and %d", maxSamples)
+	}
+	for _, model := range config.compareModels {
+		if config.provider == search.ProviderGemini && !config.modelAllowAny && !search.IsKnownModel(model) {
+			return fmt.Errorf("unknown model %q in -compare (known: %s; use -model-allow-any to override)", model, strings.Join(search.KnownModels, ", "))
+		}
 	}
 	return nil
 }
 
-func setupLogger(verbose bool) {
+// retryBudgetProblem explains, with adjusted values, when failing attempts
+// and the waits between them outlast the timeout of the call, so the last
+// retries could never run. It is empty when they fit, or when no
+// -attempt-timeout bounds an attempt.
+func retryBudgetProblem(config *Config) string {
+	if config.attemptTimeout <= 0 || config.maxRetries == 0 {
+		return ""
+	}
+	name, budget := "-timeout", config.timeout
+	if config.queryTimeout > 0 && config.queryTimeout < budget {
+		name, budget = "-query-timeout", config.queryTimeout
+	}
+
+	policy := search.DefaultRetryPolicy()
+	policy.BaseDelay = config.retryDelay
+	worst := func(retries int) time.Duration {
+		policy.Attempts = retries + 1
+		return time.Duration(policy.Attempts)*config.attemptTimeout + policy.Backoff()
+	}
+	needed := worst(config.maxRetries)
+	if needed <= budget {
+		return ""
+	}
+
+	problem := fmt.Sprintf("with -max-retries %d, -attempt-timeout %s and -retry-delay %s a failing call can take %s, more than %s %s allows; raise %s to %s",
+		config.maxRetries, config.attemptTimeout, config.retryDelay, needed, name, budget, name, needed)
+	fits := config.maxRetries - 1
+	for fits > 0 && worst(fits) > budget {
+		fits--
+	}
+	if worst(fits) <= budget {
+		problem += fmt.Sprintf(" or lower -max-retries to %d", fits)
+	}
+	// Shorter attempts, keeping the retries and their waits
+	policy.Attempts = config.maxRetries + 1
+	if spare := budget - policy.Backoff(); spare >= time.Second*time.Duration(policy.Attempts) {
+		problem += fmt.Sprintf(" or -attempt-timeout to %s", (spare / time.Duration(policy.Attempts)).Truncate(time.Second))
+	}
+	return problem
+}
+
+// loadSchema reads a JSON Schema document for -schema
+func loadSchema(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("schema must be a JSON object: %w", err)
+	}
+	return schema, nil
+}
+
+func parseBudgets(value string) ([]int32, error) {
+	var budgets []int32
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		budget, err := strconv.ParseInt(field, 10, 32)
+		if err != nil || budget < -1 {
+			return nil, fmt.Errorf("invalid thinking budget %q (expected an integer >= -1)", field)
+		}
+		budgets = append(budgets, int32(budget))
+	}
+	if len(budgets) == 0 {
+		return nil, fmt.Errorf("at least one thinking budget is required")
+	}
+	return budgets, nil
+}
+
+// setupLogger logs errors, or everything with -v, to stderr. With
+// -log-file the full log goes to the file instead, including debug records
+// with -v. The returned file is nil without -log-file.
+func setupLogger(config *Config) (*rotatingFile, error) {
 	level := slog.LevelError
-	if verbose {
+	if config.verbose {
 		level = slog.LevelInfo
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: level,
-	}))
-	slog.SetDefault(logger)
+	var out io.Writer = os.Stderr
+	var file *rotatingFile
+	if config.logFile != "" {
+		var err error
+		if file, err = openRotatingFile(config.logFile); err != nil {
+			return nil, err
+		}
+		out = file
+		level = slog.LevelInfo
+		if config.verbose {
+			level = slog.LevelDebug
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(out, opts)
+	if config.logFormat == logFormatJSON {
+		handler = slog.NewJSONHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(requestIDHandler{handler}))
+	return file, nil
 }
 
+// ErrorOutput is written to stdout on fatal errors when -output-on-error is set
+type ErrorOutput struct {
+	Success bool             `json:"success"`
+	Error   string           `json:"error"`
+	Code    search.ErrorCode `json:"error_code,omitempty"`
+	Context string           `json:"context"`
+	Result  any              `json:"result,omitempty"`
+}
+
+// Set from -output-on-error once flags are parsed
+var outputOnError bool
+
 func handleError(err error, context string) {
+	handleErrorWithResult(err, context, nil)
+}
+
+// handleConfigError reports an invalid flag, file or query source and exits
+// with exitConfig
+func handleConfigError(err error, context string) {
+	exitWithError(err, codeConfig, context, nil)
+}
+
+// handleErrorWithResult reports a fatal error and exits, including any
+// partial result in the JSON error object when -output-on-error is set
+func handleErrorWithResult(err error, context string, result any) {
+	exitWithError(err, search.Code(err), context, result)
+}
+
+func exitWithError(err error, code search.ErrorCode, context string, result any) {
 	slog.Error(context, "error", err)
 	fmt.Fprintf(os.Stderr, "Error: %s: %v\n", context, err)
-	os.Exit(1)
-}
\ No newline at end of file
+
+	if outputOnError {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(ErrorOutput{
+			Success: false,
+			Error:   err.Error(),
+			Code:    code,
+			Context: context,
+			Result:  result,
+		})
+	}
+	exit(exitCodeFor(code))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSettings maps flag names (without the dash) to values
+type fileSettings map[string]any
+
+// configFile is the layout of config.yaml: top-level defaults plus named
+// profiles that are applied on top of them
+type configFile struct {
+	Defaults   fileSettings            `yaml:",inline"`
+	Profiles   map[string]fileSettings `yaml:"profiles"`
+	LocalTools localToolsConfig        `yaml:"local-tools"`
+	SMTP       smtpConfig              `yaml:"smtp"`
+}
+
+// Flags that only make sense on the command line
+var configFileSkip = map[string]bool{
+	"config":  true,
+	"profile": true,
+}
+
+// defaultConfigPath returns ~/.config/go-search/config.yaml, or the
+// platform's equivalent user config directory
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-search", "config.yaml")
+}
+
+// applyConfigFile sets every flag named in the config file, and then in the
+// selected profile, unless it was already given on the command line, and
+// returns the file's sections that are not flags.
+// A missing file is only an error when it was asked for explicitly.
+func applyConfigFile(path string, explicit bool, profile string) (*configFile, error) {
+	file := &configFile{}
+	if path == "" {
+		if profile != "" {
+			return nil, fmt.Errorf("profile %q requested but no config directory is available", profile)
+		}
+		return file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit && profile == "" {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	settings := fileSettings{}
+	for name, value := range file.Defaults {
+		settings[name] = value
+	}
+	if profile != "" {
+		selected, ok := file.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found in %s", profile, path)
+		}
+		for name, value := range selected {
+			settings[name] = value
+		}
+	}
+
+	setOnCommandLine := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+	// GOSEARCH_MODEL sits between the flag and the config file
+	if os.Getenv("GOSEARCH_MODEL") != "" {
+		setOnCommandLine["model"] = true
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if configFileSkip[name] || setOnCommandLine[name] {
+			continue
+		}
+		if flag.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown option %q in %s", name, path)
+		}
+
+		// Lists set repeatable flags such as q and header once per item, and
+		// maps set key=value flags such as safety-category once per entry
+		var values []any
+		switch value := settings[name].(type) {
+		case []any:
+			values = value
+		case map[string]any:
+			for _, key := range sortedKeys(value) {
+				values = append(values, fmt.Sprintf("%s=%v", key, value[key]))
+			}
+		default:
+			values = []any{value}
+		}
+		for _, value := range values {
+			if err := flag.Set(name, fmt.Sprint(value)); err != nil {
+				return nil, fmt.Errorf("invalid value for %q in %s: %w", name, path, err)
+			}
+		}
+	}
+	return file, nil
+}
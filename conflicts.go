@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// flagUse is a flag or mode that conflict rules refer to, named as errors
+// mention it
+type flagUse struct {
+	name string
+	on   func(c *Config) bool
+}
+
+func hasSingleQuery(c *Config) bool { return c.query != "" && len(c.queries) == 0 }
+func hasAnyQuery(c *Config) bool    { return c.query != "" || len(c.queries) > 0 }
+func hasQueries(c *Config) bool     { return len(c.queries) > 0 }
+
+// Uses that rules exclude or require
+var (
+	useQueries      = flagUse{"queries", hasAnyQuery}
+	useMultiple     = flagUse{"multiple queries", hasQueries}
+	useOtherQueries = flagUse{"other queries", func(c *Config) bool { return hasQueries(c) || len(flag.Args()) > 0 }}
+	useURLs         = flagUse{"URLs", func(c *Config) bool { return len(c.urls) > 0 }}
+	useInteractive  = flagUse{"-interactive", func(c *Config) bool { return c.interactive }}
+	useServe        = flagUse{"-serve", func(c *Config) bool { return c.serve != "" }}
+	useGRPC         = flagUse{"-grpc", func(c *Config) bool { return c.grpc != "" }}
+	useMCP          = flagUse{"mcp mode", func(c *Config) bool { return c.mcp }}
+	useDaemonMode   = flagUse{"daemon mode", func(c *Config) bool { return c.daemon }}
+	useDaemonClient = flagUse{"-use-daemon", func(c *Config) bool { return c.useDaemon }}
+	useSweep        = flagUse{"-sweep-thinking", func(c *Config) bool { return c.sweepThinking }}
+	useConcat       = flagUse{"-concat", func(c *Config) bool { return c.concat }}
+	useFollowUp     = flagUse{"-follow-up", func(c *Config) bool { return c.followUp }}
+	useDeep         = flagUse{"-deep", func(c *Config) bool { return c.deep }}
+	useStream       = flagUse{"-stream", func(c *Config) bool { return c.stream }}
+	useClassify     = flagUse{"-classify", func(c *Config) bool { return c.classify }}
+	useRecall       = flagUse{"recall", func(c *Config) bool { return c.recall }}
+	useResume       = flagUse{"-resume", func(c *Config) bool { return c.resume }}
+	useSamples      = flagUse{"-samples", func(c *Config) bool { return c.samples > 1 }}
+	useCompare      = flagUse{"-compare", func(c *Config) bool { return len(c.compareModels) > 0 }}
+	useWatch        = flagUse{"-watch", func(c *Config) bool { return c.watch != 0 }}
+	useTUI          = flagUse{"-tui", func(c *Config) bool { return c.tui }}
+	useDryRun       = flagUse{"-dry-run", func(c *Config) bool { return c.dryRun }}
+	useDiffWith     = flagUse{"-diff-with", func(c *Config) bool { return c.diffWith != "" }}
+	useOut          = flagUse{"-out", func(c *Config) bool { return c.out != "" }}
+	useOutDir       = flagUse{"-out-dir", func(c *Config) bool { return c.outDir != "" }}
+	useSchema       = flagUse{"-schema", func(c *Config) bool { return c.schemaFile != "" }}
+	useNoHistory    = flagUse{"-no-history", func(c *Config) bool { return c.noHistory }}
+	useFreshness    = flagUse{"-freshness", func(c *Config) bool { return c.freshness > 0 }}
+	useBench        = flagUse{"bench", func(c *Config) bool { return c.bench }}
+	useContextCache = flagUse{"-use-context-cache", func(c *Config) bool { return c.useContextCache }}
+	useOutputFile   = flagUse{"-output-file", func(c *Config) bool { return c.outputFile != "" }}
+	useNoColor      = flagUse{"-no-color", func(c *Config) bool { return c.noColor }}
+	useFormat       = flagUse{"-format", func(c *Config) bool { return c.format != formatText }}
+	// -suggest streams its suggestions in -interactive mode only
+	useBareStream = flagUse{"-stream (outside -interactive)", func(c *Config) bool { return c.stream && !c.interactive }}
+
+	needSingleQuery = flagUse{"a single query", hasSingleQuery}
+	needQuery       = flagUse{"a query", hasAnyQuery}
+	needBatch       = flagUse{"multiple queries (-q, -queries-file or piped queries)", hasQueries}
+)
+
+// flagConflict is what one flag or mode needs and what it cannot be
+// combined with
+type flagConflict struct {
+	use      flagUse
+	requires *flagUse
+	excludes []flagUse
+	// Appended to the error, such as the flag to use instead
+	hint string
+}
+
+// longRunning are the modes that keep serving queries instead of running one
+// search or batch
+var longRunning = []flagUse{useInteractive, useServe, useGRPC, useMCP}
+
+func excluding(groups ...[]flagUse) []flagUse {
+	var uses []flagUse
+	for _, group := range groups {
+		uses = append(uses, group...)
+	}
+	return uses
+}
+
+var flagConflicts = []flagConflict{
+	{use: useFollowUp, excludes: []flagUse{useOtherQueries, useInteractive, useServe, useSweep, useNoHistory}},
+	{
+		use:      flagUse{"-session and -resume", func(c *Config) bool { return c.session != "" }},
+		excludes: []flagUse{useNoHistory, useServe, useGRPC, useMCP},
+	},
+	{
+		use:      useResume,
+		requires: &flagUse{"a single query or -interactive", func(c *Config) bool { return c.interactive || hasSingleQuery(c) }},
+		excludes: []flagUse{useFollowUp, useURLs, useDeep, useSweep, useCompare, useWatch},
+	},
+	{use: useServe, excludes: []flagUse{useQueries, useInteractive, useSweep, useConcat}},
+	{use: useGRPC, excludes: []flagUse{useQueries, useInteractive, useServe, useMCP, useSweep, useConcat}},
+	{use: useMCP, excludes: []flagUse{useQueries, useInteractive, useServe, useSweep, useFollowUp}},
+	{use: useURLs, excludes: []flagUse{useQueries, useInteractive, useServe, useMCP, useSweep, useDeep, useFollowUp, useStream, useClassify}},
+	{use: useDaemonMode, excludes: excluding([]flagUse{useQueries}, longRunning, []flagUse{useSweep, useDaemonClient, useDryRun})},
+	{
+		use:      useDaemonClient,
+		excludes: excluding([]flagUse{useURLs}, longRunning, []flagUse{useRecall, useFollowUp, useResume, useDeep, useSamples, useSweep, useCompare, useWatch, useTUI, useDryRun, useDiffWith}),
+	},
+	{use: useInteractive, excludes: []flagUse{useMultiple, useSweep, useConcat}},
+	{
+		use:      flagUse{"-queries-file", func(c *Config) bool { return c.queriesFile != "" }},
+		excludes: []flagUse{useInteractive, useServe, useGRPC},
+	},
+	{
+		use:      flagUse{"-audit-redact", func(c *Config) bool { return len(c.auditRedact) > 0 }},
+		requires: &flagUse{"-audit-log", func(c *Config) bool { return c.auditLog != "" }},
+	},
+	{
+		use:      flagUse{"-vars", func(c *Config) bool { return c.templateVars != "" }},
+		requires: &flagUse{"-template", func(c *Config) bool { return c.queryTemplate != "" }},
+	},
+	{use: flagUse{"-retry-budget", func(c *Config) bool { return c.retryBudget > 0 }}, requires: &needBatch},
+	{use: useStream, excludes: []flagUse{useMultiple}},
+	{use: useSchema, excludes: []flagUse{useStream, useInteractive, useSweep, useConcat}},
+	{use: useOut, excludes: []flagUse{useMultiple, useOutDir}, hint: "-out-dir writes one file per query"},
+	{use: useOut, excludes: []flagUse{useInteractive, useServe, useSweep}},
+	{use: useOutDir, excludes: []flagUse{useInteractive, useServe, useSweep}},
+	{use: flagUse{"-compress", func(c *Config) bool { return c.compress }}, requires: &useOutputFile},
+	{use: useOutputFile, excludes: excluding(longRunning, []flagUse{useTUI, useDaemonMode})},
+	{use: flagUse{"-format table", func(c *Config) bool { return c.format == formatTable }}, requires: &useCompare},
+	{use: useConcat, requires: &needBatch, excludes: []flagUse{useFormat}},
+	{
+		use:      useWatch,
+		requires: &needSingleQuery,
+		excludes: []flagUse{useURLs, useStream, useInteractive, useServe, useMCP, useSweep, useCompare, useDeep, useFollowUp, useOut, useOutDir},
+	},
+	{
+		use:      flagUse{"-image", func(c *Config) bool { return len(c.imagePaths) > 0 }},
+		excludes: excluding([]flagUse{useURLs}, longRunning, []flagUse{useDeep}),
+	},
+	{
+		use:      flagUse{"-file", func(c *Config) bool { return len(c.documentPaths) > 0 }},
+		excludes: excluding([]flagUse{useURLs}, longRunning, []flagUse{useDeep}),
+	},
+	{use: flagUse{"-docs", func(c *Config) bool { return len(c.notePaths) > 0 }}, excludes: []flagUse{useURLs, useDryRun}},
+	{use: useDiffWith, requires: &needSingleQuery, excludes: []flagUse{useURLs, useWatch, useCompare, useSweep, useNoHistory}},
+	{
+		use:      useDryRun,
+		requires: &needQuery,
+		excludes: excluding([]flagUse{useURLs}, longRunning, []flagUse{useFollowUp, useDeep, useSweep, useCompare, useWatch, useDiffWith}),
+	},
+	{
+		use:      flagUse{"-to-clipboard", func(c *Config) bool { return c.toClipboard }},
+		requires: &flagUse{"a single query or URL summary", func(c *Config) bool { return c.query != "" || len(c.urls) > 0 }},
+		excludes: []flagUse{useMultiple, useWatch, useCompare, useSweep, useDryRun},
+	},
+	{
+		use:      flagUse{"-post-hook", func(c *Config) bool { return c.postHook != "" }},
+		excludes: excluding(longRunning, []flagUse{useDryRun, useWatch, useCompare, useSweep}),
+	},
+	{use: flagUse{"-word-diff", func(c *Config) bool { return c.wordDiff }}, requires: &useDiffWith},
+	{
+		use:      useRecall,
+		requires: &needSingleQuery,
+		excludes: []flagUse{useURLs, useInteractive, useServe, useGRPC, useWatch, useCompare, useSweep, useFollowUp, useResume, useNoHistory},
+	},
+	{
+		use:      flagUse{"-auto-model", func(c *Config) bool { return c.autoModel != "" }},
+		requires: &needQuery,
+		excludes: excluding([]flagUse{useURLs}, longRunning, []flagUse{useDaemonMode, useDaemonClient, useWatch, useCompare, useSweep, useFollowUp, useResume, useRecall, useBench, useFreshness, useDryRun, useContextCache}),
+	},
+	{
+		use:      useFreshness,
+		requires: &needQuery,
+		excludes: excluding([]flagUse{useURLs}, longRunning, []flagUse{useDaemonMode, useDaemonClient, useWatch, useCompare, useSweep, useFollowUp, useResume, useRecall, useBench, useStream, useDryRun, useNoHistory}),
+	},
+	{
+		use:      useBench,
+		requires: &needSingleQuery,
+		excludes: excluding([]flagUse{useURLs}, longRunning, []flagUse{useDaemonMode, useDaemonClient, useWatch, useCompare, useSweep, useDeep, useSamples, useFollowUp, useResume, useRecall, useStream, useDryRun}),
+	},
+	{use: flagUse{"-webhook", func(c *Config) bool { return c.webhook != "" }}, excludes: longRunning},
+	{use: flagUse{"-slack-webhook", func(c *Config) bool { return c.slackWebhook != "" }}, excludes: longRunning},
+	{use: useTUI, requires: &needBatch, excludes: []flagUse{useStream, useConcat, useFormat}},
+	{use: useDeep, requires: &needSingleQuery, excludes: []flagUse{useStream, useSweep, useFollowUp}},
+	{use: flagUse{"-fail-fast", func(c *Config) bool { return c.failFast && c.continueOnError }}, requires: &needBatch},
+	{use: flagUse{"-continue-on-error=false", func(c *Config) bool { return !c.continueOnError }}, requires: &needBatch},
+	{use: flagUse{"-priority-order", func(c *Config) bool { return c.priorityOrder }}, requires: &needBatch},
+	{use: flagUse{"-checkpoint", func(c *Config) bool { return c.checkpointPath != "" }}, requires: &needBatch, excludes: []flagUse{useServe, useGRPC}},
+	{use: flagUse{"-resume-from", func(c *Config) bool { return c.resumeFrom != "" }}, requires: &needBatch, excludes: []flagUse{useServe, useGRPC}},
+	{
+		use:      flagUse{"-verify", func(c *Config) bool { return c.verify }},
+		excludes: excluding([]flagUse{useStream}, longRunning, []flagUse{useDryRun, useCompare, useSweep}),
+	},
+	{
+		use:      flagUse{"-confidence", func(c *Config) bool { return c.confidence }},
+		excludes: excluding([]flagUse{useStream}, longRunning, []flagUse{useDryRun, useCompare, useSweep}),
+	},
+	{
+		use:      flagUse{"-suggest", func(c *Config) bool { return c.suggest }},
+		excludes: []flagUse{useBareStream, useServe, useGRPC, useMCP, useDryRun, useCompare, useSweep},
+	},
+	{use: flagUse{"-render", func(c *Config) bool { return c.renderExplicit && c.render }}, excludes: []flagUse{useNoColor}},
+	{use: flagUse{"-notify", func(c *Config) bool { return c.notify }}, excludes: excluding(longRunning, []flagUse{useWatch})},
+	{use: flagUse{"-report", func(c *Config) bool { return c.report != "" }}, requires: &needBatch},
+	{use: flagUse{"-email", func(c *Config) bool { return len(c.email) > 0 }}, requires: &needBatch, excludes: []flagUse{useServe, useGRPC}},
+	{
+		use:      useSamples,
+		requires: &needSingleQuery,
+		excludes: []flagUse{useURLs, useStream, useDeep, useSweep, useCompare, useFollowUp, useResume, useInteractive, useWatch},
+	},
+	{
+		use:      useCompare,
+		requires: &needSingleQuery,
+		excludes: []flagUse{useStream, useSweep, useDeep, useFollowUp, useInteractive, useServe, useSchema, useOut, useOutDir},
+	},
+	{use: useSweep, excludes: []flagUse{useMultiple, useStream}},
+}
+
+// checkFlagConflicts returns the first rule config breaks, naming the flag
+// and what it needs or the flag it cannot be combined with
+func checkFlagConflicts(config *Config) error {
+	for _, rule := range flagConflicts {
+		if !rule.use.on(config) {
+			continue
+		}
+		if rule.requires != nil && !rule.requires.on(config) {
+			return conflictError(rule, fmt.Sprintf("%s requires %s", rule.use.name, rule.requires.name))
+		}
+		for _, other := range rule.excludes {
+			if other.on(config) {
+				return conflictError(rule, fmt.Sprintf("%s cannot be combined with %s", rule.use.name, other.name))
+			}
+		}
+	}
+	return nil
+}
+
+func conflictError(rule flagConflict, problem string) error {
+	if rule.hint != "" {
+		problem += "; " + rule.hint
+	}
+	return errors.New(problem)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckFlagConflicts(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(c *Config)
+		want  string
+	}{
+		{name: "single query", setup: func(c *Config) { c.query = "go" }},
+		{name: "batch", setup: func(c *Config) { c.queries = []string{"go", "rust"}; c.priorityOrder = true }},
+		{
+			name:  "excluded flag",
+			setup: func(c *Config) { c.query = "go"; c.deep = true; c.stream = true },
+			want:  "-deep cannot be combined with -stream",
+		},
+		{
+			name:  "missing single query",
+			setup: func(c *Config) { c.queries = []string{"go", "rust"}; c.watch = time.Minute },
+			want:  "-watch requires a single query",
+		},
+		{
+			name:  "missing flag",
+			setup: func(c *Config) { c.query = "go"; c.compress = true },
+			want:  "-compress requires -output-file",
+		},
+		{
+			name:  "hint",
+			setup: func(c *Config) { c.queries = []string{"go", "rust"}; c.out = "notes.md" },
+			want:  "-out cannot be combined with multiple queries; -out-dir writes one file per query",
+		},
+		{
+			name:  "continue on error",
+			setup: func(c *Config) { c.query = "go"; c.continueOnError = false; c.failFast = true },
+			want:  "-continue-on-error=false requires multiple queries (-q, -queries-file or piped queries)",
+		},
+		{
+			name:  "suggest streams in interactive mode",
+			setup: func(c *Config) { c.interactive = true; c.stream = true; c.suggest = true },
+		},
+		{
+			name: "routed deep research",
+			setup: func(c *Config) {
+				c.query = "go"
+				c.deep = true
+				c.autoModel = autoModelHeuristic
+				c.freshness = time.Hour
+			},
+			want: "-auto-model cannot be combined with -freshness",
+		},
+		{
+			name:  "fresh samples",
+			setup: func(c *Config) { c.query = "go"; c.samples = 3; c.freshness = time.Hour },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{format: formatText, continueOnError: true, samples: 1}
+			tt.setup(config)
+			err := checkFlagConflicts(config)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("err = %v, want nil", err)
+			case tt.want != "" && (err == nil || err.Error() != tt.want):
+				t.Errorf("err = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
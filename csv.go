@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Responses are cut to this many characters in csv and tsv cells, to keep
+// rows readable in a spreadsheet
+const csvResponseChars = 500
+
+// csvColumns are the fields -csv-columns can pick, by header name
+var csvColumns = map[string]func(r *search.Result) string{
+	"id":            func(r *search.Result) string { return r.ID },
+	"query":         func(r *search.Result) string { return r.Query },
+	"success":       func(r *search.Result) string { return strconv.FormatBool(r.Success) },
+	"duration":      func(r *search.Result) string { return r.Duration.Round(time.Millisecond).String() },
+	"summary":       func(r *search.Result) string { return r.Summary },
+	"response":      func(r *search.Result) string { return truncateRunes(strings.TrimSpace(r.Response), csvResponseChars) },
+	"sources":       func(r *search.Result) string { return strconv.Itoa(len(r.Sources)) },
+	"source_urls":   sourceURLs,
+	"error":         func(r *search.Result) string { return r.Error },
+	"error_code":    func(r *search.Result) string { return string(r.ErrorCode) },
+	"category":      func(r *search.Result) string { return r.Category },
+	"cached":        func(r *search.Result) string { return strconv.FormatBool(r.Cached) },
+	"confidence":    confidence,
+	"continuations": func(r *search.Result) string { return strconv.Itoa(r.Continuations) },
+	"prompt_tokens": func(r *search.Result) string { return strconv.Itoa(int(r.PromptTokens)) },
+	"output_tokens": func(r *search.Result) string { return strconv.Itoa(int(r.OutputTokens)) },
+	"cost_usd":      func(r *search.Result) string { return strconv.FormatFloat(r.CostUSD, 'f', 6, 64) },
+	"timestamp":     func(r *search.Result) string { return r.Timestamp.Format(time.RFC3339) },
+	"tags":          func(r *search.Result) string { return formatTags(r.Tags) },
+}
+
+var defaultCSVColumns = []string{"query", "success", "duration", "summary", "response", "sources"}
+
+func sourceURLs(r *search.Result) string {
+	urls := make([]string, len(r.Sources))
+	for i, source := range r.Sources {
+		urls[i] = source.URI
+	}
+	return strings.Join(urls, " ")
+}
+
+// confidence is empty for answers that were not rated
+func confidence(r *search.Result) string {
+	if r.Confidence == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*r.Confidence, 'f', 2, 64)
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
+
+// parseCSVColumns checks a comma-separated -csv-columns list
+func parseCSVColumns(value string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := csvColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q (known: %s)", name, strings.Join(sortedKeys(csvColumns), ", "))
+		}
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given")
+	}
+	return columns, nil
+}
+
+// csvRenderer prints one row per result under a header line. With tsv
+// fields are tab-separated, and their tabs and newlines become spaces.
+type csvRenderer struct {
+	columns []string
+	tsv     bool
+}
+
+func (c csvRenderer) result(r *search.Result) error {
+	return c.write(nil, []*search.Result{r})
+}
+
+func (c csvRenderer) multi(m *MultiSearchResult) error {
+	results := make([]*search.Result, len(m.Results))
+	for i := range m.Results {
+		results[i] = &m.Results[i]
+	}
+	return c.write(nil, results)
+}
+
+// Sweep and compare rows start with the budget or model they ran with
+func (c csvRenderer) sweep(s *SweepResult) error {
+	labels := &csvLabels{header: "thinking_budget"}
+	results := make([]*search.Result, len(s.Runs))
+	for i := range s.Runs {
+		labels.values = append(labels.values, strconv.Itoa(int(s.Runs[i].ThinkingBudget)))
+		results[i] = &s.Runs[i].Result
+	}
+	return c.write(labels, results)
+}
+
+func (c csvRenderer) compare(cr *CompareResult) error {
+	labels := &csvLabels{header: "model"}
+	results := make([]*search.Result, len(cr.Runs))
+	for i := range cr.Runs {
+		labels.values = append(labels.values, cr.Runs[i].Model)
+		results[i] = &cr.Runs[i].Result
+	}
+	return c.write(labels, results)
+}
+
+// csvLabels is an extra first column, one value per row
+type csvLabels struct {
+	header string
+	values []string
+}
+
+func (c csvRenderer) write(labels *csvLabels, results []*search.Result) error {
+	w := csv.NewWriter(os.Stdout)
+	if c.tsv {
+		w.Comma = '\t'
+	}
+
+	header := c.columns
+	if labels != nil {
+		header = append([]string{labels.header}, header...)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for i, r := range results {
+		var row []string
+		if labels != nil {
+			row = append(row, labels.values[i])
+		}
+		for _, name := range c.columns {
+			value := csvColumns[name](r)
+			if c.tsv {
+				value = strings.Join(strings.Fields(value), " ")
+			}
+			row = append(row, value)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
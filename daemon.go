@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// How long the daemon waits for in-flight searches when it is stopped
+const daemonShutdownTimeout = 30 * time.Second
+
+// defaultSocketPath returns $XDG_RUNTIME_DIR/go-search.sock, falling back
+// to a per-user socket in the temp directory
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "go-search.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("go-search-%d.sock", os.Getuid()))
+}
+
+// runDaemon serves the search API on a Unix socket with one long-lived
+// client, so its connections, caches and note embeddings stay warm between
+// queries. It stops on SIGINT or SIGTERM.
+func runDaemon(ctx context.Context, config *Config, client *search.Client) error {
+	path := config.socket
+	if path == "" {
+		path = defaultSocketPath()
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	// A socket left behind by a daemon that did not shut down cleanly
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := listenSocket(path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	defer os.Remove(path)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s := &server{config: config, client: client}
+	httpServer := &http.Server{Handler: s.routes()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), daemonShutdownTimeout)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Starting daemon", "socket", path)
+	fmt.Fprintf(os.Stderr, "go-search daemon listening on %s\n", path)
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// daemonClient sends searches to a running daemon instead of calling the API
+type daemonClient struct {
+	socket string
+	http   *http.Client
+}
+
+func newDaemonClient(config *Config) *daemonClient {
+	socket := config.socket
+	if socket == "" {
+		socket = defaultSocketPath()
+	}
+	var dialer net.Dialer
+	return &daemonClient{
+		socket: socket,
+		http: &http.Client{
+			Timeout: config.timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// post sends req to the daemon's path and returns the response. Errors the
+// daemon reports about the request itself are returned as errors.
+func (d *daemonClient) post(path string, req searchRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	// The host is ignored; every request goes to the socket
+	resp, err := d.http.Post("http://daemon"+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("no daemon is listening on %s (start one with go-search daemon)", d.socket)
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusInternalServerError || resp.StatusCode == http.StatusNotFound {
+		defer resp.Body.Close()
+		var failure ErrorOutput
+		if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Error == "" {
+			return nil, fmt.Errorf("daemon returned %s", resp.Status)
+		}
+		return nil, fmt.Errorf("%s: %s", failure.Context, failure.Error)
+	}
+	return resp, nil
+}
+
+// runDaemonClient answers the queries of this run through the daemon and
+// prints the results as a direct search would
+func runDaemonClient(config *Config) error {
+	d := newDaemonClient(config)
+	req := searchRequest{Query: config.query, Queries: config.queries}
+	if config.includeSummaryExplicit {
+		req.IncludeSummary = &config.includeSummary
+	}
+
+	if config.stream {
+		return d.stream(config, req)
+	}
+
+	resp, err := d.post("/search", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if len(config.queries) > 0 {
+		var m MultiSearchResult
+		if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+			return fmt.Errorf("failed to decode daemon response: %w", err)
+		}
+		for i := range m.Results {
+			m.Results[i].Tags = config.tagsFor(m.Results[i].Query)
+		}
+		if config.concat {
+			err = m.OutputConcat(config.delimiter, config.onlySucceeded)
+		} else {
+			err = newRenderer(config).multi(&m)
+		}
+		if err != nil {
+			exit(exitFailure)
+		}
+		if !m.Success {
+			exit(exitCodeFor(m.ErrorCode))
+		}
+		return nil
+	}
+
+	var result search.Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode daemon response: %w", err)
+	}
+	result.Tags = config.tagsFor(result.Query)
+	if !result.Success {
+		exitWithError(errors.New(result.Error), result.ErrorCode, "Search failed", &result)
+	}
+	if err := newRenderer(config).result(&result); err != nil {
+		exit(exitFailure)
+	}
+	return nil
+}
+
+// stream reads the daemon's server-sent events into the emitter -stream
+// uses for direct searches
+func (d *daemonClient) stream(config *Config, req searchRequest) error {
+	resp, err := d.post("/search/stream", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	out := newStreamEmitter(config)
+	out.begin(req.Query, "")
+	var result *search.Result
+	var event string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		switch event {
+		case "chunk":
+			var chunk struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(data), &chunk); err == nil {
+				out.event(search.StreamEvent{Type: search.EventChunk, Text: chunk.Text})
+			}
+		case "retry":
+			var retry struct {
+				Attempt int `json:"attempt"`
+			}
+			if err := json.Unmarshal([]byte(data), &retry); err == nil {
+				out.event(search.StreamEvent{Type: search.EventRetry, Attempt: retry.Attempt})
+			}
+		case "result":
+			result = &search.Result{}
+			if err := json.Unmarshal([]byte(data), result); err != nil {
+				return fmt.Errorf("failed to decode daemon response: %w", err)
+			}
+			result.Tags = config.tagsFor(result.Query)
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("daemon stream failed: %w", err)
+	}
+	if result == nil {
+		return fmt.Errorf("daemon stream ended without a result")
+	}
+
+	out.streamed(result)
+	if result.Summary != "" {
+		out.summary(result)
+	}
+	out.done(result)
+	if !result.Success {
+		exitWithError(errors.New(result.Error), result.ErrorCode, "Search failed", result)
+	}
+	return nil
+}
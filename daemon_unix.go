@@ -0,0 +1,17 @@
+//go:build unix
+
+package main
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenSocket creates the daemon socket with owner-only permissions. The
+// umask is set before the socket file exists, so there is no window in which
+// another user can connect to it.
+func listenSocket(path string) (net.Listener, error) {
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Query deduplication modes for -dedupe
+const (
+	dedupeOff   = "off"
+	dedupeExact = "exact"
+	dedupeFuzzy = "fuzzy"
+)
+
+var dedupeModes = []string{dedupeOff, dedupeExact, dedupeFuzzy}
+
+// Trigram similarity above which -dedupe=fuzzy treats two queries as the
+// same question
+const fuzzyDedupeThreshold = 0.8
+
+func validateDedupe(mode string) error {
+	if !slices.Contains(dedupeModes, mode) {
+		return fmt.Errorf("unknown dedupe mode %q (expected %s)", mode, strings.Join(dedupeModes, ", "))
+	}
+	return nil
+}
+
+// normalizeQuery folds case and whitespace and drops trailing punctuation,
+// so "What is Go?" and "what is  go" compare equal
+func normalizeQuery(query string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	return strings.TrimRightFunc(normalized, unicode.IsPunct)
+}
+
+// dedupeQueries returns the distinct queries and, for each of them, the
+// indexes of the queries it stands for, in order of first appearance
+func dedupeQueries(queries []string, mode string) ([]string, [][]int) {
+	var unique []string
+	var owners [][]int
+	var normalized []string
+	for i, query := range queries {
+		key := normalizeQuery(query)
+		match := -1
+		if mode != dedupeOff {
+			for u, other := range normalized {
+				if key == other || mode == dedupeFuzzy && similarQueries(key, other) {
+					match = u
+					break
+				}
+			}
+		}
+		if match < 0 {
+			unique = append(unique, query)
+			normalized = append(normalized, key)
+			owners = append(owners, []int{i})
+			continue
+		}
+		owners[match] = append(owners[match], i)
+		slog.Info("Merged duplicate query", "query", query, "duplicate_of", unique[match])
+	}
+	return unique, owners
+}
+
+// similarQueries compares normalized queries by their character trigrams.
+// Queries naming different numbers, such as versions or years, are never
+// similar however close the rest of their wording is.
+func similarQueries(a, b string) bool {
+	if !slices.Equal(numbers(a), numbers(b)) {
+		return false
+	}
+	return trigramSimilarity(a, b) >= fuzzyDedupeThreshold
+}
+
+func numbers(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+}
+
+// trigramSimilarity is the Dice coefficient of the character trigrams of a
+// and b, padded so short words still count
+func trigramSimilarity(a, b string) float64 {
+	gramsA, gramsB := trigrams(a), trigrams(b)
+	total := 0
+	for _, n := range gramsA {
+		total += n
+	}
+	for _, n := range gramsB {
+		total += n
+	}
+	if total == 0 {
+		return 1
+	}
+	shared := 0
+	for gram, n := range gramsA {
+		shared += min(n, gramsB[gram])
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+func trigrams(s string) map[string]int {
+	runes := []rune("  " + s + " ")
+	grams := make(map[string]int)
+	for i := 2; i < len(runes); i++ {
+		grams[string(runes[i-2:i+1])]++
+	}
+	return grams
+}
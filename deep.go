@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// runDeep researches query with -deep, reporting each stage on stderr so
+// stdout only carries the final report
+func runDeep(ctx context.Context, query string, config *Config, client *search.Client) (*search.Result, error) {
+	finished := 0
+	return client.Deep(ctx, query, search.DeepOptions{
+		MaxQuestions: config.deepQuestions,
+		Workers:      config.workers,
+		OnProgress: func(p search.DeepProgress) {
+			switch {
+			case p.Stage == search.StagePlan:
+				fmt.Fprintf(os.Stderr, "Planning research...\n")
+			case p.Stage == search.StageSearch && p.Result == nil:
+				fmt.Fprintf(os.Stderr, "Researching %d sub-questions:\n", len(p.Questions))
+			case p.Stage == search.StageSearch:
+				finished++
+				if p.Result.Success {
+					fmt.Fprintf(os.Stderr, "  ✓ [%d/%d] %s (%s)\n", finished, len(p.Questions), p.Questions[p.Index], p.Result.Duration.Round(time.Millisecond))
+				} else {
+					fmt.Fprintf(os.Stderr, "  ✗ [%d/%d] %s: %s\n", finished, len(p.Questions), p.Questions[p.Index], p.Result.Error)
+				}
+			case p.Stage == search.StageSynthesize:
+				fmt.Fprintf(os.Stderr, "Writing report...\n\n")
+			}
+		},
+	})
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Unchanged lines shown around each change in a unified diff
+const diffContext = 3
+
+// diffSide is one of the two answers being compared
+type diffSide struct {
+	ID        string
+	Query     string
+	Model     string
+	Timestamp time.Time
+	Response  string
+}
+
+func entrySide(e historyEntry) diffSide {
+	return diffSide{ID: e.ID, Query: e.Query, Model: e.Model, Timestamp: e.CreatedAt, Response: e.Response}
+}
+
+func resultSide(r *search.Result, model string) diffSide {
+	return diffSide{ID: r.ID, Query: r.Query, Model: model, Timestamp: r.Timestamp, Response: r.Response}
+}
+
+// runDiffCommand handles "diff <id-1> <id-2>"
+func runDiffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	dbPath := fs.String("history-db", "", "History database (default ~/.local/share/go-search/history.db)")
+	words := fs.Bool("word-diff", false, "Diff word by word instead of line by line")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [options] <history-id-1> <history-id-2>\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("diff takes two history IDs")
+	}
+
+	store, err := openHistory(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	from, err := store.entry(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := store.entry(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	printAnswerDiff(entrySide(from), entrySide(to), *words)
+	return nil
+}
+
+// printAnswerDiff prints what changed between the answers in from and to
+func printAnswerDiff(from, to diffSide, words bool) {
+	if from.Query != to.Query {
+		fmt.Fprintf(os.Stderr, "Warning: comparing answers to different queries\n")
+	}
+	fmt.Printf("--- %s\n", diffHeader(from))
+	fmt.Printf("+++ %s\n", diffHeader(to))
+	if from.Response == to.Response {
+		fmt.Println("Answers are identical")
+		return
+	}
+	if words {
+		fmt.Println(wordDiff(from.Response, to.Response))
+	} else {
+		fmt.Print(unifiedDiff(from.Response, to.Response))
+	}
+}
+
+func diffHeader(side diffSide) string {
+	return fmt.Sprintf("%s  %s  %s  %s", side.ID, side.Timestamp.Local().Format("2006-01-02 15:04"), side.Model, truncateQuery(side.Query, 60))
+}
+
+// diffOp is one token of a diff: kept (' '), removed ('-') or added ('+')
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// diffOps turns before into after via their longest common subsequence
+func diffOps(before, after []string) []diffOp {
+	// lcs[i][j] is the longest common subsequence of before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			ops = append(ops, diffOp{' ', before[i]})
+			i++
+			j++
+		case i < len(before) && (j == len(after) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', before[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', after[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// diffLines renders a line diff of a and b, prefixing removed lines with
+// "- " and added lines with "+ ". Unchanged lines are left out.
+func diffLines(a, b string) string {
+	var out strings.Builder
+	for _, op := range diffOps(strings.Split(a, "\n"), strings.Split(b, "\n")) {
+		if op.kind != ' ' {
+			fmt.Fprintf(&out, "%c %s\n", op.kind, op.text)
+		}
+	}
+	return out.String()
+}
+
+// unifiedDiff renders a line diff of a and b in unified format, with
+// diffContext unchanged lines around each hunk
+func unifiedDiff(a, b string) string {
+	ops := diffOps(strings.Split(a, "\n"), strings.Split(b, "\n"))
+
+	// Line numbers in a and b before each op
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for next := 0; next < len(ops); {
+		first := next
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		// Join changes whose contexts would touch into one hunk
+		end := first + 1
+		for i := end; i < len(ops) && i-end < 2*diffContext; i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			}
+		}
+		lo, hi := max(first-diffContext, 0), min(end+diffContext, len(ops))
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine[lo], oldLine[hi]), hunkRange(newLine[lo], newLine[hi]))
+		for _, op := range ops[lo:hi] {
+			out.WriteString(colorDiff(op.kind, fmt.Sprintf("%c%s", op.kind, op.text)) + "\n")
+		}
+		next = hi
+	}
+	return out.String()
+}
+
+// hunkRange formats lines [from, to) as "start,count", numbered from 1
+func hunkRange(from, to int) string {
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+var wordPattern = regexp.MustCompile(`\s+|\S+`)
+
+// wordDiff renders b with the words removed from a marked [-like this-]
+// and the words added marked {+like this+}
+func wordDiff(a, b string) string {
+	ops := diffOps(wordPattern.FindAllString(a, -1), wordPattern.FindAllString(b, -1))
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		kind := ops[i].kind
+		var run strings.Builder
+		for ; i < len(ops) && ops[i].kind == kind; i++ {
+			run.WriteString(ops[i].text)
+		}
+		switch kind {
+		case '-':
+			out.WriteString(colorDiff(kind, "[-"+run.String()+"-]"))
+		case '+':
+			out.WriteString(colorDiff(kind, "{+"+run.String()+"+}"))
+		default:
+			out.WriteString(run.String())
+		}
+	}
+	return out.String()
+}
+
+// colorDiff shows removals in red and additions in green when stdout is a
+// terminal and colors are on
+func colorDiff(kind byte, text string) string {
+	if !useColor || !isTerminal(os.Stdout) {
+		return text
+	}
+	switch kind {
+	case '-':
+		return "\033[31m" + text + "\033[0m"
+	case '+':
+		return "\033[32m" + text + "\033[0m"
+	}
+	return text
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"os"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"google.golang.org/genai"
+)
+
+// dryRunRequest is a search request as printed by -dry-run
+type dryRunRequest struct {
+	Query             string                       `json:"query"`
+	Provider          string                       `json:"provider"`
+	Model             string                       `json:"model"`
+	SystemInstruction string                       `json:"system_instruction"`
+	Contents          []*genai.Content             `json:"contents"`
+	Config            *genai.GenerateContentConfig `json:"config"`
+}
+
+// runDryRun prints the search request for each query as JSON: an object
+// for one query, an array for several
+func runDryRun(config *Config, client *search.Client) error {
+	queries := config.queries
+	if config.query != "" {
+		queries = []string{config.query}
+	}
+
+	requests := make([]dryRunRequest, len(queries))
+	for i, query := range queries {
+		req := client.SearchRequest(query)
+		requests[i] = dryRunRequest{Query: query, Provider: config.provider, Model: req.Model, Contents: req.Contents}
+		requests[i].SystemInstruction, requests[i].Config = splitSystemInstruction(req.Config)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if len(requests) == 1 {
+		return encoder.Encode(requests[0])
+	}
+	return encoder.Encode(requests)
+}
+
+var errDryRun = errors.New("requests are not sent in -dry-run mode")
+
+// dryRunProvider stands in for the real provider with -dry-run, so no API
+// key is needed and nothing can reach the network
+type dryRunProvider struct {
+	name string
+}
+
+func (p dryRunProvider) Name() string { return p.name }
+
+func (dryRunProvider) GenerateContent(context.Context, string, []*genai.Content, *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	return nil, errDryRun
+}
+
+func (dryRunProvider) GenerateContentStream(context.Context, string, []*genai.Content, *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		yield(nil, errDryRun)
+	}
+}
+
+// UploadFile skips the upload, so large documents show a placeholder URI
+func (dryRunProvider) UploadFile(_ context.Context, doc search.Document) (string, error) {
+	return "files/dry-run/" + doc.Name, nil
+}
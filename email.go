@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	smtpTimeout     = 30 * time.Second
+	smtpDefaultPort = 587
+)
+
+// SMTP connection security for the tls setting of the smtp section
+const (
+	smtpSTARTTLS = "starttls"
+	smtpTLS      = "tls"
+	smtpNoTLS    = "none"
+)
+
+// smtpConfig is the smtp section of the config file, used by -email
+type smtpConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	// $GOSEARCH_SMTP_PASSWORD when empty
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	// starttls (the default), tls for implicit TLS, or none
+	TLS string `yaml:"tls"`
+}
+
+// validate fills in the defaults and checks the settings -email needs
+func (s *smtpConfig) validate() error {
+	if s.Host == "" || s.From == "" {
+		return fmt.Errorf("-email requires host and from under smtp in the config file")
+	}
+	if _, err := mail.ParseAddress(s.From); err != nil {
+		return fmt.Errorf("invalid smtp from address %q: %w", s.From, err)
+	}
+	if s.Port == 0 {
+		s.Port = smtpDefaultPort
+	}
+	if s.TLS == "" {
+		s.TLS = smtpSTARTTLS
+		if s.Port == 465 {
+			s.TLS = smtpTLS
+		}
+	}
+	if s.TLS != smtpSTARTTLS && s.TLS != smtpTLS && s.TLS != smtpNoTLS {
+		return fmt.Errorf("unknown smtp tls setting %q (expected %s, %s or %s)", s.TLS, smtpSTARTTLS, smtpTLS, smtpNoTLS)
+	}
+	if s.Password == "" {
+		s.Password = os.Getenv("GOSEARCH_SMTP_PASSWORD")
+	}
+	return nil
+}
+
+// emailReport sends the HTML report of a multi-query run to the -email
+// recipients, with the Markdown of every result as the plain-text part
+func emailReport(config *Config, m *MultiSearchResult) error {
+	page, err := renderReport(m, config.model)
+	if err != nil {
+		return err
+	}
+	var text strings.Builder
+	for i := range m.Results {
+		if i > 0 {
+			text.WriteString("\n---\n\n")
+		}
+		r := &m.Results[i]
+		if !r.Success {
+			fmt.Fprintf(&text, "# %s\n\nFailed: %s\n", strings.Join(strings.Fields(r.Query), " "), r.Error)
+			continue
+		}
+		text.WriteString(markdownBody(r))
+	}
+
+	succeeded := 0
+	for _, r := range m.Results {
+		if r.Success {
+			succeeded++
+		}
+	}
+	subject := fmt.Sprintf("Search report: %d/%d queries succeeded", succeeded, len(m.Results))
+	message, err := emailMessage(config.smtp.From, config.email, subject, text.String(), page)
+	if err != nil {
+		return err
+	}
+	if err := sendEmail(&config.smtp, config.email, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Emailed report to %s\n", strings.Join(config.email, ", "))
+	return nil
+}
+
+// emailMessage builds a multipart/alternative message with text and HTML
+// versions of the same body
+func emailMessage(from string, to []string, subject, text string, html []byte) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", []byte(text)},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 12)
+	rand.Read(id)
+	_, domain, _ := strings.Cut(from, "@")
+	domain = strings.TrimSuffix(domain, ">")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+// sendEmail delivers message to the recipients through the configured
+// server, authenticating when a username is set
+func sendEmail(s *smtpConfig, to []string, message []byte) error {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsConfig := &tls.Config{ServerName: s.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	if s.TLS == smtpTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.TLS == smtpSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS (set tls: none to send unencrypted)", s.Host)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(s.From)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		address, _ := mail.ParseAddress(recipient)
+		if err := client.Rcpt(address.Address); err != nil {
+			return fmt.Errorf("recipient %s: %w", recipient, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
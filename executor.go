@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// runConcurrently calls work for each index in [0, n) with at most workers
+// calls in flight and returns the results in index order. Units that have
+// not started when ctx is done are filled in by cancelled(index, false) and
+// never begin. Once ctx is done, in-flight units get up to grace to finish;
+// any still running after that are abandoned and filled in by
+// cancelled(index, true), so the call always returns shortly after ctx ends.
+// When ordered is set, units start in index order rather than in whatever
+// order their goroutines win a slot.
+func runConcurrently[T any](ctx context.Context, n, workers int, grace time.Duration, ordered bool, work func(ctx context.Context, index int) T, cancelled func(index int, started bool) T) []T {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	results := make([]T, n)
+	finished := make([]bool, n)
+	closed := false
+	set := func(index int, value T) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			results[index] = value
+			finished[index] = true
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers) // Simple semaphore for concurrency control
+
+	// acquire waits for a slot, reporting false if ctx is done first
+	acquire := func() bool {
+		select {
+		case sem <- struct{}{}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		// Ordered units take their slot here, before the next one is dispatched
+		if ordered && !acquire() {
+			for index := i; index < n; index++ {
+				set(index, cancelled(index, false))
+			}
+			break
+		}
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			// Don't wait for a slot, or start the work, once ctx is done
+			if !ordered && !acquire() {
+				set(index, cancelled(index, false))
+				return
+			}
+			defer func() { <-sem }() // Release semaphore
+
+			if ctx.Err() != nil {
+				set(index, cancelled(index, false))
+				return
+			}
+			set(index, work(ctx, index))
+		}(i)
+	}
+
+	allDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(allDone)
+	}()
+
+	select {
+	case <-allDone:
+	case <-ctx.Done():
+		timer := time.NewTimer(grace)
+		select {
+		case <-allDone:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+	for i := range results {
+		if !finished[i] {
+			results[i] = cancelled(i, true)
+		}
+	}
+	return results
+}
+
+// lateGuard stops work abandoned by runConcurrently or a stage from touching
+// the caller's state once the batch has returned. Side effects run through
+// do, and close waits for any in progress.
+type lateGuard struct {
+	mu     sync.RWMutex
+	closed bool
+}
+
+// do runs fn unless the guard is closed, reporting whether it ran
+func (g *lateGuard) do(fn func()) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		return false
+	}
+	fn()
+	return true
+}
+
+func (g *lateGuard) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+}
+
+// stage runs follow-up work for units finished by an earlier stage, with
+// its own limit of workers calls in flight, so the stages overlap
+type stage[T any] struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu      sync.Mutex
+	results map[int]T
+	closed  bool
+}
+
+func newStage[T any](workers int) *stage[T] {
+	if workers < 1 {
+		workers = 1
+	}
+	return &stage[T]{sem: make(chan struct{}, workers), results: make(map[int]T)}
+}
+
+// submit runs work for index in the background once a slot is free. It is
+// dropped if ctx is done before then.
+func (s *stage[T]) submit(ctx context.Context, index int, work func(ctx context.Context) T) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case s.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		defer func() { <-s.sem }()
+		if ctx.Err() != nil {
+			return
+		}
+
+		value := work(ctx)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.closed {
+			s.results[index] = value
+		}
+	}()
+}
+
+// wait blocks until the submitted work finishes, giving it up to grace once
+// ctx is done, and returns the values of the units that completed
+func (s *stage[T]) wait(ctx context.Context, grace time.Duration) map[int]T {
+	allDone := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(allDone)
+	}()
+
+	select {
+	case <-allDone:
+	case <-ctx.Done():
+		timer := time.NewTimer(grace)
+		select {
+		case <-allDone:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.results
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// outcome records how runConcurrently produced a unit's result
+type outcome struct {
+	index int
+	// "done", "not started" or "abandoned"
+	state string
+}
+
+func cancelledOutcome(index int, started bool) outcome {
+	if started {
+		return outcome{index, "abandoned"}
+	}
+	return outcome{index, "not started"}
+}
+
+func TestRunConcurrentlyIndexOrder(t *testing.T) {
+	const n = 8
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%v", ordered), func(t *testing.T) {
+			// Later indexes finish first
+			results := runConcurrently(context.Background(), n, n, time.Second, ordered, func(ctx context.Context, index int) outcome {
+				time.Sleep(time.Duration(n-index) * time.Millisecond)
+				return outcome{index, "done"}
+			}, cancelledOutcome)
+
+			for i, r := range results {
+				if r != (outcome{i, "done"}) {
+					t.Errorf("results[%d] = %v, want unit %d done", i, r, i)
+				}
+			}
+		})
+	}
+}
+
+func TestRunConcurrentlyWorkerLimit(t *testing.T) {
+	for _, workers := range []int{1, 3, 5} {
+		for _, ordered := range []bool{false, true} {
+			t.Run(fmt.Sprintf("workers=%d/ordered=%v", workers, ordered), func(t *testing.T) {
+				var running, peak atomic.Int32
+				runConcurrently(context.Background(), 20, workers, time.Second, ordered, func(ctx context.Context, index int) outcome {
+					now := running.Add(1)
+					for {
+						old := peak.Load()
+						if now <= old || peak.CompareAndSwap(old, now) {
+							break
+						}
+					}
+					time.Sleep(time.Millisecond)
+					running.Add(-1)
+					return outcome{index, "done"}
+				}, cancelledOutcome)
+
+				if got := peak.Load(); got > int32(workers) {
+					t.Errorf("%d units ran at once, limit %d", got, workers)
+				}
+			})
+		}
+	}
+}
+
+func TestRunConcurrentlyCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%v", ordered), func(t *testing.T) {
+			var started atomic.Int32
+			results := runConcurrently(ctx, 5, 2, time.Second, ordered, func(ctx context.Context, index int) outcome {
+				started.Add(1)
+				return outcome{index, "done"}
+			}, cancelledOutcome)
+
+			if got := started.Load(); got != 0 {
+				t.Errorf("%d units started after cancel", got)
+			}
+			for i, r := range results {
+				if r != (outcome{i, "not started"}) {
+					t.Errorf("results[%d] = %v, want not started", i, r)
+				}
+			}
+		})
+	}
+}
+
+func TestRunConcurrentlyCancelledMidway(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered=%v", ordered), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// With one worker, the first unit to run cancels the rest
+			var started []int
+			var mu sync.Mutex
+			results := runConcurrently(ctx, 6, 1, time.Second, ordered, func(ctx context.Context, index int) outcome {
+				mu.Lock()
+				started = append(started, index)
+				mu.Unlock()
+				cancel()
+				return outcome{index, "done"}
+			}, cancelledOutcome)
+
+			if len(started) != 1 {
+				t.Fatalf("units %v started, want only the first", started)
+			}
+			for i, r := range results {
+				want := outcome{i, "not started"}
+				if i == started[0] {
+					want.state = "done"
+				}
+				if r != want {
+					t.Errorf("results[%d] = %v, want %v", i, r, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunConcurrentlyGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Units 0 and 1 finish, 2 and 3 ignore ctx and outlive the grace period
+	release := make(chan struct{})
+	var started, late sync.WaitGroup
+	started.Add(4)
+	late.Add(2)
+	go func() {
+		started.Wait()
+		cancel()
+	}()
+	results := runConcurrently(ctx, 4, 4, 20*time.Millisecond, false, func(ctx context.Context, index int) outcome {
+		started.Done()
+		if index < 2 {
+			return outcome{index, "done"}
+		}
+		defer late.Done()
+		<-release
+		return outcome{index, "done"}
+	}, cancelledOutcome)
+
+	want := []outcome{{0, "done"}, {1, "done"}, {2, "abandoned"}, {3, "abandoned"}}
+	if !slices.Equal(results, want) {
+		t.Fatalf("results = %v, want %v", results, want)
+	}
+
+	// The abandoned units now finish; their set calls must not land
+	close(release)
+	late.Wait()
+	time.Sleep(10 * time.Millisecond)
+	if !slices.Equal(results, want) {
+		t.Errorf("results after late finish = %v, want %v", results, want)
+	}
+}
+
+func TestRunConcurrentlyOrderedStarts(t *testing.T) {
+	var mu sync.Mutex
+	var started []int
+	runConcurrently(context.Background(), 10, 1, time.Second, true, func(ctx context.Context, index int) outcome {
+		mu.Lock()
+		started = append(started, index)
+		mu.Unlock()
+		return outcome{index, "done"}
+	}, cancelledOutcome)
+
+	want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	if !slices.Equal(started, want) {
+		t.Errorf("units started in order %v, want %v", started, want)
+	}
+}
+
+func TestStage(t *testing.T) {
+	s := newStage[int](2)
+	ctx := context.Background()
+	for i := range 5 {
+		s.submit(ctx, i, func(ctx context.Context) int {
+			time.Sleep(time.Duration(5-i) * time.Millisecond)
+			return i * 10
+		})
+	}
+
+	results := s.wait(ctx, time.Second)
+	if len(results) != 5 {
+		t.Fatalf("got %d results, want 5", len(results))
+	}
+	for i := range 5 {
+		if results[i] != i*10 {
+			t.Errorf("results[%d] = %d, want %d", i, results[i], i*10)
+		}
+	}
+}
+
+func TestStageDropsLateResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := newStage[string](4)
+	release := make(chan struct{})
+	late := make(chan struct{})
+	s.submit(ctx, 0, func(ctx context.Context) string { return "on time" })
+	s.submit(ctx, 1, func(ctx context.Context) string {
+		defer close(late)
+		<-release
+		return "late"
+	})
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	results := s.wait(ctx, 20*time.Millisecond)
+	if len(results) != 1 || results[0] != "on time" {
+		t.Fatalf("results = %v, want only unit 0", results)
+	}
+
+	close(release)
+	<-late
+	time.Sleep(10 * time.Millisecond)
+	if _, ok := results[1]; ok || len(results) != 1 {
+		t.Errorf("results after late finish = %v, want only unit 0", results)
+	}
+}
+
+func TestStageCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := newStage[int](2)
+	var ran atomic.Int32
+	for i := range 3 {
+		s.submit(ctx, i, func(ctx context.Context) int {
+			ran.Add(1)
+			return i
+		})
+	}
+	if results := s.wait(ctx, time.Second); len(results) != 0 {
+		t.Errorf("results = %v, want none", results)
+	}
+	if got := ran.Load(); got != 0 {
+		t.Errorf("%d units submitted after cancel ran", got)
+	}
+}
+
+func TestLateGuard(t *testing.T) {
+	var g lateGuard
+	if !g.do(func() {}) {
+		t.Fatal("do did not run before close")
+	}
+
+	// close waits for a call already in progress
+	running := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	go g.do(func() {
+		close(running)
+		<-release
+		finished.Store(true)
+	})
+	<-running
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+	g.close()
+	if !finished.Load() {
+		t.Error("close returned while do was running")
+	}
+
+	if g.do(func() { t.Error("do ran after close") }) {
+		t.Error("do reported running after close")
+	}
+}
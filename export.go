@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"gopkg.in/yaml.v3"
+)
+
+type frontMatterSource struct {
+	Title string `yaml:"title"`
+	URL   string `yaml:"url"`
+}
+
+// frontMatter is the YAML header written at the top of exported Markdown
+type frontMatter struct {
+	Query     string              `yaml:"query"`
+	Timestamp time.Time           `yaml:"timestamp"`
+	Model     string              `yaml:"model"`
+	Duration  string              `yaml:"duration"`
+	Category  string              `yaml:"category,omitempty"`
+	Sources   []frontMatterSource `yaml:"sources,omitempty"`
+}
+
+// renderMarkdown formats a result as Markdown with YAML front matter
+func renderMarkdown(r *search.Result, model string) ([]byte, error) {
+	meta := frontMatter{
+		Query:     r.Query,
+		Timestamp: r.Timestamp,
+		Model:     model,
+		Duration:  r.Duration.Round(time.Millisecond).String(),
+		Category:  r.Category,
+	}
+	for _, source := range r.Sources {
+		meta.Sources = append(meta.Sources, frontMatterSource{Title: source.Title, URL: source.URI})
+	}
+	var header strings.Builder
+	encoder := yaml.NewEncoder(&header)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(meta); err != nil {
+		return nil, fmt.Errorf("failed to encode front matter: %w", err)
+	}
+
+	return []byte("---\n" + header.String() + "---\n\n" + markdownBody(r)), nil
+}
+
+// markdownBody formats a result's query, summary, response and sources
+func markdownBody(r *search.Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", strings.Join(strings.Fields(r.Query), " "))
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "## Summary\n\n%s\n\n## Response\n\n", r.Summary)
+	}
+	fmt.Fprintf(&b, "%s\n", strings.TrimSpace(r.Response))
+	// With -inline-citations the response already ends with its sources
+	if showSources && len(r.Sources) > 0 {
+		b.WriteString("\n## Sources\n\n")
+		for _, source := range r.Sources {
+			fmt.Fprintf(&b, "- [%s](%s)\n", source.Title, source.URI)
+		}
+	}
+	if len(r.Notes) > 0 {
+		b.WriteString("\n## Notes\n\n")
+		for _, note := range noteSources(r.Notes) {
+			fmt.Fprintf(&b, "- %s\n", note.Source)
+		}
+	}
+	if r.Confidence != nil {
+		fmt.Fprintf(&b, "\n**Confidence:** %.2f", *r.Confidence)
+		if r.LowConfidence() {
+			b.WriteString(" ⚠")
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Caveats) > 0 {
+		b.WriteString("\n## Caveats\n\n")
+		for _, caveat := range r.Caveats {
+			fmt.Fprintf(&b, "- %s\n", caveat)
+		}
+	}
+	if v := r.Verification; v != nil && len(v.Claims) > 0 {
+		b.WriteString("\n## Verification\n\n")
+		for _, claim := range v.Claims {
+			fmt.Fprintf(&b, "- %s **%s** (%.2f): %s", verdictMarks[claim.Verdict], claim.Verdict, claim.Confidence, claim.Text)
+			if claim.Explanation != "" {
+				fmt.Fprintf(&b, " — %s", claim.Explanation)
+			}
+			b.WriteString("\n")
+			for _, source := range claim.Sources {
+				fmt.Fprintf(&b, "  - [%s](%s)\n", source.Title, source.URI)
+			}
+		}
+	}
+	if len(r.SuggestedQueries) > 0 {
+		b.WriteString("\n## Follow-up Queries\n\n")
+		for i, query := range r.SuggestedQueries {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, query)
+		}
+	}
+	return b.String()
+}
+
+// exportResults saves results as Markdown when -out or -out-dir is set
+func exportResults(config *Config, results []search.Result) error {
+	switch {
+	case config.out != "" && len(results) == 1:
+		return exportMarkdown(config.out, &results[0], config.model)
+	case config.outDir != "":
+		return exportMarkdownDir(config.outDir, results, config.model)
+	}
+	return nil
+}
+
+// exportMarkdown writes a successful result to path
+func exportMarkdown(path string, r *search.Result, model string) error {
+	if !r.Success {
+		return fmt.Errorf("not exporting failed search %q", r.Query)
+	}
+	data, err := renderMarkdown(r, model)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved %s\n", path)
+	return nil
+}
+
+// exportMarkdownDir writes every successful result to its own file in dir,
+// named after its date and query
+func exportMarkdownDir(dir string, results []search.Result, model string) error {
+	used := map[string]bool{}
+	for i := range results {
+		r := &results[i]
+		if !r.Success {
+			continue
+		}
+
+		base := r.Timestamp.Format(time.DateOnly) + "-" + slugify(r.Query)
+		name := base + ".md"
+		for n := 2; used[name] || fileExists(filepath.Join(dir, name)); n++ {
+			name = fmt.Sprintf("%s-%d.md", base, n)
+		}
+		used[name] = true
+
+		if err := exportMarkdown(filepath.Join(dir, name), r, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slugify turns a query into a lowercase, dash-separated file name
+func slugify(query string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(query) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if runes := []rune(slug); len(runes) > 60 {
+		slug = strings.TrimSuffix(string(runes[:60]), "-")
+	}
+	if slug == "" {
+		slug = "search"
+	}
+	return slug
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// freshAnswer returns the latest successful answer to query from history
+// when it is newer than -freshness. Otherwise it returns when the query was
+// last answered, or the zero time if it never was.
+func freshAnswer(config *Config, query string) (*search.Result, time.Time) {
+	if config.freshness <= 0 || history == nil {
+		return nil, time.Time{}
+	}
+	entry, ok, err := history.latestAnswer(query, config.model)
+	if err != nil {
+		slog.Info("Failed to look up earlier answer", "query", query, "error", err)
+		return nil, time.Time{}
+	}
+	if !ok {
+		return nil, time.Time{}
+	}
+	if time.Since(entry.CreatedAt) > config.freshness {
+		return nil, entry.CreatedAt
+	}
+	slog.Info("Reusing earlier answer", "query", query, "id", entry.ID, "answered", entry.CreatedAt)
+	// Tokens and cost stay zero; reusing the answer cost nothing
+	return &search.Result{
+		ID:        entry.ID,
+		Query:     query,
+		Response:  entry.Response,
+		Summary:   entry.Summary,
+		Success:   true,
+		Timestamp: entry.CreatedAt,
+		Cached:    true,
+	}, time.Time{}
+}
+
+// noteFreshness tells the user whether an answer was reused or replaced a
+// stale one; batch notes name their query
+func noteFreshness(query string, reused *search.Result, staleAt time.Time, batch bool) {
+	suffix := ""
+	if batch {
+		suffix = ": " + query
+	}
+	switch {
+	case reused != nil:
+		fmt.Fprintf(os.Stderr, "Reusing the answer from %s%s\n", reused.Timestamp.Local().Format(freshnessDate), suffix)
+	case !staleAt.IsZero():
+		fmt.Fprintf(os.Stderr, "Previously answered on %s, updated%s\n", staleAt.Local().Format(freshnessDate), suffix)
+	}
+}
+
+const freshnessDate = "2006-01-02 15:04"
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"github.com/qiushiyan/gemini-search/searchpb"
+)
+
+type grpcServer struct {
+	searchpb.UnimplementedSearchServiceServer
+	config *Config
+	client *search.Client
+}
+
+// runGRPCServer serves searchpb.SearchService on addr until the listener fails
+func runGRPCServer(config *Config, client *search.Client) error {
+	listener, err := net.Listen("tcp", config.grpc)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", config.grpc, err)
+	}
+	server := grpc.NewServer()
+	searchpb.RegisterSearchServiceServer(server, &grpcServer{config: config, client: client})
+
+	slog.Info("Starting gRPC server", "addr", config.grpc)
+	fmt.Fprintf(os.Stderr, "Serving gRPC search API on %s\n", config.grpc)
+	return server.Serve(listener)
+}
+
+// includeSummary applies the CLI's smart default: on within a batch
+func (s *grpcServer) includeSummary(req *searchpb.SearchRequest, batch bool) bool {
+	if req.IncludeSummary != nil {
+		return req.GetIncludeSummary()
+	}
+	if s.config.includeSummaryExplicit {
+		return s.config.includeSummary
+	}
+	return batch
+}
+
+// Search answers with a result even when the search fails; its success
+// and error_code fields say why. Only invalid requests are RPC errors.
+func (s *grpcServer) Search(ctx context.Context, req *searchpb.SearchRequest) (*searchpb.SearchResult, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	return resultProto(s.search(ctx, req.GetQuery(), s.includeSummary(req, false))), nil
+}
+
+func (s *grpcServer) search(ctx context.Context, query string, includeSummary bool) *search.Result {
+	ctx, cancel := context.WithTimeout(ctx, s.config.timeout)
+	defer cancel()
+	ctx = search.WithRequestID(ctx, search.NewRequestID())
+
+	result, err := s.client.Search(ctx, query)
+	if err != nil {
+		slog.Info("Search failed", "query", query, "error", err)
+	} else if includeSummary {
+		s.client.AddSummary(ctx, result)
+	}
+	history.record(s.config.model, result)
+	return result
+}
+
+func (s *grpcServer) SearchStream(req *searchpb.SearchRequest, stream searchpb.SearchService_SearchStreamServer) error {
+	if req.GetQuery() == "" {
+		return status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	ctx, cancel := context.WithTimeout(stream.Context(), s.config.timeout)
+	defer cancel()
+	ctx = search.WithRequestID(ctx, search.NewRequestID())
+
+	// Stop the search once the client has gone away
+	var sendErr error
+	send := func(event *searchpb.SearchEvent) {
+		if sendErr != nil {
+			return
+		}
+		if sendErr = stream.Send(event); sendErr != nil {
+			cancel()
+		}
+	}
+	result, err := s.client.SearchStream(ctx, req.GetQuery(), func(event search.StreamEvent) {
+		switch event.Type {
+		case search.EventChunk:
+			send(&searchpb.SearchEvent{Event: &searchpb.SearchEvent_Chunk{Chunk: event.Text}})
+		case search.EventRetry:
+			send(&searchpb.SearchEvent{Event: &searchpb.SearchEvent_Retry{Retry: &searchpb.Retry{Attempt: int32(event.Attempt)}}})
+		}
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err == nil && s.includeSummary(req, false) {
+		s.client.AddSummary(ctx, result)
+	}
+	history.record(s.config.model, result)
+	return stream.Send(&searchpb.SearchEvent{Event: &searchpb.SearchEvent_Result{Result: resultProto(result)}})
+}
+
+// BatchSearch starts each request as it arrives, with at most -workers in
+// flight, and sends the results back as they finish
+func (s *grpcServer) BatchSearch(stream searchpb.SearchService_BatchSearchServer) error {
+	ctx := stream.Context()
+	var limiter *rateLimiter
+	if s.config.rpm > 0 {
+		limiter = newRateLimiter(s.config.rpm)
+	}
+
+	sem := make(chan struct{}, s.config.workers)
+	var mu sync.Mutex
+	var sendErr error
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetQuery() == "" {
+			return status.Error(codes.InvalidArgument, "query is required")
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// Every query gets a result, even one that never got its turn
+			var result *search.Result
+			if _, err := limiter.wait(ctx); err != nil {
+				cancelled := cancelledResult(ctx, req.GetQuery(), false)
+				result = &cancelled
+			} else {
+				result = s.search(ctx, req.GetQuery(), s.includeSummary(req, true))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if sendErr == nil {
+				sendErr = stream.Send(resultProto(result))
+			}
+		}()
+	}
+
+	wg.Wait()
+	return sendErr
+}
+
+func resultProto(r *search.Result) *searchpb.SearchResult {
+	sources := make([]*searchpb.Source, len(r.Sources))
+	for i, source := range r.Sources {
+		sources[i] = &searchpb.Source{Title: source.Title, Uri: source.URI}
+	}
+	return &searchpb.SearchResult{
+		Id:             r.ID,
+		Query:          r.Query,
+		Response:       r.Response,
+		Summary:        r.Summary,
+		Success:        r.Success,
+		Error:          r.Error,
+		ErrorCode:      string(r.ErrorCode),
+		Duration:       durationpb.New(r.Duration),
+		Timestamp:      timestamppb.New(r.Timestamp),
+		PromptTokens:   r.PromptTokens,
+		OutputTokens:   r.OutputTokens,
+		ThinkingTokens: r.ThinkingTokens,
+		CostUsd:        r.CostUSD,
+		Sources:        sources,
+		Cached:         r.Cached,
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+	_ "modernc.org/sqlite"
+)
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS searches (
+	id              TEXT PRIMARY KEY,
+	query           TEXT NOT NULL,
+	response        TEXT NOT NULL,
+	summary         TEXT NOT NULL,
+	success         INTEGER NOT NULL,
+	error           TEXT NOT NULL,
+	model           TEXT NOT NULL,
+	duration_ms     INTEGER NOT NULL,
+	prompt_tokens   INTEGER NOT NULL,
+	output_tokens   INTEGER NOT NULL,
+	thinking_tokens INTEGER NOT NULL,
+	cost_usd        REAL NOT NULL,
+	created_at      TIMESTAMP NOT NULL,
+	parent_id       TEXT NOT NULL DEFAULT '',
+	session         TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS searches_created_at ON searches (created_at);
+CREATE TABLE IF NOT EXISTS embeddings (
+	search_id TEXT NOT NULL,
+	model     TEXT NOT NULL,
+	vector    BLOB NOT NULL,
+	PRIMARY KEY (search_id, model)
+);
+`
+
+// Columns added after the table was first created, with their definitions
+var historyMigrations = []struct{ column, definition string }{
+	{"parent_id", "TEXT NOT NULL DEFAULT ''"},
+	{"session", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// historyEntry is one stored search as shown by the history subcommands
+type historyEntry struct {
+	ID             string        `json:"id"`
+	Query          string        `json:"query"`
+	Response       string        `json:"response"`
+	Summary        string        `json:"summary,omitempty"`
+	Success        bool          `json:"success"`
+	Error          string        `json:"error,omitempty"`
+	Model          string        `json:"model"`
+	Duration       time.Duration `json:"duration"`
+	PromptTokens   int32         `json:"prompt_tokens,omitempty"`
+	OutputTokens   int32         `json:"output_tokens,omitempty"`
+	ThinkingTokens int32         `json:"thinking_tokens,omitempty"`
+	CostUSD        float64       `json:"cost_usd,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	// The turn this search followed up on, empty for a new conversation
+	ParentID string `json:"parent_id,omitempty"`
+	// Named session the search was recorded under, if any
+	Session string `json:"session,omitempty"`
+}
+
+type historyStore struct {
+	db *sql.DB
+	// Session new searches are recorded under, set by -session and -resume
+	session string
+}
+
+// Set in main unless -no-history is given; nil disables recording
+var history *historyStore
+
+// defaultHistoryPath returns $XDG_DATA_HOME/go-search/history.db, falling
+// back to ~/.local/share
+func defaultHistoryPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate history directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "go-search", "history.db"), nil
+}
+
+func openHistory(path string) (*historyStore, error) {
+	if path == "" {
+		var err error
+		if path, err = defaultHistoryPath(); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	// WAL and a busy timeout let concurrent workers and processes share the file
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history database: %w", err)
+	}
+	if err := migrateHistory(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &historyStore{db: db}, nil
+}
+
+// migrateHistory adds columns introduced after the table was first created
+func migrateHistory(db *sql.DB) error {
+	for _, migration := range historyMigrations {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('searches') WHERE name = ?`, migration.column).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to inspect history database: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE searches ADD COLUMN ` + migration.column + ` ` + migration.definition); err != nil {
+			return fmt.Errorf("failed to migrate history database: %w", err)
+		}
+	}
+	// Created here since older tables only gain the column above
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS searches_session ON searches (session, created_at)`); err != nil {
+		return fmt.Errorf("failed to migrate history database: %w", err)
+	}
+	return nil
+}
+
+// record stores a finished search. Failures are logged rather than
+// returned so history never breaks a search.
+func (h *historyStore) record(model string, r *search.Result) {
+	h.recordTurn(model, r, "")
+}
+
+// recordTurn stores a search that continued the conversation ending at parentID
+func (h *historyStore) recordTurn(model string, r *search.Result, parentID string) {
+	if h == nil || r == nil {
+		return
+	}
+	id := r.ID
+	if id == "" {
+		id = search.NewRequestID()
+	}
+	_, err := h.db.Exec(`INSERT OR REPLACE INTO searches
+		(id, query, response, summary, success, error, model, duration_ms, prompt_tokens, output_tokens, thinking_tokens, cost_usd, created_at, parent_id, session)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, r.Query, r.Response, r.Summary, r.Success, r.Error, model, r.Duration.Milliseconds(),
+		r.PromptTokens, r.OutputTokens, r.ThinkingTokens, r.CostUSD, r.Timestamp.UTC(), parentID, h.session)
+	if err != nil {
+		slog.Error("Failed to record search history", "query", r.Query, "error", err)
+	}
+}
+
+func (h *historyStore) Close() error {
+	if h == nil {
+		return nil
+	}
+	return h.db.Close()
+}
+
+const historyColumns = `id, query, response, summary, success, error, model, duration_ms, prompt_tokens, output_tokens, thinking_tokens, cost_usd, created_at, parent_id, session`
+
+func (h *historyStore) query(where string, args ...any) ([]historyEntry, error) {
+	rows, err := h.db.Query("SELECT "+historyColumns+" FROM searches "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []historyEntry
+	for rows.Next() {
+		var e historyEntry
+		var durationMS int64
+		if err := rows.Scan(&e.ID, &e.Query, &e.Response, &e.Summary, &e.Success, &e.Error, &e.Model, &durationMS,
+			&e.PromptTokens, &e.OutputTokens, &e.ThinkingTokens, &e.CostUSD, &e.CreatedAt, &e.ParentID, &e.Session); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		e.Duration = time.Duration(durationMS) * time.Millisecond
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// entry returns the search whose ID starts with id; IDs can be abbreviated
+// to any unique prefix
+func (h *historyStore) entry(id string) (historyEntry, error) {
+	entries, err := h.query("WHERE id LIKE ? ORDER BY created_at DESC LIMIT 2", id+"%")
+	if err != nil {
+		return historyEntry{}, err
+	}
+	if len(entries) == 0 {
+		return historyEntry{}, fmt.Errorf("no history entry with id %q", id)
+	}
+	if len(entries) > 1 {
+		return historyEntry{}, fmt.Errorf("id %q is ambiguous", id)
+	}
+	return entries[0], nil
+}
+
+// latestAnswer returns the most recent successful search for query with
+// model, ignoring case and surrounding spaces. Follow-ups are skipped, since
+// their answers depend on the conversation before them.
+func (h *historyStore) latestAnswer(query, model string) (historyEntry, bool, error) {
+	entries, err := h.query("WHERE success = 1 AND parent_id = '' AND model = ? AND lower(trim(query)) = lower(trim(?)) ORDER BY created_at DESC LIMIT 1", model, query)
+	if err != nil || len(entries) == 0 {
+		return historyEntry{}, false, err
+	}
+	return entries[0], true, nil
+}
+
+// lastConversation returns the turns of the most recent successful search's
+// conversation, oldest first, and the ID of its latest turn
+func (h *historyStore) lastConversation() ([]search.Turn, string, error) {
+	entries, err := h.query("WHERE success = 1 ORDER BY created_at DESC LIMIT 1")
+	if err != nil {
+		return nil, "", err
+	}
+	if len(entries) == 0 {
+		return nil, "", fmt.Errorf("no earlier search to follow up on")
+	}
+	latest := entries[0]
+
+	var turns []search.Turn
+	seen := map[string]bool{}
+	for entry := latest; ; {
+		turns = append(turns, search.Turn{Query: entry.Query, Response: entry.Response})
+		seen[entry.ID] = true
+		if entry.ParentID == "" || seen[entry.ParentID] {
+			break
+		}
+		parents, err := h.query("WHERE id = ?", entry.ParentID)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(parents) == 0 {
+			break
+		}
+		entry = parents[0]
+	}
+
+	for i, j := 0, len(turns)-1; i < j; i, j = i+1, j-1 {
+		turns[i], turns[j] = turns[j], turns[i]
+	}
+	return turns, latest.ID, nil
+}
+
+// runHistoryCommand handles "history", "history search <term>" and
+// "history show <id>"
+func runHistoryCommand(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dbPath := fs.String("history-db", "", "History database (default ~/.local/share/go-search/history.db)")
+	limit := fs.Int("limit", 20, "Maximum number of entries to list")
+	outputJSON := fs.Bool("json", false, "Output in JSON format")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s history [options] [search <term> | show <id>]\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	store, err := openHistory(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	rest := fs.Args()
+	var entries []historyEntry
+	switch {
+	case len(rest) == 0:
+		entries, err = store.query("ORDER BY created_at DESC LIMIT ?", *limit)
+	case rest[0] == "search" && len(rest) >= 2:
+		term := "%" + strings.Join(rest[1:], " ") + "%"
+		entries, err = store.query("WHERE query LIKE ? OR response LIKE ? ORDER BY created_at DESC LIMIT ?", term, term, *limit)
+	case rest[0] == "show" && len(rest) == 2:
+		entry, err := store.entry(rest[1])
+		if err != nil {
+			return err
+		}
+		return showHistoryEntry(entry, *outputJSON)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown history command %q", strings.Join(rest, " "))
+	}
+	if err != nil {
+		return err
+	}
+
+	if *outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No searches found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tSTATUS\tDURATION\tQUERY")
+	for _, e := range entries {
+		status := "ok"
+		if !e.Success {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.CreatedAt.Local().Format("2006-01-02 15:04"), status,
+			e.Duration.Round(time.Millisecond), truncateQuery(e.Query, 60))
+	}
+	return w.Flush()
+}
+
+func showHistoryEntry(e historyEntry, outputJSON bool) error {
+	if outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(e)
+	}
+
+	fmt.Printf("=== %s ===\n", e.Query)
+	fmt.Printf("%s · %s · %s · %d prompt / %d output / %d thinking tokens\n\n",
+		e.CreatedAt.Local().Format("2006-01-02 15:04:05"), e.Model, e.Duration.Round(time.Millisecond),
+		e.PromptTokens, e.OutputTokens, e.ThinkingTokens)
+	if !e.Success {
+		fmt.Printf("Status: FAILED - %s\n", e.Error)
+		return nil
+	}
+	if e.Summary != "" {
+		fmt.Printf("## SUMMARY\n%s\n\n## DETAILED RESPONSE\n", e.Summary)
+	}
+	fmt.Println(answerText(e.Response))
+	return nil
+}
+
+// truncateQuery shortens a query to one line of at most n runes
+func truncateQuery(query string, n int) string {
+	query = strings.Join(strings.Fields(query), " ")
+	runes := []rune(query)
+	if len(runes) <= n {
+		return query
+	}
+	return string(runes[:n-1]) + "…"
+}
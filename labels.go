@@ -0,0 +1,168 @@
+package main
+
+import "strings"
+
+// outputLabels are the fixed strings of text output, localized by -lang
+type outputLabels struct {
+	Summary           string
+	DetailedResponse  string
+	DetailedResponses string
+	Sources           string
+	SearchResults     string
+	// Takes the successful and total query counts
+	Completed    string
+	NoSummary    string
+	Failed       string
+	Verification string
+	Caveats      string
+	Suggestions  string
+	Notes        string
+	Statistics   string
+	// Marks an answer rated below search.LowConfidence
+	LowConfidence string
+}
+
+var englishLabels = outputLabels{
+	Summary:           "SUMMARY",
+	DetailedResponse:  "DETAILED RESPONSE",
+	DetailedResponses: "DETAILED RESPONSES",
+	Sources:           "SOURCES",
+	SearchResults:     "SEARCH RESULTS",
+	Completed:         "%d/%d queries completed successfully, here is a summary for each query:",
+	NoSummary:         "No summary available",
+	Failed:            "Status: FAILED",
+	Verification:      "VERIFICATION",
+	Caveats:           "CAVEATS",
+	Suggestions:       "FOLLOW-UP QUERIES",
+	Notes:             "NOTES",
+	Statistics:        "STATISTICS",
+	LowConfidence:     "LOW CONFIDENCE",
+}
+
+var localizedLabels = map[string]outputLabels{
+	"de": {
+		Summary:           "ZUSAMMENFASSUNG",
+		DetailedResponse:  "AUSFÜHRLICHE ANTWORT",
+		DetailedResponses: "AUSFÜHRLICHE ANTWORTEN",
+		Sources:           "QUELLEN",
+		SearchResults:     "SUCHERGEBNISSE",
+		Completed:         "%d/%d Anfragen erfolgreich abgeschlossen, hier eine Zusammenfassung pro Anfrage:",
+		NoSummary:         "Keine Zusammenfassung verfügbar",
+		Failed:            "Status: FEHLGESCHLAGEN",
+		Verification:      "ÜBERPRÜFUNG",
+		Caveats:           "VORBEHALTE",
+		Suggestions:       "WEITERFÜHRENDE SUCHEN",
+		Notes:             "NOTIZEN",
+		Statistics:        "STATISTIK",
+		LowConfidence:     "GERINGE ZUVERLÄSSIGKEIT",
+	},
+	"es": {
+		Summary:           "RESUMEN",
+		DetailedResponse:  "RESPUESTA DETALLADA",
+		DetailedResponses: "RESPUESTAS DETALLADAS",
+		Sources:           "FUENTES",
+		SearchResults:     "RESULTADOS DE BÚSQUEDA",
+		Completed:         "%d/%d consultas completadas correctamente, este es el resumen de cada una:",
+		NoSummary:         "Sin resumen disponible",
+		Failed:            "Estado: FALLIDA",
+		Verification:      "VERIFICACIÓN",
+		Caveats:           "SALVEDADES",
+		Suggestions:       "BÚSQUEDAS RELACIONADAS",
+		Notes:             "NOTAS",
+		Statistics:        "ESTADÍSTICAS",
+		LowConfidence:     "CONFIANZA BAJA",
+	},
+	"fr": {
+		Summary:           "RÉSUMÉ",
+		DetailedResponse:  "RÉPONSE DÉTAILLÉE",
+		DetailedResponses: "RÉPONSES DÉTAILLÉES",
+		Sources:           "SOURCES",
+		SearchResults:     "RÉSULTATS DE RECHERCHE",
+		Completed:         "%d/%d requêtes terminées avec succès, voici un résumé pour chacune :",
+		NoSummary:         "Aucun résumé disponible",
+		Failed:            "Statut : ÉCHEC",
+		Verification:      "VÉRIFICATION",
+		Caveats:           "RÉSERVES",
+		Suggestions:       "RECHERCHES COMPLÉMENTAIRES",
+		Notes:             "NOTES",
+		Statistics:        "STATISTIQUES",
+		LowConfidence:     "CONFIANCE FAIBLE",
+	},
+	"it": {
+		Summary:           "RIEPILOGO",
+		DetailedResponse:  "RISPOSTA DETTAGLIATA",
+		DetailedResponses: "RISPOSTE DETTAGLIATE",
+		Sources:           "FONTI",
+		SearchResults:     "RISULTATI DELLA RICERCA",
+		Completed:         "%d/%d query completate correttamente, ecco un riepilogo per ciascuna:",
+		NoSummary:         "Nessun riepilogo disponibile",
+		Failed:            "Stato: NON RIUSCITA",
+		Verification:      "VERIFICA",
+		Caveats:           "AVVERTENZE",
+		Suggestions:       "RICERCHE CORRELATE",
+		Notes:             "NOTE",
+		Statistics:        "STATISTICHE",
+		LowConfidence:     "AFFIDABILITÀ BASSA",
+	},
+	"ja": {
+		Summary:           "要約",
+		DetailedResponse:  "詳細な回答",
+		DetailedResponses: "詳細な回答",
+		Sources:           "出典",
+		SearchResults:     "検索結果",
+		Completed:         "%d/%d 件のクエリが成功しました。各クエリの要約:",
+		NoSummary:         "要約はありません",
+		Failed:            "ステータス: 失敗",
+		Verification:      "検証",
+		Caveats:           "注意点",
+		Suggestions:       "関連する検索",
+		Notes:             "メモ",
+		Statistics:        "統計",
+		LowConfidence:     "信頼度が低い",
+	},
+	"pt": {
+		Summary:           "RESUMO",
+		DetailedResponse:  "RESPOSTA DETALHADA",
+		DetailedResponses: "RESPOSTAS DETALHADAS",
+		Sources:           "FONTES",
+		SearchResults:     "RESULTADOS DA PESQUISA",
+		Completed:         "%d/%d consultas concluídas com sucesso, veja o resumo de cada uma:",
+		NoSummary:         "Nenhum resumo disponível",
+		Failed:            "Status: FALHOU",
+		Verification:      "VERIFICAÇÃO",
+		Caveats:           "RESSALVAS",
+		Suggestions:       "PESQUISAS RELACIONADAS",
+		Notes:             "NOTAS",
+		Statistics:        "ESTATÍSTICAS",
+		LowConfidence:     "CONFIANÇA BAIXA",
+	},
+	"zh": {
+		Summary:           "摘要",
+		DetailedResponse:  "详细回答",
+		DetailedResponses: "详细回答",
+		Sources:           "来源",
+		SearchResults:     "搜索结果",
+		Completed:         "%d/%d 个查询成功完成，以下是每个查询的摘要：",
+		NoSummary:         "暂无摘要",
+		Failed:            "状态：失败",
+		Verification:      "核查",
+		Caveats:           "注意事项",
+		Suggestions:       "后续搜索",
+		Notes:             "笔记",
+		Statistics:        "统计",
+		LowConfidence:     "置信度低",
+	},
+}
+
+// Set from -lang once flags are parsed
+var labels = englishLabels
+
+// labelsFor returns the labels for a language code such as "es" or
+// "pt-BR", falling back to English
+func labelsFor(lang string) outputLabels {
+	base, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+	if localized, ok := localizedLabels[strings.ToLower(base)]; ok {
+		return localized
+	}
+	return englishLabels
+}
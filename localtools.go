@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"google.golang.org/genai"
+)
+
+const defaultLocalToolTimeout = 30 * time.Second
+
+// Longest command output sent back to the model, in bytes
+const localToolOutputLimit = 16 << 10
+
+var localToolName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]{0,63}$`)
+
+var localToolTypes = map[string]genai.Type{
+	"string":  genai.TypeString,
+	"integer": genai.TypeInteger,
+	"number":  genai.TypeNumber,
+	"boolean": genai.TypeBoolean,
+}
+
+// localToolsConfig is the local-tools section of the config file. Only
+// commands whose executable is listed in Allow can be declared.
+type localToolsConfig struct {
+	Allow []string          `yaml:"allow"`
+	Tools []localToolConfig `yaml:"tools"`
+}
+
+// localToolConfig declares one command the model may run with
+// -enable-local-tools. Each element of Command is a template over the
+// parameters; elements that render empty are dropped.
+type localToolConfig struct {
+	Name        string                        `yaml:"name"`
+	Description string                        `yaml:"description"`
+	Command     []string                      `yaml:"command"`
+	Parameters  map[string]localToolParameter `yaml:"parameters"`
+	Required    []string                      `yaml:"required"`
+	Timeout     time.Duration                 `yaml:"timeout"`
+	// Let argument values start with a dash, which could set other flags
+	AllowFlags bool `yaml:"allow-flags"`
+}
+
+type localToolParameter struct {
+	Type        string   `yaml:"type"`
+	Description string   `yaml:"description"`
+	Enum        []string `yaml:"enum"`
+}
+
+// searchTools checks the declared tools and turns them into the
+// functions the search client offers the model
+func (l localToolsConfig) searchTools(quiet bool) ([]search.LocalTool, error) {
+	var tools []search.LocalTool
+	seen := map[string]bool{}
+	for _, tool := range l.Tools {
+		if !localToolName.MatchString(tool.Name) {
+			return nil, fmt.Errorf("local tool name %q must be letters, digits, _ or -, starting with a letter or _", tool.Name)
+		}
+		if seen[tool.Name] {
+			return nil, fmt.Errorf("local tool %s is declared twice", tool.Name)
+		}
+		seen[tool.Name] = true
+		run, declaration, err := tool.compile(l.Allow, quiet)
+		if err != nil {
+			return nil, fmt.Errorf("local tool %s: %w", tool.Name, err)
+		}
+		tools = append(tools, search.LocalTool{Declaration: declaration, Run: run})
+	}
+	return tools, nil
+}
+
+func (t localToolConfig) compile(allow []string, quiet bool) (func(context.Context, map[string]any) (string, error), *genai.FunctionDeclaration, error) {
+	if len(t.Command) == 0 {
+		return nil, nil, fmt.Errorf("no command")
+	}
+	if !slices.Contains(allow, t.Command[0]) {
+		return nil, nil, fmt.Errorf("%s is not in the local-tools allow list", t.Command[0])
+	}
+
+	declaration := &genai.FunctionDeclaration{
+		Name:        t.Name,
+		Description: t.Description,
+	}
+	if len(t.Parameters) > 0 {
+		declaration.Parameters = &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}}
+		for _, name := range sortedKeys(t.Parameters) {
+			param := t.Parameters[name]
+			if param.Type == "" {
+				param.Type = "string"
+			}
+			typ, ok := localToolTypes[param.Type]
+			if !ok {
+				return nil, nil, fmt.Errorf("parameter %s has unknown type %q (expected %s)", name, param.Type, strings.Join(sortedKeys(localToolTypes), ", "))
+			}
+			declaration.Parameters.Properties[name] = &genai.Schema{Type: typ, Description: param.Description, Enum: param.Enum}
+		}
+		for _, name := range t.Required {
+			if _, ok := t.Parameters[name]; !ok {
+				return nil, nil, fmt.Errorf("required parameter %s is not declared", name)
+			}
+		}
+		declaration.Parameters.Required = t.Required
+	}
+
+	// Rendering with every parameter set catches references to undeclared ones
+	declared := map[string]string{}
+	for name := range t.Parameters {
+		declared[name] = "x"
+	}
+	args := make([]*template.Template, len(t.Command)-1)
+	for i, arg := range t.Command[1:] {
+		tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(arg)
+		if err == nil {
+			err = tmpl.Execute(&strings.Builder{}, declared)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid command argument %q: %w", arg, err)
+		}
+		args[i] = tmpl
+	}
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = defaultLocalToolTimeout
+	}
+
+	run := func(ctx context.Context, values map[string]any) (string, error) {
+		argv, err := t.argv(args, values)
+		if err != nil {
+			return "", err
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "[Running %s: %s]\n", t.Name, strings.Join(argv, " "))
+		}
+		return runLocalCommand(ctx, timeout, argv)
+	}
+	return run, declaration, nil
+}
+
+// argv renders the command for the values the model passed. Only declared
+// parameters are used, so a value cannot name a field the command does not
+// expect, and a parameter with an enum only takes one of its values.
+func (t localToolConfig) argv(args []*template.Template, values map[string]any) ([]string, error) {
+	data := map[string]string{}
+	for name := range t.Parameters {
+		data[name] = ""
+		value, ok := values[name]
+		if !ok || value == nil {
+			if slices.Contains(t.Required, name) {
+				return nil, fmt.Errorf("missing required argument %s", name)
+			}
+			continue
+		}
+		text := fmt.Sprint(value)
+		if strings.HasPrefix(text, "-") && !t.AllowFlags {
+			return nil, fmt.Errorf("argument %s=%q may not start with a dash", name, text)
+		}
+		if enum := t.Parameters[name].Enum; len(enum) > 0 && !slices.Contains(enum, text) {
+			return nil, fmt.Errorf("argument %s=%q is not one of %s", name, text, strings.Join(enum, ", "))
+		}
+		data[name] = text
+	}
+
+	argv := []string{t.Command[0]}
+	for _, tmpl := range args {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		if b.Len() > 0 {
+			argv = append(argv, b.String())
+		}
+	}
+	return argv, nil
+}
+
+// runLocalCommand runs argv without a shell and returns its combined
+// output, cut to localToolOutputLimit
+func runLocalCommand(ctx context.Context, timeout time.Duration, argv []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+
+	text := output.String()
+	if len(text) > localToolOutputLimit {
+		text = text[:localToolOutputLimit] + "\n[output truncated]"
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return text, fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		return text, fmt.Errorf("%s failed: %w", argv[0], err)
+	}
+	return text, nil
+}
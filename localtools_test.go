@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestLocalToolArgv(t *testing.T) {
+	tool := localToolConfig{
+		Name:    "kube",
+		Command: []string{"kubectl", "{{.verb}}", "{{.resource}}"},
+		Parameters: map[string]localToolParameter{
+			"verb":     {Type: "string", Enum: []string{"get", "describe"}},
+			"resource": {Type: "string"},
+		},
+		Required: []string{"verb"},
+	}
+	args := make([]*template.Template, len(tool.Command)-1)
+	for i, arg := range tool.Command[1:] {
+		args[i] = template.Must(template.New(tool.Name).Option("missingkey=error").Parse(arg))
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]any
+		want    string
+		wantErr string
+	}{
+		{name: "allowed", values: map[string]any{"verb": "get", "resource": "pods"}, want: "kubectl get pods"},
+		{name: "optional left out", values: map[string]any{"verb": "describe"}, want: "kubectl describe"},
+		{name: "missing required", values: map[string]any{"resource": "pods"}, wantErr: "missing required argument verb"},
+		{name: "value outside enum", values: map[string]any{"verb": "delete", "resource": "pods"}, wantErr: `verb="delete" is not one of get, describe`},
+		{name: "leading dash", values: map[string]any{"verb": "get", "resource": "--all-namespaces"}, wantErr: "may not start with a dash"},
+		{name: "dash in enum parameter", values: map[string]any{"verb": "-f"}, wantErr: "may not start with a dash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argv, err := tool.argv(args, tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("argv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("argv() error = %v", err)
+			}
+			if got := strings.Join(argv, " "); got != tt.want {
+				t.Errorf("argv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalToolArgvAllowFlags(t *testing.T) {
+	tool := localToolConfig{
+		Name:       "gh",
+		Command:    []string{"gh", "{{.flag}}"},
+		Parameters: map[string]localToolParameter{"flag": {Type: "string", Enum: []string{"--json"}}},
+		AllowFlags: true,
+	}
+	args := []*template.Template{template.Must(template.New(tool.Name).Parse(tool.Command[1]))}
+
+	if argv, err := tool.argv(args, map[string]any{"flag": "--json"}); err != nil || strings.Join(argv, " ") != "gh --json" {
+		t.Errorf("argv() = %q, %v, want gh --json", argv, err)
+	}
+	// allow-flags lifts the dash check, not the enum
+	if _, err := tool.argv(args, map[string]any{"flag": "--web"}); err == nil {
+		t.Error("argv() accepted a value outside the enum with allow-flags")
+	}
+}
@@ -2,76 +2,734 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Set by the mcp, daemon, url, auth, recall and bench subcommands
+var (
+	serveMCP     bool
+	serveDaemon  bool
+	summarizeURL bool
+	checkAuth    bool
+	recallFirst  bool
+	benchmark    bool
+)
+
+// Exit statuses, so scripts can branch on the kind of failure
+const (
+	exitFailure = 1
+	// Invalid flags, config file, prompts, schema or query source
+	exitConfig = 2
+	// Missing or rejected API key
+	exitAuth    = 3
+	exitTimeout = 4
+	// Some queries of a batch failed and others succeeded
+	exitPartial = 5
+	exitEmpty   = 6
+	// The answer or prompt was withheld by safety filters
+	exitBlocked = 7
+	// Ctrl-C or SIGTERM stopped a search early
+	exitInterrupted = 130
+)
+
+// Error codes for failures the search package does not classify
+const (
+	codeConfig  search.ErrorCode = "config"
+	codePartial search.ErrorCode = "partial_failure"
+	// Not run because an earlier query failed under -fail-fast
+	codeSkipped search.ErrorCode = "skipped"
 )
 
+// exitCodeFor maps an error code to the exit status reporting it
+func exitCodeFor(code search.ErrorCode) int {
+	switch code {
+	case codeConfig:
+		return exitConfig
+	case search.CodeAuth:
+		return exitAuth
+	case search.CodeTimeout:
+		return exitTimeout
+	case codePartial:
+		return exitPartial
+	case search.CodeEmpty:
+		return exitEmpty
+	case search.CodeBlocked:
+		return exitBlocked
+	case search.CodeInterrupted:
+		return exitInterrupted
+	}
+	return exitFailure
+}
+
+// batchCode summarizes the failures among results: "" if none failed,
+// codePartial if some succeeded, otherwise the code they share (or
+// CodeUnknown if they differ)
+func batchCode(results []search.Result) search.ErrorCode {
+	succeeded := 0
+	var code search.ErrorCode
+	for _, result := range results {
+		switch {
+		case result.Success:
+			succeeded++
+		case result.ErrorCode == codeSkipped:
+			// The failure that caused the skip decides the code
+		case code == "":
+			code = result.ErrorCode
+		case code != result.ErrorCode:
+			code = search.CodeUnknown
+		}
+	}
+	if succeeded == len(results) {
+		return ""
+	}
+	if succeeded > 0 {
+		return codePartial
+	}
+	if code == "" {
+		return search.CodeUnknown
+	}
+	return code
+}
+
+// exitInterruptedWith reports what finished before the interrupt and exits
+func exitInterruptedWith(finished, total int) {
+	if total > 1 {
+		fmt.Fprintf(os.Stderr, "\nInterrupted: %d/%d finished\n", finished, total)
+	} else {
+		fmt.Fprintf(os.Stderr, "\nInterrupted\n")
+	}
+	exit(exitInterrupted)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cache":
+			if err := runCacheCommand(os.Args[2:]); err != nil {
+				handleError(err, "Cache command failed")
+			}
+			return
+		case "completion":
+			if err := runCompletionCommand(os.Args[2:]); err != nil {
+				handleError(err, "Completion command failed")
+			}
+			return
+		case "diff":
+			if err := runDiffCommand(os.Args[2:]); err != nil {
+				handleError(err, "Diff command failed")
+			}
+			return
+		case "history":
+			if err := runHistoryCommand(os.Args[2:]); err != nil {
+				handleError(err, "History command failed")
+			}
+			return
+		case "sessions":
+			if err := runSessionsCommand(os.Args[2:]); err != nil {
+				handleError(err, "Sessions command failed")
+			}
+			return
+		case "mcp":
+			// Remaining arguments are regular flags for the served client
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+			serveMCP = true
+		case "daemon":
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+			serveDaemon = true
+		case "url":
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+			summarizeURL = true
+		case "auth":
+			if len(os.Args) < 3 || os.Args[2] != "check" {
+				fmt.Fprintf(os.Stderr, "Usage: %s auth check [options]\n", os.Args[0])
+				exit(exitConfig)
+			}
+			os.Args = append(os.Args[:1:1], os.Args[3:]...)
+			checkAuth = true
+		case "recall":
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+			recallFirst = true
+		case "bench":
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+			benchmark = true
+		}
+	}
+
 	config := parseFlags()
-	
+	config.mcp = serveMCP
+	config.daemon = serveDaemon
+	config.authCheck = checkAuth
+	config.recall = recallFirst
+	config.bench = benchmark
+	if summarizeURL {
+		// Positional arguments of the url subcommand are URLs, not a query
+		config.urlMode = true
+		config.urls = append(config.urls, flag.Args()...)
+		config.query = ""
+	}
+	outputOnError = config.outputOnError
+	showSources = !config.inlineCitations
+	useColor = !config.noColor && os.Getenv("NO_COLOR") == ""
+	renderAnswers = config.render && useColor
+	labels = labelsFor(config.lang)
+
+	if config.fromClipboard {
+		if err := queryFromClipboard(config); err != nil {
+			handleConfigError(err, "Failed to read query")
+		}
+	}
+	if config.queryTemplate != "" {
+		if err := expandQueryTemplate(config); err != nil {
+			handleConfigError(err, "Failed to expand query template")
+		}
+	}
+	if err := loadQueries(config); err != nil {
+		handleConfigError(err, "Failed to read queries")
+	}
+	applySummaryDefault(config)
+	if config.templateFile != "" {
+		tmpl, err := loadOutputTemplate(config.templateFile)
+		if err != nil {
+			handleConfigError(err, "Failed to load output template")
+		}
+		config.outputTemplate = tmpl
+	}
+	if config.schemaFile != "" {
+		schema, err := loadSchema(config.schemaFile)
+		if err != nil {
+			handleConfigError(err, "Failed to load schema")
+		}
+		config.schema = schema
+	}
+	if err := loadPrompts(config); err != nil {
+		handleConfigError(err, "Failed to load prompts")
+	}
+	if len(config.imagePaths) > 0 {
+		images, err := search.LoadImages(config.imagePaths)
+		if err != nil {
+			handleConfigError(err, "Failed to load images")
+		}
+		config.images = images
+	}
+	if config.caCertFile != "" {
+		pool, err := loadCACerts(config.caCertFile)
+		if err != nil {
+			handleConfigError(err, "Failed to load CA certificates")
+		}
+		config.caCerts = pool
+	}
+	if config.checkpointPath != "" || config.resumeFrom != "" {
+		cp, err := openCheckpoint(config.checkpointPath, config.resumeFrom, config.model)
+		if err != nil {
+			handleConfigError(err, "Failed to open checkpoint")
+		}
+		if config.resumeFrom != "" {
+			fmt.Fprintf(os.Stderr, "Resuming from %s: %d of %d queries already completed\n", config.resumeFrom, cp.completed(config.queries), len(config.queries))
+		}
+		config.checkpoint = cp
+	}
+	if keys := configuredAPIKeys(config); len(keys) > 0 {
+		config.keyRing = newKeyRing(keys, config.keyRotation)
+		// genai will not build a client without a key; keyTransport replaces
+		// it on every request. Other providers never read it.
+		if config.provider == search.ProviderGemini && os.Getenv("GOOGLE_API_KEY") == "" && os.Getenv("GEMINI_API_KEY") == "" {
+			os.Setenv("GOOGLE_API_KEY", keys[0])
+		}
+	}
+	if len(config.documentPaths) > 0 {
+		documents, err := search.LoadDocuments(config.documentPaths)
+		if err != nil {
+			handleConfigError(err, "Failed to load documents")
+		}
+		config.documents = documents
+	}
+	if len(config.notePaths) > 0 {
+		notes, err := search.LoadNotes(config.notePaths)
+		if err != nil {
+			handleConfigError(err, "Failed to load notes")
+		}
+		config.notes = notes
+	}
+
 	if err := validateConfig(config); err != nil {
-		handleError(err, "Configuration validation failed")
+		handleConfigError(err, "Configuration validation failed")
+	}
+	if config.outputFile != "" {
+		if err := openOutputFile(config); err != nil {
+			handleConfigError(err, "Failed to open output file")
+		}
+		defer func() {
+			if err := closeOutputFile(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to finish output file: %v\n", err)
+			}
+		}()
+	}
+
+	// The daemon already holds a client, so this run needs none
+	if config.useDaemon {
+		if err := runDaemonClient(config); err != nil {
+			handleError(err, "Daemon request failed")
+		}
+		return
+	}
+
+	logFile, err := setupLogger(config)
+	if err != nil {
+		handleError(err, "Failed to set up logging")
+	}
+	defer logFile.Close()
+
+	if err := setupTelemetry(context.Background()); err != nil {
+		handleError(err, "Failed to set up telemetry")
+	}
+	defer shutdownTelemetry()
+
+	if config.authCheck {
+		if err := runAuthCheck(config); err != nil {
+			handleError(err, "Auth check failed")
+		}
+		return
+	}
+
+	if config.promptLog != "" {
+		logger, err := openPromptLog(config.promptLog)
+		if err != nil {
+			handleError(err, "Failed to open prompt log")
+		}
+		promptLog = logger
+		defer promptLog.Close()
+	}
+	if config.auditLog != "" {
+		logger, err := openAuditLog(config.auditLog, config.auditRedact)
+		if err != nil {
+			handleError(err, "Failed to open audit log")
+		}
+		auditLog = logger
+		defer auditLog.Close()
+	}
+
+	if !config.noHistory {
+		store, err := openHistory(config.historyDB)
+		if err != nil {
+			// Sessions live in the history database
+			if config.session != "" {
+				handleError(err, "Failed to open session")
+			}
+			slog.Info("Search history disabled", "error", err)
+		} else {
+			store.session = config.session
+			history = store
+			defer history.Close()
+		}
 	}
-	
-	setupLogger(config.verbose)
-	
+
 	ctx := context.Background()
-	client, err := initializeClient(ctx)
+	client, err := initializeClient(ctx, config)
 	if err != nil {
 		handleError(err, "Failed to initialize client")
 	}
-	
-	// Handle single query
-	if config.query != "" {
-		var result *SearchResult
+	if config.useContextCache && !config.dryRun {
+		if client.ContextCache() == "" {
+			fmt.Fprintln(os.Stderr, "Warning: no context cache was created, sending the full prompt with each query (see -v for why)")
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := client.Close(ctx); err != nil {
+				slog.Info("Failed to delete context cache", "error", err)
+			}
+		}()
+	}
+
+	// Print the requests instead of sending them
+	if config.dryRun {
+		if err := runDryRun(config, client); err != nil {
+			handleError(err, "Dry run failed")
+		}
+		return
+	}
+
+	// Handle MCP server mode
+	if config.mcp {
+		if err := runMCP(ctx, config, client); err != nil {
+			handleError(err, "MCP server failed")
+		}
+		return
+	}
+
+	// Handle daemon mode
+	if config.daemon {
+		if err := runDaemon(ctx, config, client); err != nil {
+			handleError(err, "Daemon failed")
+		}
+		return
+	}
+
+	// Handle server mode
+	if config.serve != "" {
+		if err := runServer(config, client); err != nil {
+			handleError(err, "Server failed")
+		}
+		return
+	}
+
+	// Handle gRPC server mode
+	if config.grpc != "" {
+		if err := runGRPCServer(config, client); err != nil {
+			handleError(err, "gRPC server failed")
+		}
+		return
+	}
+
+	// Handle interactive session
+	if config.interactive {
+		runInteractive(ctx, config, client)
+		return
+	}
+
+	// From here on Ctrl-C cancels in-flight requests so finished results are
+	// still printed; a second Ctrl-C exits immediately
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// Handle thinking budget sweep
+	if config.sweepThinking {
+		sweep := runThinkingSweep(ctx, config.query, config, client)
+		err := newRenderer(config).sweep(sweep)
+		deliverWebhook(ctx, config, sweep)
+		deliverSlack(ctx, config, sweep)
+		notifyDone(config, sweep)
+		if ctx.Err() != nil {
+			finished := 0
+			for _, run := range sweep.Runs {
+				if run.Result.Success {
+					finished++
+				}
+			}
+			exitInterruptedWith(finished, len(sweep.Runs))
+		}
+		if err != nil {
+			exit(exitFailure)
+		}
+		if !sweep.Success {
+			exit(exitCodeFor(sweep.ErrorCode))
+		}
+		return
+	}
+
+	// Handle benchmark runs
+	if config.bench {
+		bench := runBench(ctx, config, client)
+		err := printBench(config, bench)
+		if ctx.Err() != nil {
+			exitInterruptedWith(bench.Runs-bench.Failures, bench.Runs)
+		}
+		if err != nil {
+			exit(exitFailure)
+		}
+		if bench.ErrorCode != "" {
+			exit(exitCodeFor(bench.ErrorCode))
+		}
+		return
+	}
+
+	// Handle model comparison
+	if len(config.compareModels) > 0 {
+		comparison := runComparison(ctx, config.query, config, client)
+		err := newRenderer(config).compare(comparison)
+		deliverWebhook(ctx, config, comparison)
+		deliverSlack(ctx, config, comparison)
+		notifyDone(config, comparison)
+		if ctx.Err() != nil {
+			finished := 0
+			for _, run := range comparison.Runs {
+				if run.Result.Success {
+					finished++
+				}
+			}
+			exitInterruptedWith(finished, len(comparison.Runs))
+		}
+		if err != nil {
+			exit(exitFailure)
+		}
+		if !comparison.Success {
+			exit(exitCodeFor(comparison.ErrorCode))
+		}
+		return
+	}
+
+	// Handle recurring searches; Ctrl-C is the normal way to stop watching
+	if config.watch > 0 {
+		runWatch(ctx, config, client)
+		return
+	}
+
+	// recall answers from history when an earlier search is close enough
+	if config.recall {
+		recalled, err := runRecall(ctx, config, client)
+		if err != nil {
+			handleError(err, "Recall failed")
+		}
+		if recalled {
+			return
+		}
+	}
+
+	// Handle single query, or the pages given with url
+	if config.query != "" || len(config.urls) > 0 {
+		ctx := search.WithRequestID(ctx, search.NewRequestID())
+		var result *search.Result
 		var err error
-		
-		if config.stream {
-			result, err = performSingleSearchStream(ctx, config.query, client)
+
+		// A follow-up continues the most recent conversation from history
+		var searcher searcher = client
+		var parentID string
+		if config.followUp {
+			if history == nil {
+				handleError(fmt.Errorf("search history is disabled"), "Cannot follow up")
+			}
+			turns, lastID, err := history.lastConversation()
+			if err != nil {
+				handleError(err, "Cannot follow up")
+			}
+			slog.Info("Following up on earlier conversation", "turns", len(turns), "last_id", lastID)
+			searcher = client.ResumeSession(turns)
+			parentID = lastID
+		}
+		if config.resume {
+			turns, lastID, err := history.sessionConversation(config.session)
+			if err != nil {
+				handleError(err, "Cannot resume session")
+			}
+			slog.Info("Resuming session", "session", config.session, "turns", len(turns))
+			searcher = client.ResumeSession(turns)
+			parentID = lastID
+		}
+
+		// -diff-with compares the new answer to an earlier one from history
+		var diffFrom historyEntry
+		if config.diffWith != "" {
+			if history == nil {
+				handleError(fmt.Errorf("search history is disabled"), "Cannot diff")
+			}
+			entry, err := history.entry(config.diffWith)
+			if err != nil {
+				handleError(err, "Cannot diff")
+			}
+			diffFrom = entry
+		}
+
+		var waitClassification func() []search.Classification
+		if config.classify {
+			waitClassification = startClassification(ctx, []string{config.query}, client)
+		}
+
+		// Deep research and samples run on the routed model too
+		var routing *search.Routing
+		queryClient := client
+		if config.autoModel != "" {
+			routing = &routeQueries(ctx, config, client, []string{config.query})[0]
+			queryClient = client.WithModel(routing.Model)
+			searcher = queryClient
+		}
+
+		streamOut := newStreamEmitter(config)
+		fresh, staleAt := freshAnswer(config, config.query)
+		if len(config.urls) > 0 {
+			result, err = client.SummarizeURLs(ctx, config.urls)
+		} else if fresh != nil {
+			result = fresh
+		} else if config.deep {
+			result, err = runDeep(ctx, config.query, config, queryClient)
+		} else if config.samples > 1 {
+			result, err = runSamples(ctx, config.query, config, queryClient)
+		} else if config.stream && config.includeSummary {
+			result, err = performSingleSearchStreamWithSummary(ctx, config.query, client, searcher, config.streamSummary, streamOut)
+		} else if config.stream {
+			result, err = performSingleSearchStream(ctx, config.query, searcher, streamOut)
 		} else {
-			result, err = performSingleSearch(ctx, config.query, client)
+			result, err = searcher.Search(ctx, config.query)
 		}
-		
+
+		if waitClassification != nil {
+			// A failed search may return no result to classify
+			if classifications := waitClassification(); len(classifications) == 1 && result != nil {
+				result.SetClassification(classifications[0])
+			}
+		}
+		if result != nil && len(config.urls) == 0 {
+			result.Tags = config.tagsFor(config.query)
+			result.Routing = routing
+		}
+
+		if config.stream && result != nil {
+			streamOut.done(result)
+		}
+
 		if err != nil {
-			handleError(err, "Search failed")
+			history.recordTurn(modelFor(config, result), result, parentID)
+			runPostHook(ctx, config, result)
+			deliverWebhook(ctx, config, result)
+			deliverSlack(ctx, config, result)
+			notifyDone(config, result)
+			if ctx.Err() != nil {
+				exitInterruptedWith(0, 1)
+			}
+			handleErrorWithResult(err, "Search failed", result)
 		}
-		
+
 		// In stream mode, output is already shown, just exit
 		if config.stream {
+			history.recordTurn(modelFor(config, result), result, parentID)
+			runPostHook(ctx, config, result)
+			deliverWebhook(ctx, config, result)
+			deliverSlack(ctx, config, result)
+			notifyDone(config, result)
+			if result.Success {
+				if config.diffWith != "" {
+					printAnswerDiff(entrySide(diffFrom), resultSide(result, modelFor(config, result)), config.wordDiff)
+				}
+				if config.toClipboard {
+					if err := copyResult(result); err != nil {
+						handleError(err, "Failed to copy result")
+					}
+				}
+				if err := exportResults(config, []search.Result{*result}); err != nil {
+					handleError(err, "Failed to export results")
+				}
+			}
+			if config.showCost {
+				printCostSummary(modelFor(config, result), result.PromptTokens, result.OutputTokens, result.ThinkingTokens, result.CostUSD)
+			}
+			if ctx.Err() != nil {
+				exitInterruptedWith(0, 1)
+			}
 			if !result.Success {
-				os.Exit(1)
+				exit(exitCodeFor(result.ErrorCode))
 			}
 			return
 		}
-		
+
+		if result.Success {
+			noteFreshness(config.query, fresh, staleAt, false)
+		}
+
 		// Generate summary for single query if requested
-		if config.includeSummary && result.Success {
-			summary, err := generateSummary(ctx, result.Query, result.Response, client)
-			if err != nil {
-				result.Summary = "Summary generation failed"
-			} else {
-				result.Summary = summary
+		if config.includeSummary && result.Summary == "" {
+			client.AddSummary(ctx, result)
+		}
+		if config.schema != nil {
+			client.AddStructured(ctx, result, config.schema)
+		}
+		if config.confidence {
+			client.AddConfidence(ctx, result)
+		}
+		if config.verify && result.Success {
+			fmt.Fprintln(os.Stderr, "Verifying answer...")
+			client.AddVerification(ctx, result, config.workers)
+		}
+		if config.suggest {
+			client.AddSuggestions(ctx, result)
+		}
+		if fresh == nil {
+			history.recordTurn(modelFor(config, result), result, parentID)
+		}
+
+		err = newRenderer(config).result(result)
+		runPostHook(ctx, config, result)
+		deliverWebhook(ctx, config, result)
+		deliverSlack(ctx, config, result)
+		notifyDone(config, result)
+		if err != nil {
+			if ctx.Err() != nil {
+				exitInterruptedWith(0, 1)
+			}
+			exit(exitFailure)
+		}
+		if config.diffWith != "" {
+			printAnswerDiff(entrySide(diffFrom), resultSide(result, modelFor(config, result)), config.wordDiff)
+		}
+		if config.toClipboard {
+			if err := copyResult(result); err != nil {
+				handleError(err, "Failed to copy result")
 			}
 		}
-		
-		if err := result.Output(config.outputJSON); err != nil {
-			os.Exit(1)
+		if err := exportResults(config, []search.Result{*result}); err != nil {
+			handleError(err, "Failed to export results")
+		}
+		if config.showCost {
+			printCostSummary(modelFor(config, result), result.PromptTokens, result.OutputTokens, result.ThinkingTokens, result.CostUSD)
+		}
+		if ctx.Err() != nil {
+			exitInterruptedWith(1, 1)
 		}
 		return
 	}
-	
+
 	// Handle multiple queries
 	if len(config.queries) > 0 {
-		multiResult, err := processMultipleQueries(ctx, config.queries, config, client)
+		var multiResult *MultiSearchResult
+		var err error
+		if config.tui {
+			multiResult, err = runTUI(ctx, config, client)
+		} else {
+			multiResult, err = processMultipleQueries(ctx, config.queries, config, client, nil)
+		}
 		if err != nil {
 			handleError(err, "Multi-query search failed")
 		}
-		
-		if err := multiResult.Output(config.outputJSON, config.stream, config.includeSummary); err != nil {
-			os.Exit(1)
+
+		switch {
+		case config.tui:
+			// The TUI has already shown the results
+		case config.concat:
+			err = multiResult.OutputConcat(config.delimiter, config.onlySucceeded)
+		default:
+			err = newRenderer(config).multi(multiResult)
+		}
+		deliverWebhook(ctx, config, multiResult)
+		deliverSlack(ctx, config, multiResult)
+		notifyDone(config, multiResult)
+		if err != nil {
+			exit(exitFailure)
+		}
+		if err := exportResults(config, multiResult.Results); err != nil {
+			handleError(err, "Failed to export results")
+		}
+		if config.report != "" {
+			if err := writeReport(config.report, multiResult, config.model); err != nil {
+				handleError(err, "Failed to write report")
+			}
 		}
-		
+		if len(config.email) > 0 {
+			if err := emailReport(config, multiResult); err != nil {
+				handleError(err, "Failed to email report")
+			}
+		}
+		if config.showCost {
+			printCostSummary(config.model, multiResult.PromptTokens, multiResult.OutputTokens, multiResult.ThinkingTokens, multiResult.CostUSD)
+		}
+		if multiResult.Interrupted {
+			finished := 0
+			for _, result := range multiResult.Results {
+				if result.Success {
+					finished++
+				}
+			}
+			exitInterruptedWith(finished, len(multiResult.Results))
+		}
+
 		if !multiResult.Success {
-			os.Exit(1)
+			exit(exitCodeFor(multiResult.ErrorCode))
 		}
 	}
-}
\ No newline at end of file
+}
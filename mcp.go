@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qiushiyan/gemini-search/mcp"
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Reported to MCP clients during initialization
+const mcpServerVersion = "1.0.0"
+
+// runMCP serves the search and summarize tools over stdio until stdin closes
+func runMCP(ctx context.Context, config *Config, client *search.Client) error {
+	server := mcp.NewServer("go-search", mcpServerVersion)
+
+	server.AddTool(mcp.Tool{
+		Name:        "web_search",
+		Description: "Search the web and return a grounded answer with its sources. Partial answer text is streamed as progress notifications when a progress token is given.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query":           map[string]any{"type": "string", "description": "The question or search query"},
+				"include_summary": map[string]any{"type": "boolean", "description": "Also return a 1-3 sentence summary"},
+			},
+			"required": []string{"query"},
+		},
+		Handler: func(ctx context.Context, arguments json.RawMessage, progress func(string)) (*mcp.ToolResult, error) {
+			var args struct {
+				Query          string `json:"query"`
+				IncludeSummary bool   `json:"include_summary"`
+			}
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if strings.TrimSpace(args.Query) == "" {
+				return nil, errors.New("query is required")
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, config.timeout)
+			defer cancel()
+			ctx = search.WithRequestID(ctx, search.NewRequestID())
+
+			result, err := client.SearchStream(ctx, args.Query, func(event search.StreamEvent) {
+				switch event.Type {
+				case search.EventChunk:
+					if event.Text != "" {
+						progress(event.Text)
+					}
+				case search.EventRetry:
+					progress("\n[Retrying...]\n")
+				}
+			})
+			if err == nil && (args.IncludeSummary || config.includeSummary) {
+				client.AddSummary(ctx, result)
+			}
+			history.record(config.model, result)
+			if err != nil {
+				return mcp.ErrorResult(fmt.Errorf("search failed: %w", err)), nil
+			}
+
+			return &mcp.ToolResult{
+				Content:           []mcp.Content{{Type: "text", Text: mcpResultText(result)}},
+				StructuredContent: result,
+			}, nil
+		},
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        "summarize",
+		Description: "Condense a search answer or other text into a 1-3 sentence summary for the given query.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string", "description": "The question the text answers"},
+				"text":  map[string]any{"type": "string", "description": "The text to summarize"},
+			},
+			"required": []string{"query", "text"},
+		},
+		Handler: func(ctx context.Context, arguments json.RawMessage, progress func(string)) (*mcp.ToolResult, error) {
+			var args struct {
+				Query string `json:"query"`
+				Text  string `json:"text"`
+			}
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			if strings.TrimSpace(args.Text) == "" {
+				return nil, errors.New("text is required")
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, config.timeout)
+			defer cancel()
+
+			summary, err := client.Summarize(ctx, args.Query, args.Text)
+			if err != nil {
+				return mcp.ErrorResult(fmt.Errorf("summary failed: %w", err)), nil
+			}
+			return mcp.TextResult(summary.Text), nil
+		},
+	})
+
+	fmt.Fprintf(os.Stderr, "Serving MCP tools on stdio\n")
+	return server.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// mcpResultText renders a result the way the text output does, with the
+// summary first and a numbered sources list
+func mcpResultText(r *search.Result) string {
+	var b strings.Builder
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "## SUMMARY\n%s\n\n## DETAILED RESPONSE\n", r.Summary)
+	}
+	b.WriteString(r.Response)
+	if showSources && len(r.Sources) > 0 {
+		b.WriteString("\n\n## SOURCES\n")
+		for i, source := range r.Sources {
+			fmt.Fprintf(&b, "%d. %s - %s\n", i+1, source.Title, source.URI)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,278 @@
+// Package mcp implements a minimal Model Context Protocol server that
+// exposes tools over newline-delimited JSON-RPC on stdio.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Protocol versions this server can speak, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Content is one block of a tool result
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ToolResult is returned by a tool handler. Failures the calling model should
+// see go in a result with IsError set rather than in a Go error.
+type ToolResult struct {
+	Content           []Content `json:"content"`
+	StructuredContent any       `json:"structuredContent,omitempty"`
+	IsError           bool      `json:"isError,omitempty"`
+}
+
+// TextResult returns a result with a single text block
+func TextResult(text string) *ToolResult {
+	return &ToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// ErrorResult returns a failed result describing err
+func ErrorResult(err error) *ToolResult {
+	result := TextResult(err.Error())
+	result.IsError = true
+	return result
+}
+
+// Tool is a callable tool. Handler receives the raw arguments and a progress
+// function that streams partial output to clients that asked for it.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema map[string]any
+	Handler     func(ctx context.Context, arguments json.RawMessage, progress func(message string)) (*ToolResult, error)
+}
+
+// Server dispatches MCP requests to registered tools
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewServer creates a server that reports name and version to clients
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version, cancels: map[string]context.CancelFunc{}}
+}
+
+// AddTool registers a tool; call before Serve
+func (s *Server) AddTool(tool Tool) {
+	s.tools = append(s.tools, tool)
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Serve reads requests from r and writes responses to w until r ends or ctx
+// is done. Tool calls run concurrently; Serve waits for them before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.encoder = json.NewEncoder(w)
+	defer s.wg.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.send(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		s.handle(ctx, req)
+	}
+	return scanner.Err()
+}
+
+func (s *Server) send(v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.encoder.Encode(v); err != nil {
+		slog.Error("Failed to write MCP message", "error", err)
+	}
+}
+
+func (s *Server) reply(id json.RawMessage, result any) {
+	s.send(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) fail(id json.RawMessage, code int, message string) {
+	s.send(response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+func (s *Server) handle(ctx context.Context, req request) {
+	isNotification := len(req.ID) == 0
+
+	switch req.Method {
+	case "initialize":
+		s.reply(req.ID, s.initialize(req.Params))
+	case "ping":
+		s.reply(req.ID, struct{}{})
+	case "tools/list":
+		s.reply(req.ID, map[string]any{"tools": s.toolList()})
+	case "tools/call":
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.callTool(ctx, req)
+		}()
+	case "notifications/cancelled":
+		var params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		if json.Unmarshal(req.Params, &params) == nil {
+			s.mu.Lock()
+			if cancel, ok := s.cancels[string(params.RequestID)]; ok {
+				cancel()
+			}
+			s.mu.Unlock()
+		}
+	default:
+		if isNotification {
+			// Notifications such as notifications/initialized need no answer
+			return
+		}
+		s.fail(req.ID, codeMethodNotFound, fmt.Sprintf("method %q not found", req.Method))
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) map[string]any {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(params, &p)
+
+	version := protocolVersions[0]
+	for _, supported := range protocolVersions {
+		if p.ProtocolVersion == supported {
+			version = supported
+		}
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{}},
+		"serverInfo":      map[string]any{"name": s.name, "version": s.version},
+	}
+}
+
+func (s *Server) toolList() []map[string]any {
+	tools := make([]map[string]any, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, map[string]any{
+			"name":        tool.Name,
+			"description": tool.Description,
+			"inputSchema": tool.InputSchema,
+		})
+	}
+	return tools
+}
+
+func (s *Server) callTool(ctx context.Context, req request) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+		Meta      struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.fail(req.ID, codeInvalidParams, err.Error())
+		return
+	}
+
+	var tool *Tool
+	for i := range s.tools {
+		if s.tools[i].Name == params.Name {
+			tool = &s.tools[i]
+		}
+	}
+	if tool == nil {
+		s.fail(req.ID, codeInvalidParams, fmt.Sprintf("unknown tool %q", params.Name))
+		return
+	}
+	if len(params.Arguments) == 0 {
+		params.Arguments = json.RawMessage("{}")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.cancels[string(req.ID)] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, string(req.ID))
+		s.mu.Unlock()
+		cancel()
+	}()
+
+	// Progress is only sent when the client supplied a token to match it by
+	progress := func(string) {}
+	if len(params.Meta.ProgressToken) > 0 {
+		var mu sync.Mutex
+		count := 0
+		progress = func(message string) {
+			mu.Lock()
+			count++
+			n := count
+			mu.Unlock()
+			s.send(notification{JSONRPC: "2.0", Method: "notifications/progress", Params: map[string]any{
+				"progressToken": params.Meta.ProgressToken,
+				"progress":      n,
+				"message":       message,
+			}})
+		}
+	}
+
+	result, err := tool.Handler(ctx, params.Arguments, progress)
+	if err != nil {
+		result = ErrorResult(err)
+	}
+	s.reply(req.ID, result)
+}
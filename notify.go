@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+const notifyTimeout = 5 * time.Second
+
+// Longest query shown in a notification, in runes
+const notifyQueryLength = 100
+
+// Shows a toast with the text of $GOSEARCH_NOTIFY_TITLE and
+// $GOSEARCH_NOTIFY_BODY, so neither needs quoting
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$toast = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $toast.GetElementsByTagName('text')
+$text.Item(0).AppendChild($toast.CreateTextNode($env:GOSEARCH_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($toast.CreateTextNode($env:GOSEARCH_NOTIFY_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('go-search').Show([Windows.UI.Notifications.ToastNotification]::new($toast))`
+
+// notifyDone shows a desktop notification for a finished run with -notify.
+// Failures are reported on stderr but never change the exit status.
+func notifyDone(config *Config, payload any) {
+	if !config.notify {
+		return
+	}
+	title, body := notification(payload)
+	if err := sendNotification(title, body); err != nil {
+		slog.Error("Desktop notification failed", "error", err)
+		fmt.Fprintf(os.Stderr, "Warning: desktop notification failed: %v\n", err)
+	}
+}
+
+// notification returns the title and text announcing payload
+func notification(payload any) (string, string) {
+	switch p := payload.(type) {
+	case *search.Result:
+		if !p.Success {
+			return "Search failed", shorten(p.Query) + ": " + p.Error
+		}
+		return "Search finished", shorten(p.Query)
+	case *MultiSearchResult:
+		succeeded := 0
+		for _, result := range p.Results {
+			if result.Success {
+				succeeded++
+			}
+		}
+		return "Batch finished", fmt.Sprintf("%d/%d queries succeeded in %s", succeeded, len(p.Results), p.TotalTime.Round(time.Second))
+	case *SweepResult:
+		succeeded := 0
+		for _, run := range p.Runs {
+			if run.Result.Success {
+				succeeded++
+			}
+		}
+		return "Thinking sweep finished", fmt.Sprintf("%d/%d budgets succeeded: %s", succeeded, len(p.Runs), shorten(p.Query))
+	case *CompareResult:
+		succeeded := 0
+		for _, run := range p.Runs {
+			if run.Result.Success {
+				succeeded++
+			}
+		}
+		return "Comparison finished", fmt.Sprintf("%d/%d models succeeded: %s", succeeded, len(p.Runs), shorten(p.Query))
+	}
+	return "go-search finished", ""
+}
+
+// shorten collapses whitespace in query and cuts it to notifyQueryLength
+func shorten(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if runes := []rune(query); len(runes) > notifyQueryLength {
+		return string(runes[:notifyQueryLength-1]) + "…"
+	}
+	return query
+}
+
+// sendNotification shows title and body with the platform's notifier:
+// osascript on macOS, PowerShell on Windows, notify-send elsewhere
+func sendNotification(title, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		cmd.Env = append(os.Environ(), "GOSEARCH_NOTIFY_TITLE="+title, "GOSEARCH_NOTIFY_BODY="+body)
+	default:
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return fmt.Errorf("notify-send not found (install libnotify)")
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=go-search", title, body)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
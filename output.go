@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Set once flags are parsed; off with -inline-citations, whose responses
+// already end with their own sources list
+var showSources = true
+
+// dim renders reasoning text faintly when stdout is a terminal
+func dim(text string) string {
+	if !useColor || !isTerminal(os.Stdout) {
+		return text
+	}
+	return ansiDim + text + ansiReset
+}
+
+func printSources(sources []search.Source) {
+	if !showSources || len(sources) == 0 {
+		return
+	}
+	fmt.Printf("\n## %s\n", labels.Sources)
+	for i, source := range sources {
+		fmt.Printf("%d. %s - %s\n", i+1, source.Title, source.URI)
+	}
+}
+
+// printNotes lists the note files an answer was given excerpts from
+func printNotes(notes []search.NoteMatch) {
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Printf("\n## %s\n", labels.Notes)
+	for i, note := range noteSources(notes) {
+		fmt.Printf("%d. %s (%.2f)\n", i+1, note.Source, note.Similarity)
+	}
+}
+
+// noteSources keeps the closest excerpt of each note file
+func noteSources(notes []search.NoteMatch) []search.NoteMatch {
+	var sources []search.NoteMatch
+	seen := map[string]bool{}
+	for _, note := range notes {
+		if !seen[note.Source] {
+			seen[note.Source] = true
+			sources = append(sources, note)
+		}
+	}
+	return sources
+}
+
+// verdictMarks prefix each claim of a verification
+var verdictMarks = map[search.Verdict]string{
+	search.VerdictSupported:   "✓",
+	search.VerdictUnsupported: "✗",
+	search.VerdictUncertain:   "?",
+}
+
+// printVerification lists the checked claims of an answer with their
+// verdicts and evidence
+func printVerification(v *search.Verification) {
+	if v == nil {
+		return
+	}
+	if v.Error != "" {
+		fmt.Fprintf(os.Stderr, "Verification failed: %s\n", v.Error)
+	}
+	if len(v.Claims) == 0 {
+		return
+	}
+	fmt.Printf("\n## %s\n", labels.Verification)
+	for _, claim := range v.Claims {
+		fmt.Printf("%s [%s %.2f] %s\n", verdictMarks[claim.Verdict], claim.Verdict, claim.Confidence, claim.Text)
+		if claim.Explanation != "" {
+			fmt.Printf("  %s\n", claim.Explanation)
+		}
+		if showSources {
+			for _, source := range claim.Sources {
+				fmt.Printf("  - %s - %s\n", source.Title, source.URI)
+			}
+		}
+	}
+	if n := v.Unsupported(); n > 0 {
+		fmt.Fprintf(os.Stderr, "Verification: %d of %d claims unsupported\n", n, len(v.Claims))
+	}
+}
+
+// printCaveats lists the uncertain points of a rated answer
+func printCaveats(caveats []string) {
+	if len(caveats) == 0 {
+		return
+	}
+	fmt.Printf("\n## %s\n", labels.Caveats)
+	for _, caveat := range caveats {
+		fmt.Printf("- %s\n", caveat)
+	}
+}
+
+// printSuggestions numbers the follow-up queries proposed for an answer
+func printSuggestions(queries []string) {
+	if len(queries) == 0 {
+		return
+	}
+	fmt.Printf("\n## %s\n", labels.Suggestions)
+	for i, query := range queries {
+		fmt.Printf("%d. %s\n", i+1, query)
+	}
+}
+
+// confidenceNote describes the rating of an answer, highlighted when it is
+// low
+func confidenceNote(r *search.Result) string {
+	if r.Confidence == nil {
+		return ""
+	}
+	if !r.LowConfidence() {
+		return fmt.Sprintf("[confidence: %.2f]", *r.Confidence)
+	}
+	note := fmt.Sprintf("⚠ %s: %.2f", labels.LowConfidence, *r.Confidence)
+	if useColor && isTerminal(os.Stdout) {
+		note = ansiBold + ansiYellow + note + ansiReset
+	}
+	return note
+}
+
+// textRenderer is the default human-readable -format
+type textRenderer struct {
+	stream         bool
+	includeSummary bool
+	// Leave out headers and banners, and send notes about the search to
+	// stderr, so stdout only carries answers
+	quiet bool
+}
+
+// note prints an annotation about the search, on stderr with -quiet
+func (t textRenderer) note(format string, args ...any) {
+	if t.quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+func (t textRenderer) result(r *search.Result) error {
+	if !r.Success {
+		fmt.Fprintf(os.Stderr, "Search failed: %s\n", r.Error)
+		return fmt.Errorf("search failed")
+	}
+
+	// Structured output is printed alone so it can be piped as JSON
+	if len(r.Structured) > 0 {
+		return printStructured(r.Structured)
+	}
+
+	if r.Category != "" {
+		t.note("[category: %s, confidence %.2f]\n\n", r.Category, r.CategoryConfidence)
+	}
+	if r.RewrittenQuery != "" {
+		t.note("[searched as: %s]\n\n", r.RewrittenQuery)
+	}
+	if note := confidenceNote(r); note != "" {
+		t.note("%s\n\n", note)
+	}
+
+	// Show summary first if available
+	if r.Summary != "" {
+		fmt.Printf("## %s\n%s\n\n", labels.Summary, answerText(r.Summary))
+		fmt.Printf("## %s\n", labels.DetailedResponse)
+	}
+
+	fmt.Println(answerText(r.Response))
+	printSources(r.Sources)
+	printNotes(r.Notes)
+	printCaveats(r.Caveats)
+	printVerification(r.Verification)
+	printSuggestions(r.SuggestedQueries)
+
+	for _, warning := range r.CitationWarnings {
+		fmt.Fprintf(os.Stderr, "Citation warning: %s\n", warning)
+	}
+	return nil
+}
+
+func (t textRenderer) multi(m *MultiSearchResult) error {
+	if t.stream {
+		// In stream mode, results already shown, just show completion
+		successful := 0
+		for _, result := range m.Results {
+			if result.Success {
+				successful++
+			}
+		}
+		if !t.quiet {
+			fmt.Printf("\n🏁 COMPLETED: %d/%d queries\n", successful, len(m.Results))
+		}
+		return nil
+	}
+
+	// Calculate success/failure counts
+	successful := 0
+	failed := 0
+	for _, result := range m.Results {
+		if result.Success {
+			successful++
+		} else {
+			failed++
+		}
+	}
+
+	if t.includeSummary {
+		// Combined overview and summaries section
+		fmt.Printf("## %s\n", labels.SearchResults)
+		fmt.Printf(labels.Completed+"\n\n", successful, len(m.Results))
+
+		for _, result := range m.Results {
+			if result.Success {
+				summary := result.Summary
+				if summary == "" {
+					summary = labels.NoSummary
+				}
+				fmt.Printf("✓ %s%s: %s\n", result.Query, categoryLabel(&result), summary)
+			} else {
+				fmt.Printf("✗ %s%s: %s\n", result.Query, categoryLabel(&result), result.Error)
+			}
+		}
+		fmt.Printf("\n")
+
+		// Detailed responses section (without durations)
+		fmt.Printf("## %s\n\n", labels.DetailedResponses)
+	}
+	for _, result := range m.Results {
+		if len(m.Results) > 1 && !t.quiet {
+			fmt.Printf("=== %s%s ===\n", result.Query, categoryLabel(&result))
+		}
+		if result.Success && len(result.Structured) > 0 {
+			printStructured(result.Structured)
+		} else if result.Success {
+			fmt.Printf("%s\n", answerText(result.Response))
+			printSources(result.Sources)
+			printNotes(result.Notes)
+			printCaveats(result.Caveats)
+			printVerification(result.Verification)
+			printSuggestions(result.SuggestedQueries)
+		} else if t.quiet {
+			fmt.Fprintf(os.Stderr, "Search failed: %s: %s\n", result.Query, result.Error)
+			continue
+		} else {
+			fmt.Printf("%s - %s\n", labels.Failed, result.Error)
+		}
+		fmt.Printf("\n")
+	}
+	if len(m.Results) > 1 && !t.quiet {
+		printStats(m.Stats)
+	}
+
+	return nil
+}
+
+// OutputConcat prints the raw responses joined by delimiter, without any
+// headers, summaries, or status lines
+func (m *MultiSearchResult) OutputConcat(delimiter string, onlySucceeded bool) error {
+	var parts []string
+	for _, result := range m.Results {
+		if result.Success {
+			parts = append(parts, result.Response)
+		} else if !onlySucceeded {
+			parts = append(parts, fmt.Sprintf("FAILED: %s", result.Error))
+		}
+	}
+
+	_, err := fmt.Println(strings.Join(parts, delimiter))
+	return err
+}
+
+func printStructured(data json.RawMessage) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return err
+	}
+	_, err := fmt.Println(out.String())
+	return err
+}
+
+// categoryLabel tags a query in multi-query output with its category, and
+// with ⚠ when its answer was rated low confidence
+func categoryLabel(r *search.Result) string {
+	label := ""
+	if r.Category != "" {
+		label = fmt.Sprintf(" [%s]", r.Category)
+	}
+	if r.LowConfidence() {
+		label += " ⚠"
+	}
+	return label
+}
+
+// printCostSummary writes token totals and the estimated cost to stderr, so
+// it never mixes with -json or -concat output
+func printCostSummary(model string, promptTokens, outputTokens, thinkingTokens int32, cost float64) {
+	fmt.Fprintf(os.Stderr, "\nTokens: %d prompt, %d output, %d thinking", promptTokens, outputTokens, thinkingTokens)
+	if _, ok := search.ModelPrices[model]; !ok {
+		fmt.Fprintf(os.Stderr, " (no price known for %s)\n", model)
+		return
+	}
+	fmt.Fprintf(os.Stderr, " | Estimated cost: $%.6f (excludes grounding fees)\n", cost)
+}
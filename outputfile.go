@@ -0,0 +1,67 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Set by openOutputFile; flushes and closes -output-file before exiting
+var closeOutputFile = func() error { return nil }
+
+// outputFilePath is -output-file, with .gz added for -compress
+func outputFilePath(config *Config) (string, bool) {
+	path := config.outputFile
+	if strings.HasSuffix(path, ".gz") {
+		return path, true
+	}
+	if config.compress {
+		return path + ".gz", true
+	}
+	return path, false
+}
+
+// openOutputFile sends stdout to -output-file. Gzipped output goes through
+// a pipe, so everything written to os.Stdout is compressed on its way to the
+// file; closeOutputFile drains the pipe and writes the gzip footer.
+func openOutputFile(config *Config) error {
+	path, compress := outputFilePath(config)
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	stdout := os.Stdout
+	if !compress {
+		os.Stdout = f
+		closeOutputFile = sync.OnceValue(func() error {
+			os.Stdout = stdout
+			return f.Close()
+		})
+		return nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(gz, r)
+		copied <- err
+	}()
+	os.Stdout = w
+	closeOutputFile = sync.OnceValue(func() error {
+		os.Stdout = stdout
+		w.Close()
+		err := <-copied
+		r.Close()
+		return errors.Join(err, gz.Close(), f.Close())
+	})
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputFilePath(t *testing.T) {
+	tests := []struct {
+		outputFile   string
+		compress     bool
+		want         string
+		wantCompress bool
+	}{
+		{outputFile: "answers.json", want: "answers.json"},
+		{outputFile: "answers.json", compress: true, want: "answers.json.gz", wantCompress: true},
+		{outputFile: "answers.json.gz", want: "answers.json.gz", wantCompress: true},
+		{outputFile: "answers.json.gz", compress: true, want: "answers.json.gz", wantCompress: true},
+		{outputFile: "answers.gzip", want: "answers.gzip"},
+	}
+	for _, tt := range tests {
+		path, compress := outputFilePath(&Config{outputFile: tt.outputFile, compress: tt.compress})
+		if path != tt.want || compress != tt.wantCompress {
+			t.Errorf("outputFilePath(%q, compress=%v) = %q, %v, want %q, %v", tt.outputFile, tt.compress, path, compress, tt.want, tt.wantCompress)
+		}
+	}
+}
+
+// writeOutputFile sends lines to stdout through openOutputFile and closes it
+func writeOutputFile(t *testing.T, config *Config, lines int) string {
+	t.Helper()
+	stdout := os.Stdout
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		closeOutputFile = func() error { return nil }
+	})
+
+	if err := openOutputFile(config); err != nil {
+		t.Fatal(err)
+	}
+	if os.Stdout == stdout {
+		t.Fatal("stdout was not redirected")
+	}
+	var want strings.Builder
+	for i := range lines {
+		line := fmt.Sprintf("answer %d\n", i)
+		fmt.Fprint(os.Stdout, line)
+		want.WriteString(line)
+	}
+	if err := closeOutputFile(); err != nil {
+		t.Fatal(err)
+	}
+	if os.Stdout != stdout {
+		t.Error("stdout was not restored")
+	}
+	// Closing again, as exit does after main's deferred close, is a no-op
+	if err := closeOutputFile(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+	return want.String()
+}
+
+func TestOpenOutputFileCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.jsonl")
+	// More than a pipe buffer, so the copy has to keep draining it
+	want := writeOutputFile(t, &Config{outputFile: path, compress: true}, 20000)
+
+	f, err := os.Open(path + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reading to EOF checks the footer and its checksum
+	got, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("gunzip: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("gunzipped %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func TestOpenOutputFilePlain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.txt")
+	want := writeOutputFile(t, &Config{outputFile: path}, 100)
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(path + ".gz"); !os.IsNotExist(err) {
+		t.Errorf("plain output also wrote %s.gz", path)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// outputTemplateFuncs are available in -template-file templates
+var outputTemplateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		return string(data), err
+	},
+	"join":    strings.Join,
+	"trim":    strings.TrimSpace,
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"oneline": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+	"truncate": func(n int, s string) string {
+		return truncateRunes(s, n)
+	},
+	"answer": answerText,
+}
+
+// loadOutputTemplate parses the -template-file template
+func loadOutputTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(outputTemplateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateRenderer executes a user template with the result structs, the
+// same values -format json encodes. A file can define "result", "multi",
+// "sweep" and "compare" templates for each kind of output; the file itself
+// renders any kind it does not define.
+type templateRenderer struct {
+	tmpl *template.Template
+}
+
+func (t templateRenderer) result(r *search.Result) error    { return t.execute("result", r) }
+func (t templateRenderer) multi(m *MultiSearchResult) error { return t.execute("multi", m) }
+func (t templateRenderer) sweep(s *SweepResult) error       { return t.execute("sweep", s) }
+func (t templateRenderer) compare(c *CompareResult) error   { return t.execute("compare", c) }
+
+func (t templateRenderer) execute(name string, data any) error {
+	tmpl := t.tmpl
+	if defined := t.tmpl.Lookup(name); defined != nil {
+		tmpl = defined
+	}
+	if err := tmpl.Execute(os.Stdout, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to render output template: %v\n", err)
+		return err
+	}
+	return nil
+}
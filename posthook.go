@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// How long a -post-hook command may run for one result
+const postHookTimeout = 30 * time.Second
+
+// Hooks run one at a time, so a script appending to a file never sees
+// results from concurrent queries interleaved
+var postHookMu sync.Mutex
+
+// runPostHook pipes the JSON of r to the -post-hook command, if set.
+// Failures are reported on stderr but never fail the search.
+func runPostHook(ctx context.Context, config *Config, r *search.Result) {
+	if config.postHook == "" {
+		return
+	}
+	postHookMu.Lock()
+	defer postHookMu.Unlock()
+
+	// Run hooks for what finished even if Ctrl-C cancelled the searches
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), postHookTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := execPostHook(ctx, config.postHook, r); err != nil {
+		slog.Error("Post hook failed", "command", config.postHook, "query", r.Query, "error", err)
+		fmt.Fprintf(os.Stderr, "Warning: post hook failed: %v\n", err)
+		return
+	}
+	slog.Info("Post hook finished", "command", config.postHook, "query", r.Query, "duration", time.Since(start).Round(time.Millisecond))
+}
+
+func execPostHook(ctx context.Context, command string, r *search.Result) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	// The command goes through the shell, so it may carry arguments and pipes
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	// Keep stdout for the search output
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"GOSEARCH_REQUEST_ID="+r.ID,
+		"GOSEARCH_QUERY="+r.Query,
+		fmt.Sprintf("GOSEARCH_SUCCESS=%t", r.Success),
+	)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", postHookTimeout)
+		}
+		return err
+	}
+	return nil
+}
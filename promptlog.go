@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"google.golang.org/genai"
+)
+
+type promptLogEntry struct {
+	ID                string                       `json:"id"`
+	Kind              string                       `json:"kind"`
+	Attempt           int                          `json:"attempt"`
+	Timestamp         time.Time                    `json:"timestamp"`
+	Model             string                       `json:"model"`
+	SystemInstruction string                       `json:"system_instruction,omitempty"`
+	Contents          []*genai.Content             `json:"contents"`
+	Config            *genai.GenerateContentConfig `json:"config,omitempty"`
+}
+
+// promptLogger appends every request sent to the API as a JSONL record.
+// Only the request body is recorded; credentials live in the client and
+// headers and are never written.
+type promptLogger struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// Set from -prompt-log; nil disables prompt logging
+var promptLog *promptLogger
+
+func openPromptLog(path string) (*promptLogger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompt log: %w", err)
+	}
+	return &promptLogger{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// record is used as search.Options.OnRequest
+func (l *promptLogger) record(ctx context.Context, req search.Request) {
+	if l == nil {
+		return
+	}
+
+	entry := promptLogEntry{
+		ID:        req.ID,
+		Kind:      req.Kind,
+		Attempt:   req.Attempt,
+		Timestamp: time.Now(),
+		Model:     req.Model,
+		Contents:  req.Contents,
+	}
+	entry.SystemInstruction, entry.Config = splitSystemInstruction(req.Config)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.encoder.Encode(entry)
+}
+
+// splitSystemInstruction returns the text of config's system instruction and
+// a copy of config without it, so the prompt reads as plain text
+func splitSystemInstruction(config *genai.GenerateContentConfig) (string, *genai.GenerateContentConfig) {
+	if config == nil {
+		return "", nil
+	}
+	var text string
+	if config.SystemInstruction != nil {
+		for _, part := range config.SystemInstruction.Parts {
+			text += part.Text
+		}
+	}
+	configCopy := *config
+	configCopy.SystemInstruction = nil
+	return text, &configCopy
+}
+
+func (l *promptLogger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defaultPromptDir returns ~/.config/go-search/prompts, or the platform's
+// equivalent user config directory
+func defaultPromptDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-search", "prompts")
+}
+
+// loadPrompts reads the custom system and summary prompts. A file given by
+// flag must exist; otherwise system.txt and summary.txt in the prompt
+// directory are used when present.
+func loadPrompts(config *Config) error {
+	var err error
+	if config.systemPrompt, err = loadPrompt(config.systemPromptFile, "system"); err != nil {
+		return err
+	}
+	if config.summaryPrompt, err = loadPrompt(config.summaryPromptFile, "summary"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func loadPrompt(path, name string) (string, error) {
+	explicit := path != ""
+	if !explicit {
+		dir := defaultPromptDir()
+		if dir == "" {
+			return "", nil
+		}
+		path = filepath.Join(dir, name+".txt")
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s prompt: %w", name, err)
+	}
+	return string(data), nil
+}
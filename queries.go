@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readQueries returns one query per non-empty line, skipping # comments
+func readQueries(r io.Reader) ([]string, error) {
+	var queries []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		queries = append(queries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// loadQueries adds queries from -queries-file, or from piped stdin when no
+// query was given on the command line. A line can end with #key=value tags,
+// and a !nocache word on it skips the response cache for that query.
+func loadQueries(config *Config) error {
+	var source io.Reader
+	name := config.queriesFile
+
+	switch {
+	case name == "-":
+		source = os.Stdin
+		name = "stdin"
+	case name != "":
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open queries file: %w", err)
+		}
+		defer f.Close()
+		source = f
+	case config.query == "" && len(config.queries) == 0 && len(config.urls) == 0 && !config.interactive && config.serve == "" && config.grpc == "" && !config.mcp && !config.daemon && !config.authCheck && !isTerminal(os.Stdin):
+		source = os.Stdin
+		name = "stdin"
+	default:
+		return nil
+	}
+
+	queries, err := readQueries(source)
+	if err != nil {
+		return fmt.Errorf("failed to read queries from %s: %w", name, err)
+	}
+	if len(queries) == 0 && config.queriesFile != "" {
+		return fmt.Errorf("no queries found in %s", name)
+	}
+	for _, line := range queries {
+		line, noCache := splitNoCache(line)
+		query, tags := splitQueryTags(line)
+		if noCache {
+			if config.noCacheQueries == nil {
+				config.noCacheQueries = make(map[string]bool)
+			}
+			config.noCacheQueries[query] = true
+		}
+		if tags != nil {
+			if config.queryTags == nil {
+				config.queryTags = make(map[string]map[string]string)
+			}
+			config.queryTags[query] = tags
+		}
+		config.queries = append(config.queries, query)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// expandQueryTemplate adds one query per -vars row, rendered from -template
+func expandQueryTemplate(config *Config) error {
+	if config.query != "" || len(config.queries) > 0 || config.queriesFile != "" || len(config.urls) > 0 {
+		return fmt.Errorf("-template cannot be combined with a query, -q, -queries-file, or URLs")
+	}
+	if config.templateVars == "" {
+		return fmt.Errorf("-template requires -vars")
+	}
+	// A missing variable is almost always a typo in the template or header
+	tmpl, err := template.New("query").Option("missingkey=error").Parse(config.queryTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid query template: %w", err)
+	}
+	rows, err := loadTemplateVars(config.templateVars)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no rows found in %s", config.templateVars)
+	}
+
+	for i, row := range rows {
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, row); err != nil {
+			return fmt.Errorf("failed to render query for row %d of %s: %w", i+1, config.templateVars, err)
+		}
+		query := strings.TrimSpace(b.String())
+		if query == "" {
+			return fmt.Errorf("query for row %d of %s is empty", i+1, config.templateVars)
+		}
+		config.queries = append(config.queries, query)
+	}
+	return nil
+}
+
+// loadTemplateVars reads the rows of a CSV file with a header line, or of a
+// JSON array of objects. "-" reads stdin; the format follows the extension,
+// or the content when there is none.
+func loadTemplateVars(path string) ([]map[string]any, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template variables: %w", err)
+	}
+
+	isJSON := strings.HasPrefix(strings.TrimSpace(string(data)), "[")
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		isJSON = true
+	case ".csv":
+		isJSON = false
+	}
+	if isJSON {
+		var rows []map[string]any
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("invalid template variables in %s (expected an array of objects): %w", path, err)
+		}
+		return rows, nil
+	}
+	return csvRows(path, data)
+}
+
+func csvRows(path string, data []byte) ([]map[string]any, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid template variables in %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	for i, name := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+	var rows []map[string]any
+	for _, record := range records[1:] {
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
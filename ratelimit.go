@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by the workers of a batch. It holds
+// one token and refills at rpm per minute, so queries start evenly spaced
+// instead of in bursts that trip the API quota.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	tokens   float64
+	last     time.Time
+}
+
+func newRateLimiter(rpm int) *rateLimiter {
+	return &rateLimiter{
+		interval: time.Minute / time.Duration(rpm),
+		tokens:   1,
+		last:     time.Now(),
+	}
+}
+
+// wait blocks until a token is available and returns how long it waited.
+// A nil limiter never waits.
+func (l *rateLimiter) wait(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	// Reserve a token now; a negative balance is the time owed before it is ours
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(1, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens * float64(l.interval))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		// Hand the token back so later callers are not delayed for it
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Characters of an answer embedded for recall; the embedding model reads
+// only the start of longer inputs
+const recallTextChars = 8000
+
+// recallMatch is an earlier search similar to a recall query
+type recallMatch struct {
+	historyEntry
+	Similarity float64 `json:"similarity"`
+}
+
+// runRecall looks for earlier answers close to the query and prints them.
+// It reports false when none reaches -recall-threshold, so the caller can
+// search the web instead.
+func runRecall(ctx context.Context, config *Config, client *search.Client) (bool, error) {
+	if history == nil {
+		return false, fmt.Errorf("search history is disabled")
+	}
+	if err := history.embedAnswers(ctx, client, config.quiet); err != nil {
+		return false, fmt.Errorf("failed to embed history: %w", err)
+	}
+	vectors, err := client.Embed(ctx, []string{config.query}, search.EmbedQuery)
+	if err != nil {
+		return false, fmt.Errorf("failed to embed query: %w", err)
+	}
+	matches, err := history.similar(vectors[0], config.recallThreshold, config.recallLimit)
+	if err != nil {
+		return false, err
+	}
+	if len(matches) == 0 {
+		if !config.quiet {
+			fmt.Fprintf(os.Stderr, "No earlier answer reaches similarity %.2f, searching the web\n", config.recallThreshold)
+		}
+		return false, nil
+	}
+	return true, printRecall(config, matches)
+}
+
+// embedAnswers stores vectors for the successful searches that have none
+// for search.EmbeddingModel yet
+func (h *historyStore) embedAnswers(ctx context.Context, client *search.Client, quiet bool) error {
+	entries, err := h.query(`WHERE success = 1 AND id NOT IN (SELECT search_id FROM embeddings WHERE model = ?) ORDER BY created_at`, search.EmbeddingModel)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Embedding %d stored answers...\n", len(entries))
+	}
+
+	texts := make([]string, len(entries))
+	for i, e := range entries {
+		answer := e.Summary
+		if answer == "" {
+			answer = e.Response
+		}
+		texts[i] = truncateRunes(e.Query+"\n\n"+answer, recallTextChars)
+	}
+	vectors, err := client.Embed(ctx, texts, search.EmbedDocument)
+	if err != nil {
+		return err
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i, e := range entries {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO embeddings (search_id, model, vector) VALUES (?, ?, ?)`,
+			e.ID, search.EmbeddingModel, encodeVector(vectors[i])); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// similar returns up to limit searches whose cosine similarity to vector is
+// at least threshold, most similar first
+func (h *historyStore) similar(vector []float32, threshold float64, limit int) ([]recallMatch, error) {
+	rows, err := h.db.Query(`SELECT search_id, vector FROM embeddings WHERE model = ?`, search.EmbeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	type scored struct {
+		id         string
+		similarity float64
+	}
+	var candidates []scored
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		if similarity := search.CosineSimilarity(vector, decodeVector(blob)); similarity >= threshold {
+			candidates = append(candidates, scored{id, similarity})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	slices.SortFunc(candidates, func(a, b scored) int {
+		return cmp.Compare(b.similarity, a.similarity)
+	})
+
+	var matches []recallMatch
+	for _, c := range candidates {
+		if len(matches) == limit {
+			break
+		}
+		entries, err := h.query("WHERE id = ?", c.id)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 1 {
+			matches = append(matches, recallMatch{entries[0], c.similarity})
+		}
+	}
+	return matches, nil
+}
+
+// printRecall shows the closest answer in full and lists the others
+func printRecall(config *Config, matches []recallMatch) error {
+	if config.format == formatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(matches)
+	}
+
+	best := matches[0]
+	fmt.Printf("Recalled from %s (similarity %.2f, id %s)\n\n", best.CreatedAt.Local().Format("2006-01-02 15:04"), best.Similarity, best.ID)
+	if err := showHistoryEntry(best.historyEntry, false); err != nil {
+		return err
+	}
+	if len(matches) == 1 {
+		return nil
+	}
+
+	fmt.Println("\nOther matches:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tSIMILARITY\tQUERY")
+	for _, m := range matches[1:] {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\n", m.ID, m.CreatedAt.Local().Format("2006-01-02 15:04"), m.Similarity, truncateQuery(m.Query, 60))
+	}
+	return w.Flush()
+}
+
+// encodeVector stores a vector as little-endian float32s
+func encodeVector(vector []float32) []byte {
+	b := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	vector := make([]float32, len(b)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return vector
+}
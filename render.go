@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by -format
+const (
+	formatText     = "text"
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+	formatJSONL    = "jsonl"
+	formatYAML     = "yaml"
+	formatCSV      = "csv"
+	formatTSV      = "tsv"
+	formatTemplate = "template"
+	// Models side by side, for -compare only
+	formatTable = "table"
+)
+
+var outputFormats = []string{formatText, formatMarkdown, formatJSON, formatJSONL, formatYAML, formatCSV, formatTSV, formatTemplate, formatTable}
+
+// renderer prints results to stdout in one -format
+type renderer interface {
+	result(r *search.Result) error
+	multi(m *MultiSearchResult) error
+	sweep(s *SweepResult) error
+	compare(c *CompareResult) error
+}
+
+func newRenderer(config *Config) renderer {
+	switch config.format {
+	case formatMarkdown:
+		return markdownRenderer{}
+	case formatJSON:
+		return jsonRenderer{}
+	case formatJSONL:
+		return jsonlRenderer{}
+	case formatYAML:
+		return yamlRenderer{}
+	case formatCSV, formatTSV:
+		columns := config.csvColumns
+		// Tagged batches get a tags column unless the columns were chosen
+		if !config.csvColumnsExplicit && (len(config.tags) > 0 || len(config.queryTags) > 0) {
+			columns = append(slices.Clip(columns), "tags")
+		}
+		return csvRenderer{columns: columns, tsv: config.format == formatTSV}
+	case formatTemplate:
+		return templateRenderer{tmpl: config.outputTemplate}
+	case formatTable:
+		return tableRenderer{textRenderer{includeSummary: config.includeSummary, quiet: config.quiet}}
+	}
+	return textRenderer{stream: config.stream, includeSummary: config.includeSummary, quiet: config.quiet}
+}
+
+// jsonRenderer prints each output as one indented JSON document
+type jsonRenderer struct{}
+
+func (jsonRenderer) result(r *search.Result) error    { return encodeJSON(r) }
+func (jsonRenderer) multi(m *MultiSearchResult) error { return encodeJSON(m) }
+func (jsonRenderer) sweep(s *SweepResult) error       { return encodeJSON(s) }
+func (jsonRenderer) compare(c *CompareResult) error   { return encodeJSON(c) }
+
+func encodeJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// jsonlRenderer prints one compact line per result, so multi-query output
+// can be streamed through jq or into log pipelines
+type jsonlRenderer struct{}
+
+func (jsonlRenderer) result(r *search.Result) error {
+	return json.NewEncoder(os.Stdout).Encode(r)
+}
+
+func (jsonlRenderer) multi(m *MultiSearchResult) error {
+	encoder := json.NewEncoder(os.Stdout)
+	for i := range m.Results {
+		if err := encoder.Encode(&m.Results[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (jsonlRenderer) sweep(s *SweepResult) error {
+	encoder := json.NewEncoder(os.Stdout)
+	for _, run := range s.Runs {
+		if err := encoder.Encode(run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (jsonlRenderer) compare(c *CompareResult) error {
+	encoder := json.NewEncoder(os.Stdout)
+	for _, run := range c.Runs {
+		if err := encoder.Encode(run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlRenderer prints the same fields as JSON, with the same names
+type yamlRenderer struct{}
+
+func (yamlRenderer) result(r *search.Result) error    { return encodeYAML(r) }
+func (yamlRenderer) multi(m *MultiSearchResult) error { return encodeYAML(m) }
+func (yamlRenderer) sweep(s *SweepResult) error       { return encodeYAML(s) }
+func (yamlRenderer) compare(c *CompareResult) error   { return encodeYAML(c) }
+
+// encodeYAML goes through JSON so the json struct tags name the keys and
+// their order is kept
+func encodeYAML(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	clearStyle(&node)
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// clearStyle drops the flow and quoting styles parsed from JSON, so the
+// output uses block YAML
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// markdownRenderer prints results as Markdown documents like the -out files,
+// without the front matter
+type markdownRenderer struct{}
+
+func (markdownRenderer) result(r *search.Result) error {
+	if !r.Success {
+		fmt.Fprintf(os.Stderr, "Search failed: %s\n", r.Error)
+		return fmt.Errorf("search failed")
+	}
+	if len(r.Structured) > 0 {
+		return printMarkdownStructured(r.Structured)
+	}
+	_, err := fmt.Print(markdownBody(r))
+	return err
+}
+
+func (markdownRenderer) multi(m *MultiSearchResult) error {
+	for i := range m.Results {
+		r := &m.Results[i]
+		if i > 0 {
+			fmt.Println()
+		}
+		switch {
+		case !r.Success:
+			fmt.Printf("# %s\n\n**Failed:** %s\n", strings.Join(strings.Fields(r.Query), " "), r.Error)
+		case len(r.Structured) > 0:
+			fmt.Printf("# %s\n\n", strings.Join(strings.Fields(r.Query), " "))
+			if err := printMarkdownStructured(r.Structured); err != nil {
+				return err
+			}
+		default:
+			fmt.Print(markdownBody(r))
+		}
+	}
+	return nil
+}
+
+func (markdownRenderer) sweep(s *SweepResult) error {
+	fmt.Printf("# Thinking budget sweep: %s\n\n", s.Query)
+	fmt.Println("| Budget | Status | Latency | Prompt | Thinking | Output |")
+	fmt.Println("|-------:|--------|--------:|-------:|---------:|-------:|")
+	for _, run := range s.Runs {
+		status := "ok"
+		if !run.Result.Success {
+			status = "failed"
+		}
+		fmt.Printf("| %d | %s | %s | %d | %d | %d |\n", run.ThinkingBudget, status,
+			run.Result.Duration.Round(time.Millisecond), run.Result.PromptTokens,
+			run.Result.ThinkingTokens, run.Result.OutputTokens)
+	}
+	for _, run := range s.Runs {
+		fmt.Printf("\n## Thinking budget %d\n\n", run.ThinkingBudget)
+		if run.Result.Success {
+			fmt.Println(strings.TrimSpace(run.Result.Response))
+		} else {
+			fmt.Printf("**Failed:** %s\n", run.Result.Error)
+		}
+	}
+	return nil
+}
+
+func printMarkdownStructured(data json.RawMessage) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return err
+	}
+	_, err := fmt.Printf("```json\n%s\n```\n", out.String())
+	return err
+}
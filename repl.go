@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// streamer is implemented by both *search.Client and *search.Session
+type streamer interface {
+	SearchStream(ctx context.Context, query string, onEvent func(search.StreamEvent)) (*search.Result, error)
+}
+
+// searcher is a streamer that can also search without streaming
+type searcher interface {
+	streamer
+	Search(ctx context.Context, query string) (*search.Result, error)
+}
+
+const replHelp = `Commands:
+  /help      Show this help
+  /history   List the questions asked in this session
+  /reset     Forget the conversation so far
+  /exit      Leave interactive mode (Ctrl-D also works)
+
+With -suggest, type the number of a suggested follow-up to search it.
+End a line with \ to continue on the next line, or wrap a block in """ lines.
+`
+
+// readQuery reads one possibly multiline query. It returns io.EOF when input
+// ends before anything was typed.
+func readQuery(reader *bufio.Reader) (string, error) {
+	var lines []string
+	inBlock := false
+
+	fmt.Print("> ")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if len(lines) > 0 {
+				return strings.Join(lines, "\n"), nil
+			}
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.TrimSpace(line) == `"""`:
+			if inBlock {
+				return strings.Join(lines, "\n"), nil
+			}
+			inBlock = true
+		case inBlock:
+			lines = append(lines, line)
+		case strings.HasSuffix(line, `\`):
+			lines = append(lines, strings.TrimSuffix(line, `\`))
+		default:
+			lines = append(lines, line)
+			return strings.Join(lines, "\n"), nil
+		}
+
+		if err == io.EOF {
+			return strings.Join(lines, "\n"), nil
+		}
+		fmt.Print(". ")
+	}
+}
+
+// runInteractive answers successive queries from stdin, keeping earlier turns
+// as context. An initial query, if given, is asked first.
+func runInteractive(ctx context.Context, config *Config, client *search.Client) {
+	session := client.NewSession()
+	// Latest successful turn, so history links the session's turns together
+	var lastID string
+	if config.resume {
+		turns, id, err := history.sessionConversation(config.session)
+		if err != nil {
+			handleError(err, "Cannot resume session")
+		}
+		session = client.ResumeSession(turns)
+		lastID = id
+	}
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Fprintf(os.Stderr, "Interactive mode, type /help for commands\n")
+	if config.resume {
+		fmt.Fprintf(os.Stderr, "Resumed session %s with %d earlier questions\n", config.session, len(session.Turns()))
+	}
+
+	pending := config.query
+	// Follow-ups proposed for the latest answer, picked by number
+	var suggestions []string
+	for {
+		query := pending
+		pending = ""
+		if query == "" {
+			var err error
+			query, err = readQuery(reader)
+			if err != nil {
+				fmt.Println()
+				return
+			}
+		}
+
+		query = strings.TrimSpace(query)
+		if n, err := strconv.Atoi(query); err == nil && n >= 1 && n <= len(suggestions) {
+			query = suggestions[n-1]
+			fmt.Printf("> %s\n", query)
+		}
+		switch query {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return
+		case "/help":
+			fmt.Print(replHelp)
+			continue
+		case "/reset":
+			session.Reset()
+			lastID = ""
+			suggestions = nil
+			fmt.Println("Conversation cleared")
+			continue
+		case "/history":
+			for i, turn := range session.Turns() {
+				fmt.Printf("%d. %s\n", i+1, turn.Query)
+			}
+			continue
+		}
+
+		ctx := search.WithRequestID(ctx, search.NewRequestID())
+		result, err := performSearchStreamWithProgress(ctx, query, session, &textEmitter{quiet: config.quiet}, nil)
+		if err != nil {
+			history.recordTurn(config.model, result, lastID)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		if config.includeSummary {
+			client.AddSummary(ctx, result)
+			fmt.Printf("\n## SUMMARY\n%s\n", answerText(result.Summary))
+		}
+		if config.suggest {
+			client.AddSuggestions(ctx, result)
+			suggestions = result.SuggestedQueries
+			printSuggestions(suggestions)
+		}
+		history.recordTurn(config.model, result, lastID)
+		lastID = result.ID
+		fmt.Println()
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+//go:embed templates/report.html
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"markdown": markdownHTML,
+	"duration": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"add": func(values ...int32) int32 {
+		var total int32
+		for _, v := range values {
+			total += v
+		}
+		return total
+	},
+}).Parse(reportTemplateText))
+
+// reportData is what templates/report.html renders
+type reportData struct {
+	Title       string
+	Generated   time.Time
+	Model       string
+	Multi       *MultiSearchResult
+	Results     []search.Result
+	Succeeded   int
+	ShowSources bool
+}
+
+// writeReport renders a multi-query run as a standalone HTML page at path
+func writeReport(path string, m *MultiSearchResult, model string) error {
+	page, err := renderReport(m, model)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, page, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved %s\n", path)
+	return nil
+}
+
+// renderReport returns the standalone HTML page for a multi-query run
+func renderReport(m *MultiSearchResult, model string) ([]byte, error) {
+	data := reportData{
+		Title:       fmt.Sprintf("Search report: %d queries", len(m.Results)),
+		Generated:   time.Now(),
+		Model:       model,
+		Multi:       m,
+		Results:     m.Results,
+		ShowSources: showSources,
+	}
+	for _, r := range m.Results {
+		if r.Success {
+			data.Succeeded++
+		}
+	}
+
+	var b bytes.Buffer
+	if err := reportTemplate.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdBullet   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdNumbered = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	mdCode     = regexp.MustCompile("`([^`]+)`")
+	mdBold     = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdItalic   = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*`)
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+)
+
+// markdownHTML converts the Markdown the model writes (headings, lists,
+// code, emphasis and links) to HTML. Everything else is escaped text.
+func markdownHTML(text string) template.HTML {
+	var b strings.Builder
+	var paragraph []string
+	list := ""
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			flushParagraph()
+			closeList()
+			if inCode {
+				b.WriteString("</code></pre>\n")
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			b.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flushParagraph()
+			closeList()
+		case mdHeading.MatchString(line):
+			flushParagraph()
+			closeList()
+			m := mdHeading.FindStringSubmatch(line)
+			level := len(m[1])
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, markdownInline(m[2]), level)
+		case mdBullet.MatchString(line):
+			flushParagraph()
+			openList("ul")
+			b.WriteString("<li>" + markdownInline(mdBullet.FindStringSubmatch(line)[1]) + "</li>\n")
+		case mdNumbered.MatchString(line):
+			flushParagraph()
+			openList("ol")
+			b.WriteString("<li>" + markdownInline(mdNumbered.FindStringSubmatch(line)[1]) + "</li>\n")
+		default:
+			closeList()
+			paragraph = append(paragraph, markdownInline(line))
+		}
+	}
+	flushParagraph()
+	closeList()
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+	return template.HTML(b.String())
+}
+
+// markdownInline escapes a line and converts its code spans, emphasis and
+// http(s) links
+func markdownInline(line string) string {
+	line = html.EscapeString(strings.TrimSpace(line))
+	line = mdCode.ReplaceAllString(line, "<code>$1</code>")
+	line = mdLink.ReplaceAllString(line, `<a href="$2" rel="noopener noreferrer">$1</a>`)
+	line = mdBold.ReplaceAllString(line, "<strong>$1</strong>")
+	line = mdItalic.ReplaceAllString(line, "$1<em>$2</em>")
+	return line
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// -auto-model modes: rate queries with a model call, or by wording alone
+const (
+	autoModelRated     = "rated"
+	autoModelHeuristic = "heuristic"
+)
+
+// parseAutoModel reads -auto-model, which is a boolean or heuristic
+func parseAutoModel(value string) (string, error) {
+	if value == autoModelHeuristic {
+		return autoModelHeuristic, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", fmt.Errorf("must be true, false or %s", autoModelHeuristic)
+	}
+	if enabled {
+		return autoModelRated, nil
+	}
+	return "", nil
+}
+
+// routeQueries picks -simple-model or -complex-model for each query. The
+// rating call goes to the simple model; if it fails, queries are rated by
+// their wording instead.
+func routeQueries(ctx context.Context, config *Config, client *search.Client, queries []string) []search.Routing {
+	var routes []search.Routing
+	if config.autoModel == autoModelRated {
+		rated, err := client.WithModel(config.simpleModel).RateComplexity(ctx, queries)
+		if err != nil {
+			slog.Info("Query routing failed, using heuristics", "error", err)
+		}
+		routes = rated
+	}
+	if routes == nil {
+		routes = make([]search.Routing, len(queries))
+		for i, query := range queries {
+			routes[i] = search.HeuristicComplexity(query)
+		}
+	}
+
+	for i := range routes {
+		routes[i].Model = config.simpleModel
+		if routes[i].Complexity == search.ComplexityComplex {
+			routes[i].Model = config.complexModel
+		}
+		slog.Info("Routed query", "query", queries[i], "complexity", routes[i].Complexity, "model", routes[i].Model, "method", routes[i].Method, "reason", routes[i].Reason)
+	}
+	return routes
+}
+
+// modelFor returns the model that answered r
+func modelFor(config *Config, r *search.Result) string {
+	if r != nil && r.Routing != nil {
+		return r.Routing.Model
+	}
+	return config.model
+}
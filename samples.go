@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Upper bound for -samples, each of which is a full grounded search
+const maxSamples = 10
+
+// runSamples answers query with -samples, reporting the choice on stderr
+// so stdout only carries the selected answer
+func runSamples(ctx context.Context, query string, config *Config, client *search.Client) (*search.Result, error) {
+	fmt.Fprintf(os.Stderr, "Sampling %d answers...\n", config.samples)
+	result, err := client.Samples(ctx, query, config.samples, config.workers)
+	if err != nil {
+		return result, err
+	}
+	succeeded := 0
+	for _, sample := range result.Samples {
+		if sample.Success {
+			succeeded++
+		}
+	}
+	if result.SelectedSample == 0 {
+		fmt.Fprintf(os.Stderr, "Merged %d of %d samples: %s\n\n", succeeded, config.samples, result.SelectionReason)
+	} else {
+		fmt.Fprintf(os.Stderr, "Selected sample %d of %d: %s\n\n", result.SelectedSample, config.samples, result.SelectionReason)
+	}
+	return result, nil
+}
@@ -2,281 +2,396 @@ package main
 
 import (
 	"context"
-	_ "embed"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os"
-	"sync"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
 	"time"
 
-	"google.golang.org/genai"
+	"github.com/qiushiyan/gemini-search/search"
 )
 
-//go:embed prompts/system.txt
-var systemInstructionText string
-
-//go:embed prompts/summary.txt
-var summaryInstructionText string
-
-var tools = []*genai.Tool{
-	{GoogleSearch: &genai.GoogleSearch{}},
-	{URLContext: &genai.URLContext{}},
-}
-
-var thinkingBudget int32 = 512
-var model = "gemini-2.5-flash"
-
-func getSystemInstruction() *genai.Content {
-	return &genai.Content{
-		Parts: []*genai.Part{{
-			Text: systemInstructionText,
-		}},
+func initializeClient(ctx context.Context, config *Config) (*search.Client, error) {
+	if len(config.headers) > 0 {
+		slog.Info("Using custom request headers", "headers", redactHeaders(config.headers))
 	}
-}
 
-func getSummaryInstruction() *genai.Content {
-	return &genai.Content{
-		Parts: []*genai.Part{{
-			Text: summaryInstructionText,
-		}},
-	}
-}
-
-func initializeClient(ctx context.Context) (*genai.Client, error) {
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{})
+	opts := search.DefaultOptions()
+	opts.Model = config.model
+	opts.InlineCitations = config.inlineCitations
+	opts.SummaryFallback = config.summaryFallback
+	opts.AutoRewrite = config.autoRewrite
+	opts.Language = config.lang
+	safety, err := search.SafetySettings(config.safety, config.safetyOverrides)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create client: %w", err)
-	}
-	return client, nil
-}
-
-func performSingleSearch(ctx context.Context, query string, client *genai.Client) (*SearchResult, error) {
-	startTime := time.Now()
-	result := &SearchResult{
-		Query:     query,
-		Timestamp: startTime,
+		return nil, err
+	}
+	opts.SafetySettings = safety
+	opts.Sites = config.sites
+	opts.Style = config.style
+	opts.MaxWords = config.maxWords
+	opts.MaxContinuations = config.maxContinuations
+	opts.Tools = config.tools
+	if config.enableLocalTools {
+		tools, err := config.localTools.searchTools(config.quiet)
+		if err != nil {
+			return nil, err
+		}
+		opts.LocalTools = tools
+	}
+	opts.Images = config.images
+	opts.Documents = config.documents
+	opts.Notes = config.notes
+	opts.NoteChunks = config.noteChunks
+	opts.ThinkingBudget = config.thinkingBudget
+	opts.IncludeThoughts = config.showThinking
+	opts.Retry.Attempts = config.maxRetries + 1
+	opts.Retry.BaseDelay = config.retryDelay
+	if config.retryBudget > 0 {
+		opts.Retry.Budget = search.NewRetryBudget(config.retryBudget)
+	}
+	opts.QueryTimeout = config.queryTimeout
+	opts.SystemPrompt = config.systemPrompt
+	opts.SummaryPrompt = config.summaryPrompt
+	opts.HTTPClient = newHTTPClient(config)
+	if config.useContextCache && !config.dryRun {
+		opts.ContextCacheTTL = config.contextCacheTTL
+	}
+	if promptLog != nil || auditLog != nil {
+		opts.OnRequest = func(ctx context.Context, req search.Request) {
+			promptLog.record(ctx, req)
+			auditLog.request(ctx, req)
+		}
 	}
-
-	isoDateString := time.Now().Format(time.DateOnly)
-	parts := []*genai.Part{
-		{Text: fmt.Sprintf(`
-<query>
-%s
-</query>
-
-Time Context: today is %s
-
-`, query, isoDateString)},
+	if auditLog != nil {
+		opts.OnResponse = auditLog.response
 	}
-	content := []*genai.Content{{
-		Role:  "user",
-		Parts: parts,
-	}}
-
-	slog.Info("Performing search", "query", query)
-
-	// Simple retry logic - try twice with 3 second delay
-	var response *genai.GenerateContentResponse
-	var err error
-	for attempt := 0; attempt < 2; attempt++ {
-		response, err = client.Models.GenerateContent(ctx, model, content, &genai.GenerateContentConfig{
-			SystemInstruction: getSystemInstruction(),
-			Tools:             tools,
-			ThinkingConfig: &genai.ThinkingConfig{
-				ThinkingBudget: &thinkingBudget,
-			},
-		})
-
-		if err == nil && response.Text() != "" {
-			break
+	if config.dryRun {
+		opts.Provider = dryRunProvider{name: config.provider}
+	} else if config.backend == backendVertex {
+		provider, err := search.NewVertexProvider(ctx, config.project, config.location, opts.HTTPClient)
+		if err != nil {
+			return nil, err
 		}
-
-		if attempt == 0 {
-			slog.Info("Retrying search request", "query", query, "attempt", attempt+2)
-			time.Sleep(3 * time.Second)
+		opts.Provider = provider
+	} else if config.provider != search.ProviderGemini {
+		provider, err := search.NewProvider(ctx, config.provider, opts.HTTPClient)
+		if err != nil {
+			return nil, err
 		}
+		opts.Provider = provider
 	}
-
-	result.Duration = time.Since(startTime)
-
-	if err != nil {
-		result.Error = "Search failed"
-		result.Success = false
-		return result, fmt.Errorf("failed to generate content after retries: %w", err)
-	}
-
-	if response.Text() == "" {
-		result.Error = "Empty response"
-		result.Success = false
-		return result, fmt.Errorf("received empty response after retries")
+	// Watch runs must reach the API to notice a changed answer
+	if !config.noCache && config.watch == 0 {
+		dir, err := search.DefaultCacheDir()
+		if err != nil {
+			slog.Info("Response cache disabled", "error", err)
+		} else {
+			opts.Cache = search.NewDiskCache(dir, config.cacheTTL)
+			opts.NoteCacheDir = filepath.Join(dir, "notes")
+		}
 	}
 
-	result.Response = response.Text()
-	result.Success = true
-	return result, nil
+	return search.NewClient(ctx, opts)
 }
 
-func performSingleSearchStream(ctx context.Context, query string, client *genai.Client) (*SearchResult, error) {
-	startTime := time.Now()
-	result := &SearchResult{
-		Query:     query,
-		Timestamp: startTime,
-	}
-
-	isoDateString := time.Now().Format(time.DateOnly)
-	parts := []*genai.Part{
-		{Text: fmt.Sprintf(`
-<query>
-%s
-</query>
-
-Time Context: today is %s
-
-`, query, isoDateString)},
-	}
-	content := []*genai.Content{{
-		Role:  "user",
-		Parts: parts,
-	}}
-
-	slog.Info("Performing search", "query", query)
+// performSingleSearchStream streams a search through out
+func performSingleSearchStream(ctx context.Context, query string, client streamer, out streamEmitter) (*search.Result, error) {
+	return performSearchStreamWithProgress(ctx, query, client, out, nil)
+}
 
-	fmt.Printf("\n=== %s ===\n", query)
+// performSearchStreamWithProgress streams a search through out, calling
+// onText (if set) with the accumulated response text after every chunk
+func performSearchStreamWithProgress(ctx context.Context, query string, client streamer, out streamEmitter, onText func(string)) (*search.Result, error) {
+	out.begin(query, search.RequestID(ctx))
 
 	var responseText string
-	var lastErr error
-
-	// Simple retry logic for streaming - try twice with 3 second delay
-	for attempt := 0; attempt < 2; attempt++ {
-		responseText = ""
-
-		iterator := client.Models.GenerateContentStream(ctx, model, content, &genai.GenerateContentConfig{
-			SystemInstruction: getSystemInstruction(),
-			Tools:             tools,
-			ThinkingConfig: &genai.ThinkingConfig{
-				ThinkingBudget: &thinkingBudget,
-			},
-		})
-
-		streamSuccess := true
-		for response, err := range iterator {
-			if err != nil {
-				lastErr = err
-				streamSuccess = false
-				break
-			}
-
-			if len(response.Candidates) > 0 {
-				chunk := response.Text()
-				fmt.Print(chunk)
-				responseText += chunk
+	result, err := client.SearchStream(ctx, query, func(event search.StreamEvent) {
+		out.event(event)
+		switch event.Type {
+		case search.EventChunk:
+			responseText += event.Text
+			if onText != nil {
+				onText(responseText)
 			}
+		case search.EventRetry:
+			responseText = ""
 		}
+	})
+	out.streamed(result)
+	return result, err
+}
 
-		if streamSuccess && responseText != "" {
-			break
-		}
+// Number of streamed characters after which an early summary is started
+const earlySummaryThreshold = 2000
 
-		if attempt == 0 {
-			slog.Info("Retrying stream search request", "query", query, "attempt", attempt+2)
-			fmt.Printf("\n[Retrying...]\n")
-			time.Sleep(3 * time.Second)
-		}
+// performSingleSearchStreamWithSummary streams a search through searcher and
+// then adds a summary, which out shows after the stream. In "after" mode the summary is
+// generated from the full response once the stream closes. In "early" mode
+// generation starts in the background as soon as earlySummaryThreshold
+// characters have arrived, so it is based on a partial response but is
+// usually ready when the stream ends.
+func performSingleSearchStreamWithSummary(ctx context.Context, query string, client *search.Client, searcher streamer, mode string, out streamEmitter) (*search.Result, error) {
+	type summaryOutcome struct {
+		summary *search.Summary
+		err     error
 	}
 
-	fmt.Printf("\n%s\n", "─────────────────────────────────────────────────────────────────────────────")
+	if search.RequestID(ctx) == "" {
+		ctx = search.WithRequestID(ctx, search.NewRequestID())
+	}
 
-	result.Duration = time.Since(startTime)
+	var early chan summaryOutcome
+	var summaryStart time.Time
+	var onText func(string)
+	if mode == streamSummaryEarly {
+		onText = func(text string) {
+			if early != nil || len(text) < earlySummaryThreshold {
+				return
+			}
+			early = make(chan summaryOutcome, 1)
+			summaryStart = time.Now()
+			go func(partial string) {
+				summary, err := client.Summarize(ctx, query, partial)
+				early <- summaryOutcome{summary, err}
+			}(text)
+		}
+	}
 
-	if lastErr != nil && responseText == "" {
-		result.Error = "Stream search failed"
-		result.Success = false
-		return result, fmt.Errorf("failed to stream content after retries: %w", lastErr)
+	result, err := performSearchStreamWithProgress(ctx, query, searcher, out, onText)
+	if err != nil || !result.Success {
+		return result, err
 	}
 
-	if responseText == "" {
-		result.Error = "Empty stream response"
-		result.Success = false
-		return result, fmt.Errorf("received empty stream response after retries")
+	stop := startSpinner("Generating summary...")
+	var outcome summaryOutcome
+	if early != nil {
+		outcome = <-early
+	} else {
+		summaryStart = time.Now()
+		outcome.summary, outcome.err = client.Summarize(ctx, query, result.Response)
 	}
+	stop()
+	result.Timings.Summary = time.Since(summaryStart)
+	result.SetSummary(outcome.summary, outcome.err)
 
-	result.Response = responseText
-	result.Success = true
+	out.summary(result)
 	return result, nil
 }
 
-func generateSummary(ctx context.Context, query, response string, client *genai.Client) (string, error) {
-	parts := []*genai.Part{
-		{Text: fmt.Sprintf("Query: %s\n\nSearch Results:\n%s", query, response)},
-	}
-	content := []*genai.Content{{
-		Role:  "user",
-		Parts: parts,
-	}}
-
-	// Simple retry logic for summary
-	var result *genai.GenerateContentResponse
-	var err error
-	for attempt := 0; attempt < 2; attempt++ {
-		result, err = client.Models.GenerateContent(ctx, model, content, &genai.GenerateContentConfig{
-			SystemInstruction: getSummaryInstruction(),
-			ThinkingConfig: &genai.ThinkingConfig{
-				ThinkingBudget: &thinkingBudget,
-			},
-		})
-
-		if err == nil && result.Text() != "" {
-			break
-		}
-
-		if attempt == 0 {
-			time.Sleep(3 * time.Second)
+// startClassification runs Classify in the background so it overlaps with
+// the searches. The returned function blocks until it finishes and returns
+// nil if classification failed.
+func startClassification(ctx context.Context, queries []string, client *search.Client) func() []search.Classification {
+	done := make(chan []search.Classification, 1)
+	go func() {
+		classifications, err := client.Classify(ctx, queries)
+		if err != nil {
+			slog.Info("Query classification failed", "error", err)
 		}
-	}
-
-	if err != nil {
-		return "", fmt.Errorf("failed to generate summary after retries: %w", err)
-	}
-
-	if result.Text() == "" {
-		return "", fmt.Errorf("received empty summary after retries")
-	}
+		done <- classifications
+	}()
+	return func() []search.Classification { return <-done }
+}
 
-	return result.Text(), nil
+// queryEvent reports a query of a batch starting or finishing, for live
+// progress displays
+type queryEvent struct {
+	Index int
+	// Nil when the query has just started
+	Result *search.Result
 }
 
-func processMultipleQueries(ctx context.Context, queries []string, config *Config, client *genai.Client) (*MultiSearchResult, error) {
+// processMultipleQueries runs queries concurrently. If events is non-nil it
+// receives a start and a finish event per query; it must be buffered for
+// 2*len(queries) so workers never block on it.
+func processMultipleQueries(ctx context.Context, queries []string, config *Config, client *search.Client, events chan<- queryEvent) (*MultiSearchResult, error) {
 	startTime := time.Now()
 
+	// Duplicates share one search; owners maps each distinct query back to
+	// the positions it was given at
+	all := queries
+	queries, owners := dedupeQueries(all, config.dedupe)
+	if len(queries) < len(all) {
+		slog.Info("Deduplicated queries", "queries", len(all), "distinct", len(queries))
+	}
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(ctx, config.timeout)
 	defer cancel()
 
-	results := make([]SearchResult, len(queries))
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, config.workers) // Simple semaphore for concurrency control
+	// With -fail-fast the first failure stops new queries from starting;
+	// -continue-on-error=false also cancels the ones in flight
+	var failed atomic.Bool
+	runCtx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	stop := func(result search.Result) {
+		if result.Success || !config.failFast || !failed.CompareAndSwap(false, true) {
+			return
+		}
+		slog.Info("Stopping batch after failed query", "query", result.Query, "error", result.Error)
+		if !config.continueOnError {
+			abort(errSkipped)
+		}
+	}
+
+	var waitClassification func() []search.Classification
+	if config.classify {
+		waitClassification = startClassification(ctx, queries, client)
+	}
 
-	for i, query := range queries {
-		wg.Add(1)
-		go func(index int, q string) {
-			defer wg.Done()
-			sem <- struct{}{}        // Acquire semaphore
-			defer func() { <-sem }() // Release semaphore
+	var routes []search.Routing
+	if config.autoModel != "" {
+		routes = routeQueries(ctx, config, client, queries)
+	}
 
-			result := processQuery(ctx, q, client, config.includeSummary)
-			results[index] = result
+	// Queries answered from history within -freshness, which are not
+	// recorded again
+	reused := make([]bool, len(queries))
 
+	var limiter *rateLimiter
+	if config.rpm > 0 {
+		limiter = newRateLimiter(config.rpm)
+	}
+
+	// Summaries and structured output run in their own pool, so a worker
+	// moves on to the next search as soon as its current one returns
+	var post *stage[search.Result]
+	if config.includeSummary || config.schema != nil || config.verify || config.confidence || config.suggest {
+		post = newStage[search.Result](config.summaryWorkers)
+	}
+	// emit sends the event for each position of a query; a nil result
+	// marks its start
+	emit := func(index int, result *search.Result) {
+		if events == nil {
+			return
+		}
+		for _, i := range owners[index] {
+			event := queryEvent{Index: i}
+			if result != nil {
+				duplicate := *result
+				duplicate.Query = all[i]
+				event.Result = &duplicate
+			}
+			events <- event
+		}
+	}
+	// Units still running when the batch gives up on them must not report
+	// or record anything after it returns
+	var late lateGuard
+	finish := func(index int, result search.Result) {
+		late.do(func() {
+			emit(index, &result)
+			config.checkpoint.record(result)
 			if config.verbose {
 				slog.Info("Query completed", "query", result.Query, "success", result.Success, "duration", result.Duration)
 			}
-		}(i, query)
+			runPostHook(ctx, config, &result)
+		})
+	}
+
+	results := runConcurrently(runCtx, len(queries), config.workers, config.timeoutGrace, config.priorityOrder, func(ctx context.Context, index int) search.Result {
+		if failed.Load() {
+			return skippedResult(queries[index])
+		}
+		if restored, ok := config.checkpoint.lookup(queries[index]); ok {
+			slog.Info("Restored query from checkpoint", "query", queries[index])
+			emit(index, nil)
+			emit(index, &restored)
+			return restored
+		}
+		// !nocache queries skip reused answers as well as the response cache.
+		// A duplicate carrying the directive refreshes the search it shares.
+		noCache := slices.ContainsFunc(owners[index], func(i int) bool { return config.noCacheQueries[all[i]] })
+		var fresh *search.Result
+		var staleAt time.Time
+		if !noCache {
+			fresh, staleAt = freshAnswer(config, queries[index])
+		}
+		if fresh != nil {
+			noteFreshness(queries[index], fresh, staleAt, true)
+			late.do(func() { reused[index] = true })
+			emit(index, nil)
+			finish(index, *fresh)
+			return *fresh
+		}
+		// Throttled queries wait for their turn rather than fail
+		waited, err := limiter.wait(ctx)
+		if err != nil {
+			return cancelledResult(ctx, queries[index], false)
+		}
+		if waited > 0 {
+			slog.Info("Waited for rate limit", "query", queries[index], "wait", waited.Round(time.Millisecond))
+		}
+		emit(index, nil)
+
+		queryClient := client
+		if routes != nil {
+			queryClient = client.WithModel(routes[index].Model)
+		}
+		if noCache {
+			queryClient = queryClient.WithCacheRefresh()
+		}
+		result := processQuery(ctx, queries[index], queryClient)
+		if routes != nil {
+			result.Routing = &routes[index]
+		}
+		if result.Success {
+			noteFreshness(queries[index], nil, staleAt, true)
+		}
+		stop(result)
+		if post == nil || !result.Success {
+			finish(index, result)
+			return result
+		}
+		post.submit(ctx, index, func(ctx context.Context) search.Result {
+			processed := result
+			postProcess(ctx, &processed, client, config)
+			finish(index, processed)
+			return processed
+		})
+		return result
+	}, func(index int, started bool) search.Result {
+		return cancelledResult(runCtx, queries[index], started)
+	})
+	if post != nil {
+		for index, result := range post.wait(runCtx, config.timeoutGrace) {
+			results[index] = result
+		}
 	}
+	late.close()
+	config.checkpoint.flush()
 
-	wg.Wait()
+	if waitClassification != nil {
+		for i, c := range waitClassification() {
+			results[i].SetClassification(c)
+		}
+	}
+	for i := range results {
+		if !reused[i] {
+			history.record(modelFor(config, &results[i]), &results[i])
+		}
+	}
 	totalTime := time.Since(startTime)
 
-	// Calculate success count
+	// Phase and token totals count each search once
+	var timings BatchTimings
+	for _, result := range results {
+		timings.Construction += result.Timings.Construction
+		timings.Generation += result.Timings.Generation
+		timings.Summary += result.Timings.Summary
+	}
+	unique := results
+	results = make([]search.Result, len(all))
+	for u, indexes := range owners {
+		for _, i := range indexes {
+			results[i] = unique[u]
+			results[i].Query = all[i]
+			results[i].Tags = config.tagsFor(all[i])
+		}
+	}
 	successCount := 0
 	for _, result := range results {
 		if result.Success {
@@ -286,142 +401,119 @@ func processMultipleQueries(ctx context.Context, queries []string, config *Confi
 
 	if config.verbose {
 		slog.Info("Query execution completed",
-			"total_queries", len(queries),
+			"total_queries", len(all),
 			"successful", successCount,
-			"total_duration", totalTime.Round(time.Millisecond))
+			"total_duration", totalTime.Round(time.Millisecond),
+			"search_time", timings.Generation.Round(time.Millisecond),
+			"summary_time", timings.Summary.Round(time.Millisecond))
 	}
 
 	multiResult := &MultiSearchResult{
 		Results:   results,
 		TotalTime: totalTime,
-		Success:   successCount == len(queries),
+		Timings:   timings,
+		Success:   successCount == len(all),
+		// Only a signal cancels the parent; the batch timeout is DeadlineExceeded
+		Interrupted: errors.Is(ctx.Err(), context.Canceled),
 	}
+	for _, result := range unique {
+		multiResult.PromptTokens += result.PromptTokens
+		multiResult.OutputTokens += result.OutputTokens
+		multiResult.ThinkingTokens += result.ThinkingTokens
+		multiResult.CostUSD += result.CostUSD
+	}
+
+	multiResult.Stats = batchStats(multiResult)
 
 	if !multiResult.Success {
-		multiResult.Error = fmt.Sprintf("Completed %d/%d queries successfully", successCount, len(queries))
+		multiResult.Error = fmt.Sprintf("Completed %d/%d queries successfully", successCount, len(all))
+		multiResult.ErrorCode = batchCode(results)
 	}
 
 	return multiResult, nil
 }
 
-func processQuery(ctx context.Context, query string, client *genai.Client, includeSummary bool) SearchResult {
-	startTime := time.Now()
+// errSkipped is the cause a batch is cancelled with when a query fails
+// under -continue-on-error=false
+var errSkipped = errors.New("skipped after an earlier query failed")
 
-	result := SearchResult{
-		Query:     query,
-		Timestamp: startTime,
-	}
+const skippedMessage = "Skipped after an earlier query failed"
 
-	// Perform regular search (no streaming for multi-query)
-	searchResult, err := performSingleSearch(ctx, query, client)
-	if err != nil {
-		result.Success = false
-		result.Error = err.Error()
-		result.Duration = time.Since(startTime)
-		return result
+// skippedResult stands in for a query -fail-fast kept from starting
+func skippedResult(query string) search.Result {
+	slog.Info("Skipping query after earlier failure", "query", query)
+	return search.Result{
+		ID:        search.NewRequestID(),
+		Query:     query,
+		Timestamp: time.Now(),
+		Success:   false,
+		Error:     skippedMessage,
+		ErrorCode: codeSkipped,
 	}
+}
 
-	result.Response = searchResult.Response
-	result.Success = searchResult.Success
-	result.Duration = searchResult.Duration
-
-	// Generate summary if requested
-	if result.Success && includeSummary {
-		summary, err := generateSummary(ctx, query, result.Response, client)
-		if err != nil {
-			result.Summary = "Summary generation failed"
-		} else {
-			result.Summary = summary
-		}
+// cancelledResult stands in for a query that was skipped because ctx ended
+// before it started, or abandoned because it outlived the timeout grace
+func cancelledResult(ctx context.Context, query string, started bool) search.Result {
+	if errors.Is(context.Cause(ctx), errSkipped) {
+		return skippedResult(query)
 	}
-
+	result := search.Result{
+		ID:        search.NewRequestID(),
+		Query:     query,
+		Timestamp: time.Now(),
+		Success:   false,
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		slog.Info("Skipping query after interrupt", "query", query, "started", started)
+		result.Error = "Interrupted"
+	} else if started {
+		slog.Info("Abandoning query after timeout grace", "query", query, "reason", ctx.Err())
+		result.Error = fmt.Sprintf("Abandoned after timeout grace: %v", ctx.Err())
+	} else {
+		slog.Info("Skipping query, context already done", "query", query, "reason", ctx.Err())
+		result.Error = fmt.Sprintf("Cancelled before start: %v", ctx.Err())
+	}
+	result.ErrorCode = search.Code(ctx.Err())
 	return result
 }
 
-func (r *SearchResult) Output(outputJSON bool) error {
-	if outputJSON {
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(r)
-	}
-
-	if !r.Success {
-		fmt.Fprintf(os.Stderr, "Search failed: %s\n", r.Error)
-		return fmt.Errorf("search failed")
-	}
+func processQuery(ctx context.Context, query string, client *search.Client) search.Result {
+	startTime := time.Now()
 
-	// Show summary first if available
-	if r.Summary != "" {
-		fmt.Printf("## SUMMARY\n%s\n\n", r.Summary)
-		fmt.Printf("## DETAILED RESPONSE\n")
+	// Perform regular search (no streaming for multi-query)
+	result, err := client.Search(ctx, query)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		if errors.Is(context.Cause(ctx), errSkipped) {
+			result.Error = skippedMessage
+			result.ErrorCode = codeSkipped
+		} else if errors.Is(ctx.Err(), context.Canceled) {
+			result.Error = "Interrupted"
+			result.ErrorCode = search.CodeInterrupted
+		}
+		result.Duration = time.Since(startTime)
 	}
-	
-	fmt.Println(r.Response)
-	return nil
+	return *result
 }
 
-func (m *MultiSearchResult) Output(outputJSON bool, isStream bool, includeSummary bool) error {
-	if outputJSON {
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(m)
+// postProcess adds the summary, structured output and verification
+// requested for a successful search
+func postProcess(ctx context.Context, result *search.Result, client *search.Client, config *Config) {
+	if config.includeSummary {
+		client.AddSummary(ctx, result)
 	}
-
-	if isStream {
-		// In stream mode, results already shown, just show completion
-		successful := 0
-		for _, result := range m.Results {
-			if result.Success {
-				successful++
-			}
-		}
-		fmt.Printf("\n🏁 COMPLETED: %d/%d queries\n", successful, len(m.Results))
-		return nil
+	if config.schema != nil {
+		client.AddStructured(ctx, result, config.schema)
 	}
-
-	// Calculate success/failure counts
-	successful := 0
-	failed := 0
-	for _, result := range m.Results {
-		if result.Success {
-			successful++
-		} else {
-			failed++
-		}
+	if config.confidence {
+		client.AddConfidence(ctx, result)
 	}
-
-	if includeSummary {
-		// Combined overview and summaries section
-		fmt.Printf("## SEARCH RESULTS\n")
-		fmt.Printf("%d/%d queries completed successfully, here is a summary for each query:\n\n", successful, len(m.Results))
-
-		for _, result := range m.Results {
-			if result.Success {
-				summary := result.Summary
-				if summary == "" {
-					summary = "No summary available"
-				}
-				fmt.Printf("✓ %s: %s\n", result.Query, summary)
-			} else {
-				fmt.Printf("✗ %s: %s\n", result.Query, result.Error)
-			}
-		}
-		fmt.Printf("\n")
-
-		// Detailed responses section (without durations)
-		fmt.Printf("## DETAILED RESPONSES\n\n")
+	if config.verify {
+		client.AddVerification(ctx, result, config.workers)
 	}
-	for _, result := range m.Results {
-		if len(m.Results) > 1 {
-			fmt.Printf("=== %s ===\n", result.Query)
-		}
-		if result.Success {
-			fmt.Printf("%s\n", result.Response)
-		} else {
-			fmt.Printf("Status: FAILED - %s\n", result.Error)
-		}
-		fmt.Printf("\n")
+	if config.suggest {
+		client.AddSuggestions(ctx, result)
 	}
-
-	return nil
 }
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Upper bound on response length, required by the Messages API
+const anthropicMaxTokens = 8192
+
+// AnthropicProvider calls the Anthropic Messages API, using its web search
+// tool for grounded searches
+type AnthropicProvider struct {
+	httpClient *http.Client
+	apiKey     string
+	baseURL    string
+}
+
+// NewAnthropicProvider reads ANTHROPIC_API_KEY and, optionally,
+// ANTHROPIC_BASE_URL
+func NewAnthropicProvider(httpClient *http.Client) (*AnthropicProvider, error) {
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: set ANTHROPIC_API_KEY", ErrNoAPIKey)
+	}
+	baseURL := os.Getenv("ANTHROPIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	return &AnthropicProvider{httpClient: httpClient, apiKey: apiKey, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (p *AnthropicProvider) Name() string { return ProviderAnthropic }
+
+type anthropicRequest struct {
+	Model     string           `json:"model"`
+	MaxTokens int              `json:"max_tokens"`
+	System    string           `json:"system,omitempty"`
+	Messages  []chatMessage    `json:"messages"`
+	Tools     []map[string]any `json:"tools,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type      string `json:"type"`
+		Text      string `json:"text"`
+		Citations []struct {
+			URL   string `json:"url"`
+			Title string `json:"title"`
+		} `json:"citations"`
+		// Results of a web_search_tool_result block
+		Content json.RawMessage `json:"content"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int32 `json:"input_tokens"`
+		OutputTokens int32 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type anthropicSearchResult struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+func (p *AnthropicProvider) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	req := anthropicRequest{
+		Model:     model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  chatMessages(contents),
+	}
+	if config != nil {
+		req.System = contentText(config.SystemInstruction)
+		if config.MaxOutputTokens > 0 {
+			req.MaxTokens = int(config.MaxOutputTokens)
+		}
+	}
+	if usesSearch(config) {
+		req.Tools = []map[string]any{{"type": "web_search_20250305", "name": "web_search", "max_uses": 5}}
+	}
+	// The Messages API has no JSON mode, so the schema goes in the prompt
+	if schema := responseJSONSchema(config); schema != nil {
+		encoded, _ := json.Marshal(schema)
+		req.System += "\n\nRespond with only a JSON value, no prose or code fences, matching this JSON Schema:\n" + string(encoded)
+	}
+
+	var resp anthropicResponse
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": "2023-06-01",
+	}
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/v1/messages", headers, req, &resp); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	var sources []Source
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+			for _, citation := range block.Citations {
+				if citation.URL != "" {
+					sources = addSource(sources, citation.URL, citation.Title)
+				}
+			}
+		case "web_search_tool_result":
+			var results []anthropicSearchResult
+			if err := json.Unmarshal(block.Content, &results); err == nil {
+				for _, result := range results {
+					if result.Type == "web_search_result" && result.URL != "" {
+						sources = addSource(sources, result.URL, result.Title)
+					}
+				}
+			}
+		}
+	}
+
+	return textResponse(text.String(), sources, resp.Usage.InputTokens, resp.Usage.OutputTokens, 0), nil
+}
+
+// GenerateContentStream returns the whole response as a single chunk
+func (p *AnthropicProvider) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return singleResponseStream(func() (*genai.GenerateContentResponse, error) {
+		return p.GenerateContent(ctx, model, contents, config)
+	})
+}
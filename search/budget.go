@@ -0,0 +1,53 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// ErrRetryBudgetExhausted is wrapped by errors from calls that needed a
+// retry after the shared RetryBudget ran out, and from every call made after
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryBudget caps the retries of every call sharing it, so a systemic
+// outage costs a fixed number of extra requests instead of a few per
+// query. Once a retry is refused the budget stays exhausted and later calls
+// fail without reaching the API.
+type RetryBudget struct {
+	limit     int64
+	used      atomic.Int64
+	exhausted atomic.Bool
+}
+
+// NewRetryBudget allows limit retries in total
+func NewRetryBudget(limit int) *RetryBudget {
+	return &RetryBudget{limit: int64(limit)}
+}
+
+// take claims one retry, reporting false once the budget is used up. A nil
+// budget never runs out.
+func (b *RetryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	if b.exhausted.Load() {
+		return false
+	}
+	if b.used.Add(1) > b.limit {
+		if !b.exhausted.Swap(true) {
+			slog.Info("Retry budget exhausted, failing remaining calls", "retries", b.limit)
+		}
+		return false
+	}
+	return true
+}
+
+// check fails once the budget is exhausted
+func (b *RetryBudget) check() error {
+	if b != nil && b.exhausted.Load() {
+		return fmt.Errorf("%w (%d retries used)", ErrRetryBudgetExhausted, b.limit)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache stores successful search results between runs
+type Cache interface {
+	Get(key string) (*Result, bool)
+	Put(key string, result *Result) error
+}
+
+// DiskCache keeps one JSON file per cached result in a directory
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+type cacheEntry struct {
+	StoredAt time.Time `json:"stored_at"`
+	Result   *Result   `json:"result"`
+}
+
+// DefaultCacheDir returns the go-search directory under the user cache dir
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return filepath.Join(dir, "go-search"), nil
+}
+
+// NewDiskCache returns a cache in dir whose entries expire after ttl
+func NewDiskCache(dir string, ttl time.Duration) *DiskCache {
+	return &DiskCache{dir: dir, ttl: ttl}
+}
+
+func (d *DiskCache) path(key string) string {
+	return filepath.Join(d.dir, key+".json")
+}
+
+// Get returns the cached result for key if it exists and has not expired
+func (d *DiskCache) Get(key string) (*Result, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Result == nil {
+		return nil, false
+	}
+	if time.Since(entry.StoredAt) > d.ttl {
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// Put stores result under key, replacing any earlier entry
+func (d *DiskCache) Put(key string, result *Result) error {
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.Marshal(cacheEntry{StoredAt: time.Now(), Result: result})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	// Write to a temp file first so concurrent readers never see a partial entry
+	tmp, err := os.CreateTemp(d.dir, "entry-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every cached entry and returns how many were removed
+func (d *DiskCache) Clear() (int, error) {
+	entries, err := os.ReadDir(d.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".tmp")) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(d.dir, name)); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// cacheKey covers every option that changes the search response
+func (c *Client) cacheKey(query string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%t\x00%t\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%d\x00%s\x00%s", c.provider.Name(), c.opts.Model, c.opts.ThinkingBudget, c.opts.InlineCitations, c.opts.IncludeThoughts, c.opts.SystemPrompt, c.opts.Language, c.safetyKey(), c.opts.Sites.key(), c.toolsKey(), c.imagesKey(), c.documentsKey(), c.opts.Style, c.opts.MaxWords, c.notesKey, query)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// caching reports whether results are read from and stored in the cache.
+// Answers built with local tools depend on live command output, so they
+// are never cached.
+func (c *Client) caching() bool {
+	return c.opts.Cache != nil && len(c.opts.LocalTools) == 0
+}
+
+// cached returns a copy of the cached result for query, stamped as a new
+// request with id
+func (c *Client) cached(query, id string) (*Result, bool) {
+	if !c.caching() || c.refreshCache {
+		return nil, false
+	}
+	hit, ok := c.opts.Cache.Get(c.cacheKey(query))
+	if !ok {
+		return nil, false
+	}
+	result := *hit
+	result.ID = id
+	result.Timestamp = time.Now()
+	result.Duration = 0
+	result.Timings = Timings{}
+	// Served without an API call
+	result.CostUSD = 0
+	result.Cached = true
+	return &result, true
+}
+
+func (c *Client) storeCached(query string, result *Result) {
+	if !c.caching() {
+		return
+	}
+	if err := c.opts.Cache.Put(c.cacheKey(query), result); err != nil {
+		slog.Info("Failed to cache search result", "query", query, "error", err)
+	}
+}
@@ -0,0 +1,96 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed prompts/citations.txt
+var citationInstructionText string
+
+var (
+	citationMarkerPattern = regexp.MustCompile(`\[(\d+)\]`)
+	citationSourcePattern = regexp.MustCompile(`^\s*\[(\d+)\]\s+(.+)$`)
+)
+
+// splitSources separates the prose from a trailing "Sources:" section
+func splitSources(text string) (string, string) {
+	idx := strings.LastIndex(text, "Sources:")
+	if idx == -1 {
+		return text, ""
+	}
+	return text[:idx], text[idx+len("Sources:"):]
+}
+
+// verifyCitations checks the footnote markers in text against the listed
+// sources and the grounding sources returned by the model. It returns any
+// text that should be appended to the response (a generated sources list when
+// the model omitted one) and a list of human-readable warnings.
+func verifyCitations(text string, grounded []Source) (string, []string) {
+	var warnings []string
+
+	prose, sourcesSection := splitSources(text)
+
+	markers := map[int]bool{}
+	for _, match := range citationMarkerPattern.FindAllStringSubmatch(prose, -1) {
+		n, _ := strconv.Atoi(match[1])
+		markers[n] = true
+	}
+
+	listed := map[int]bool{}
+	for _, line := range strings.Split(sourcesSection, "\n") {
+		if match := citationSourcePattern.FindStringSubmatch(line); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			listed[n] = true
+		}
+	}
+
+	if len(markers) > 0 && len(grounded) == 0 {
+		warnings = append(warnings, "response contains citation markers but no grounding sources were returned")
+	}
+
+	// Model omitted the sources list, build one from grounding metadata
+	if len(listed) == 0 {
+		if len(grounded) == 0 {
+			return "", warnings
+		}
+		var b strings.Builder
+		b.WriteString("\n\nSources:\n")
+		for i, source := range grounded {
+			fmt.Fprintf(&b, "[%d] %s - %s\n", i+1, source.Title, source.URI)
+		}
+		if len(markers) > 0 {
+			warnings = append(warnings, "model did not list its sources; sources list was generated from grounding metadata and may not match the markers")
+		}
+		return b.String(), warnings
+	}
+
+	for _, n := range sortedKeys(markers) {
+		if !listed[n] {
+			warnings = append(warnings, fmt.Sprintf("citation [%d] has no matching source", n))
+		}
+	}
+	for _, n := range sortedKeys(listed) {
+		if !markers[n] {
+			warnings = append(warnings, fmt.Sprintf("source [%d] is never cited", n))
+		}
+	}
+	if len(grounded) > 0 && len(listed) > len(grounded) {
+		warnings = append(warnings, fmt.Sprintf("%d sources listed but only %d grounding sources were returned", len(listed), len(grounded)))
+	}
+
+	return "", warnings
+}
+
+func sortedKeys(m map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
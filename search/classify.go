@@ -0,0 +1,99 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/classify.txt
+var classifyInstructionText string
+
+var queryCategories = []string{"factual", "opinion", "coding", "news", "comparison", "how-to", "other"}
+
+// Classification is a query's intent category with the model's confidence
+type Classification struct {
+	Index      int     `json:"index"`
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+}
+
+var classificationSchema = &genai.Schema{
+	Type: genai.TypeArray,
+	Items: &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"index":      {Type: genai.TypeInteger},
+			"category":   {Type: genai.TypeString, Enum: queryCategories},
+			"confidence": {Type: genai.TypeNumber},
+		},
+		Required: []string{"index", "category", "confidence"},
+	},
+}
+
+// Classify labels all queries with a single model call, with thinking
+// disabled to keep it cheap. The returned slice is indexed like queries;
+// queries the model skipped are left with an empty category.
+func (c *Client) Classify(ctx context.Context, queries []string) ([]Classification, error) {
+	var b strings.Builder
+	for i, query := range queries {
+		fmt.Fprintf(&b, "%d. %s\n", i, query)
+	}
+	content := []*genai.Content{{
+		Role:  "user",
+		Parts: []*genai.Part{{Text: b.String()}},
+	}}
+
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: classifyInstructionText}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    classificationSchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	var response *genai.GenerateContentResponse
+	err := c.opts.Retry.do(ctx, func(attempt int) error {
+		c.logRequest(ctx, "classify", attempt, content, genConfig)
+
+		var err error
+		response, err = c.provider.GenerateContent(ctx, c.opts.Model, content, genConfig)
+		c.logResponse(ctx, "classify", attempt, response, err)
+		if err != nil {
+			return err
+		}
+		if response.Text() == "" {
+			return errEmptyResponse
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify queries: %w", err)
+	}
+
+	var labels []Classification
+	if err := json.Unmarshal([]byte(response.Text()), &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse classification: %w", err)
+	}
+
+	classifications := make([]Classification, len(queries))
+	for _, label := range labels {
+		if label.Index >= 0 && label.Index < len(queries) {
+			classifications[label.Index] = label
+		}
+	}
+	return classifications, nil
+}
+
+// SetClassification records a query's category on the result
+func (r *Result) SetClassification(c Classification) {
+	r.Category = c.Category
+	r.CategoryConfidence = c.Confidence
+}
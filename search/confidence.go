@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/confidence.txt
+var confidenceInstructionText string
+
+// LowConfidence is the confidence below which an answer is flagged
+const LowConfidence = 0.5
+
+var confidenceSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"confidence": {Type: genai.TypeNumber},
+		"caveats":    {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+	},
+	Required: []string{"confidence", "caveats"},
+}
+
+// LowConfidence reports whether the answer was rated below LowConfidence
+func (r *Result) LowConfidence() bool {
+	return r.Confidence != nil && *r.Confidence < LowConfidence
+}
+
+// AddConfidence rates a successful result: the model scores how far the
+// answer and its sources can be trusted and lists its uncertain points in
+// Confidence and Caveats. Grounded search cannot be combined with
+// structured output, so this is a second call. A failed rating is logged
+// and leaves both unset.
+func (c *Client) AddConfidence(ctx context.Context, r *Result) {
+	if !r.Success {
+		return
+	}
+	ctx = WithRequestID(ctx, r.ID)
+
+	var sources strings.Builder
+	for _, source := range r.Sources {
+		fmt.Fprintf(&sources, "- %s - %s\n", source.Title, source.URI)
+	}
+	if sources.Len() == 0 {
+		sources.WriteString("None\n")
+	}
+	content := []*genai.Content{{
+		Role: "user",
+		Parts: []*genai.Part{{Text: fmt.Sprintf("Query: %s\n\nSources:\n%s\nSearch Answer:\n%s",
+			r.Query, sources.String(), r.Response)}},
+	}}
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: c.withLanguage(confidenceInstructionText)}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    confidenceSchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	response, err := c.generate(ctx, "confidence", content, genConfig)
+	if err != nil {
+		slog.InfoContext(ctx, "Confidence rating failed", "query", r.Query, "error", err)
+		return
+	}
+	r.addResponseUsage(c.opts.Model, response.UsageMetadata)
+
+	var rating struct {
+		Confidence float64  `json:"confidence"`
+		Caveats    []string `json:"caveats"`
+	}
+	if err := json.Unmarshal([]byte(response.Text()), &rating); err != nil {
+		slog.InfoContext(ctx, "Confidence rating is not valid JSON", "query", r.Query, "error", err)
+		return
+	}
+	confidence := min(max(rating.Confidence, 0), 1)
+	r.Confidence = &confidence
+	r.Caveats = nil
+	for _, caveat := range rating.Caveats {
+		if caveat = strings.TrimSpace(caveat); caveat != "" {
+			r.Caveats = append(r.Caveats, caveat)
+		}
+	}
+}
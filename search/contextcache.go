@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/genai"
+)
+
+// ContextCacher is implemented by providers that can store a request
+// prefix server-side and reference it by name, such as Gemini context
+// caching
+type ContextCacher interface {
+	CreateContextCache(ctx context.Context, model string, config *genai.CreateCachedContentConfig) (string, error)
+	DeleteContextCache(ctx context.Context, name string) error
+}
+
+func (p *GeminiProvider) CreateContextCache(ctx context.Context, model string, config *genai.CreateCachedContentConfig) (string, error) {
+	cached, err := p.client.Caches.Create(ctx, model, config)
+	if err != nil {
+		return "", err
+	}
+	return cached.Name, nil
+}
+
+func (p *GeminiProvider) DeleteContextCache(ctx context.Context, name string) error {
+	_, err := p.client.Caches.Delete(ctx, name, nil)
+	return err
+}
+
+// createContextCache stores the search system prompt, tools and
+// attachments in a context cache for Options.ContextCacheTTL, so searches
+// send only the query. Without a cache the full prompt is sent as usual.
+func (c *Client) createContextCache(ctx context.Context) {
+	cacher, ok := c.provider.(ContextCacher)
+	if !ok {
+		slog.Info("Context cache disabled: provider does not support it", "provider", c.provider.Name())
+		return
+	}
+	// Every search must share the cached prompt
+	if c.renderPrompt(c.systemPrompt, systemInstructionText, "a") != c.renderPrompt(c.systemPrompt, systemInstructionText, "b") {
+		slog.Info("Context cache disabled: the system prompt uses {{.Query}}")
+		return
+	}
+
+	config := &genai.CreateCachedContentConfig{
+		TTL:               c.opts.ContextCacheTTL,
+		DisplayName:       "go-search",
+		SystemInstruction: c.systemInstruction(""),
+		Tools:             c.tools(),
+	}
+	if parts := c.attachmentParts(); len(parts) > 0 {
+		config.Contents = []*genai.Content{{Role: "user", Parts: parts}}
+	}
+	name, err := cacher.CreateContextCache(ctx, c.opts.Model, config)
+	if err != nil {
+		// Usually the prompt is below the model's minimum cacheable size
+		slog.Info("Context cache disabled", "error", err)
+		return
+	}
+	slog.Info("Created context cache", "name", name, "ttl", c.opts.ContextCacheTTL)
+	c.contextCache = name
+}
+
+// ContextCache returns the name of the context cache searches use, or ""
+// when they send the full prompt
+func (c *Client) ContextCache() string {
+	return c.contextCache
+}
+
+// Close deletes the context cache, if one was created. Caches left behind
+// expire after Options.ContextCacheTTL.
+func (c *Client) Close(ctx context.Context) error {
+	if c.contextCache == "" {
+		return nil
+	}
+	cacher, ok := c.provider.(ContextCacher)
+	if !ok {
+		return nil
+	}
+	return cacher.DeleteContextCache(ctx, c.contextCache)
+}
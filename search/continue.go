@@ -0,0 +1,70 @@
+package search
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/genai"
+)
+
+// DefaultMaxContinuations is the Options.MaxContinuations of DefaultOptions
+const DefaultMaxContinuations = 2
+
+const continuePrompt = "Your answer was cut off. Continue it from exactly where it stopped, without repeating any of it or starting over."
+
+// truncated reports whether a response stopped at the output token limit
+func truncated(response *genai.GenerateContentResponse) bool {
+	return response != nil && len(response.Candidates) > 0 && response.Candidates[0] != nil &&
+		response.Candidates[0].FinishReason == genai.FinishReasonMaxTokens
+}
+
+// continueTruncated asks for the rest of text, a response to content that
+// was cut off at the output token limit, up to Options.MaxContinuations
+// times. onChunk receives each continuation as it arrives. It returns the
+// stitched text and a result holding the continuation count, the sources
+// they were grounded in and their usage, to merge with addContinuation.
+// A failed continuation keeps the text received so far.
+func (c *Client) continueTruncated(ctx context.Context, content []*genai.Content, genConfig *genai.GenerateContentConfig, text string, onChunk func(string)) (string, *Result) {
+	extra := &Result{}
+	// The word limit caps the tokens on purpose
+	if c.opts.MaxWords > 0 {
+		slog.InfoContext(ctx, "Response reached the word limit's token cap", "max_words", c.opts.MaxWords)
+		return text, extra
+	}
+
+	for extra.Continuations < c.opts.MaxContinuations {
+		next := append(append([]*genai.Content{}, content...),
+			&genai.Content{Role: "model", Parts: []*genai.Part{{Text: text}}},
+			&genai.Content{Role: "user", Parts: []*genai.Part{{Text: continuePrompt}}})
+		slog.InfoContext(ctx, "Continuing truncated response", "continuation", extra.Continuations+1, "bytes", len(text))
+
+		response, err := c.generate(ctx, "continue", next, genConfig)
+		if err != nil {
+			slog.InfoContext(ctx, "Continuation failed, keeping the truncated response", "error", err)
+			return text, extra
+		}
+		extra.Continuations++
+		extra.addResponseUsage(c.opts.Model, response.UsageMetadata)
+		for _, source := range c.filterSources(sourcesFrom(groundingMetadata(response))) {
+			extra.Sources = addSource(extra.Sources, source.URI, source.Title)
+		}
+		chunk := response.Text()
+		text += chunk
+		onChunk(chunk)
+		if !truncated(response) {
+			return text, extra
+		}
+	}
+	slog.InfoContext(ctx, "Response still truncated after continuations", "continuations", extra.Continuations)
+	return text, extra
+}
+
+// addContinuation merges the continuations returned by continueTruncated
+// into r
+func (r *Result) addContinuation(extra *Result) {
+	r.Continuations = extra.Continuations
+	r.addUsage(extra)
+	for _, source := range extra.Sources {
+		r.Sources = addSource(r.Sources, source.URI, source.Title)
+	}
+}
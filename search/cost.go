@@ -0,0 +1,39 @@
+package search
+
+// ModelPrice is a model's list price in USD per million tokens
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// ModelPrices holds the paid-tier text prices for KnownModels. Thinking
+// tokens are billed as output. Grounding fees are not included.
+var ModelPrices = map[string]ModelPrice{
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10.00},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+}
+
+// Cached prompt tokens are billed at a quarter of the input price. Cache
+// storage fees are not included.
+const cachedInputRate = 0.25
+
+// EstimateCost returns the estimated USD cost of a call to model, and false
+// if the model has no known price
+func EstimateCost(model string, promptTokens, outputTokens, thinkingTokens int32) (float64, bool) {
+	price, ok := ModelPrices[model]
+	if !ok {
+		return 0, false
+	}
+	input := float64(promptTokens) * price.Input
+	output := float64(outputTokens+thinkingTokens) * price.Output
+	return (input + output) / 1e6, true
+}
+
+// estimateCachedCost returns the estimated USD cost of cachedTokens prompt
+// tokens read from a context cache
+func estimateCachedCost(model string, cachedTokens int32) float64 {
+	return float64(cachedTokens) * ModelPrices[model].Input * cachedInputRate / 1e6
+}
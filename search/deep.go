@@ -0,0 +1,271 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/plan.txt
+var planInstructionText string
+
+//go:embed prompts/synthesize.txt
+var synthesizeInstructionText string
+
+// DefaultDeepQuestions is the number of sub-questions Deep plans when
+// DeepOptions.MaxQuestions is 0
+const DefaultDeepQuestions = 5
+
+var planSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"questions": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+	},
+	Required: []string{"questions"},
+}
+
+// DeepOptions configures Deep
+type DeepOptions struct {
+	// Upper bound on the sub-questions planned, DefaultDeepQuestions if 0
+	MaxQuestions int
+	// Sub-questions searched at once, 1 if 0
+	Workers int
+	// Called as each stage starts and each sub-question finishes
+	OnProgress func(DeepProgress)
+}
+
+// DeepStage identifies a step of Deep
+type DeepStage string
+
+const (
+	StagePlan       DeepStage = "plan"
+	StageSearch     DeepStage = "search"
+	StageSynthesize DeepStage = "synthesize"
+)
+
+// DeepProgress reports a step of Deep. During StageSearch, Result is set
+// once the sub-question at Index has finished.
+type DeepProgress struct {
+	Stage     DeepStage
+	Questions []string
+	Index     int
+	Result    *Result
+}
+
+// Deep researches query in three steps: it plans sub-questions, searches
+// them concurrently, and writes a cited report from the findings. The
+// returned result holds the report, the merged sources and, in Steps, the
+// individual searches. Token counts and cost cover every call.
+func (c *Client) Deep(ctx context.Context, query string, opts DeepOptions) (*Result, error) {
+	ctx, id := ensureRequestID(ctx)
+	startTime := time.Now()
+	result := &Result{ID: id, Query: query, Timestamp: startTime}
+	progress := func(p DeepProgress) {
+		if opts.OnProgress != nil {
+			opts.OnProgress(p)
+		}
+	}
+
+	maxQuestions := opts.MaxQuestions
+	if maxQuestions <= 0 {
+		maxQuestions = DefaultDeepQuestions
+	}
+	progress(DeepProgress{Stage: StagePlan})
+	questions, err := c.plan(ctx, query, maxQuestions, result)
+	if err != nil {
+		result.Duration = time.Since(startTime)
+		return result, result.fail("Research planning failed", err)
+	}
+	slog.InfoContext(ctx, "Planned deep research", "query", query, "questions", len(questions))
+
+	progress(DeepProgress{Stage: StageSearch, Questions: questions})
+	result.Steps = c.searchAll(ctx, questions, opts.Workers, func(index int, step *Result) {
+		progress(DeepProgress{Stage: StageSearch, Questions: questions, Index: index, Result: step})
+	})
+
+	var findings []Result
+	for _, step := range result.Steps {
+		result.addUsage(&step)
+		if step.Success {
+			findings = append(findings, step)
+		}
+	}
+	if len(findings) == 0 {
+		result.Duration = time.Since(startTime)
+		err := result.fail("Every sub-question search failed", fmt.Errorf("deep research found nothing: all %d searches failed", len(questions)))
+		// Report why the searches failed rather than the generic fallback
+		if len(result.Steps) > 0 {
+			result.ErrorCode = result.Steps[0].ErrorCode
+		}
+		return result, err
+	}
+
+	progress(DeepProgress{Stage: StageSynthesize, Questions: questions})
+	if err := c.synthesize(ctx, query, findings, result); err != nil {
+		result.Duration = time.Since(startTime)
+		return result, result.fail("Report synthesis failed", err)
+	}
+	result.Duration = time.Since(startTime)
+	result.Timings.Generation = result.Duration
+	result.Success = true
+	return result, nil
+}
+
+// plan asks the model for up to max standalone sub-questions of query
+func (c *Client) plan(ctx context.Context, query string, max int, result *Result) ([]string, error) {
+	content := []*genai.Content{{
+		Role: "user",
+		Parts: []*genai.Part{{Text: fmt.Sprintf("Question: %s\n\nToday: %s\nMaximum sub-questions: %d",
+			query, time.Now().Format(time.DateOnly), max)}},
+	}}
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: planInstructionText}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    planSchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	response, err := c.generate(ctx, "plan", content, genConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan research: %w", err)
+	}
+	result.addResponseUsage(c.opts.Model, response.UsageMetadata)
+
+	var plan struct {
+		Questions []string `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(response.Text()), &plan); err != nil {
+		return nil, fmt.Errorf("research plan is not valid JSON: %w", err)
+	}
+	var questions []string
+	for _, question := range plan.Questions {
+		if question = strings.TrimSpace(question); question != "" {
+			questions = append(questions, question)
+		}
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("research plan has no sub-questions")
+	}
+	if len(questions) > max {
+		questions = questions[:max]
+	}
+	return questions, nil
+}
+
+// searchAll searches each question with at most workers in flight, calling
+// done as each one finishes
+func (c *Client) searchAll(ctx context.Context, questions []string, workers int, done func(int, *Result)) []Result {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]Result, len(questions))
+	sem := make(chan struct{}, workers)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, question := range questions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			step, err := c.Search(WithRequestID(ctx, NewRequestID()), question)
+			if err != nil {
+				step.Error = err.Error()
+			}
+			results[i] = *step
+
+			mu.Lock()
+			defer mu.Unlock()
+			done(i, step)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// synthesize writes the report for query from findings into result,
+// numbering the merged sources as the report cites them
+func (c *Client) synthesize(ctx context.Context, query string, findings []Result, result *Result) error {
+	var sources []Source
+	for _, finding := range findings {
+		for _, source := range finding.Sources {
+			sources = addSource(sources, source.URI, source.Title)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Original question: %s\n\n", query)
+	for i, finding := range findings {
+		fmt.Fprintf(&b, "## Finding %d: %s\n\n%s\n\n", i+1, finding.Query, strings.TrimSpace(finding.Response))
+	}
+	b.WriteString("## Sources\n\n")
+	for i, source := range sources {
+		fmt.Fprintf(&b, "[%d] %s - %s\n", i+1, source.Title, source.URI)
+	}
+	content := []*genai.Content{{Role: "user", Parts: []*genai.Part{{Text: b.String()}}}}
+
+	budget := c.opts.ThinkingBudget
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: c.withLanguage(synthesizeInstructionText)}}},
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &budget,
+		},
+	}
+
+	response, err := c.generate(ctx, "synthesize", content, genConfig)
+	if err != nil {
+		return fmt.Errorf("failed to synthesize report: %w", err)
+	}
+	result.addResponseUsage(c.opts.Model, response.UsageMetadata)
+	result.Response = strings.TrimSpace(response.Text())
+	result.Sources = sources
+	return nil
+}
+
+// generate makes a single untooled call with retries, treating an empty
+// reply as a failure
+func (c *Client) generate(ctx context.Context, kind string, content []*genai.Content, genConfig *genai.GenerateContentConfig) (response *genai.GenerateContentResponse, err error) {
+	ctx, span := c.startSpan(ctx, kind)
+	defer func(start time.Time) {
+		var usage *genai.GenerateContentResponseUsageMetadata
+		if response != nil {
+			usage = response.UsageMetadata
+		}
+		c.endSpan(ctx, span, kind, start, usage, err)
+	}(time.Now())
+
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+
+	err = c.opts.Retry.do(ctx, func(attempt int) error {
+		c.logRequest(ctx, kind, attempt, content, genConfig)
+
+		var err error
+		response, err = c.provider.GenerateContent(ctx, c.opts.Model, content, genConfig)
+		c.logResponse(ctx, kind, attempt, response, err)
+		if err != nil {
+			return err
+		}
+		if response.Text() == "" {
+			return errEmptyResponse
+		}
+		return nil
+	}, nil)
+	if err != nil && timedOut(ctx) {
+		return nil, fmt.Errorf("%w after %s", ErrQueryTimeout, c.opts.QueryTimeout)
+	}
+	return response, err
+}
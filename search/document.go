@@ -0,0 +1,170 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// Documents up to this size are sent inline with each query; larger ones
+	// are uploaded once through the Files API
+	InlineDocumentBytes = 4 << 20
+	// MaxDocumentBytes is the largest document the Files API accepts for
+	// document understanding
+	MaxDocumentBytes = 50 << 20
+)
+
+// How often an uploaded document is checked while Gemini processes it
+const documentPollInterval = 2 * time.Second
+
+// Document types Gemini can read, by extension
+var documentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".md":   "text/md",
+	".html": "text/html",
+	".htm":  "text/html",
+	".csv":  "text/csv",
+	".xml":  "text/xml",
+	".rtf":  "text/rtf",
+}
+
+// Document is a file attached to search queries as context
+type Document struct {
+	// File the document was read from, for logs
+	Name     string
+	MIMEType string
+	Data     []byte
+	// Files API URI once uploaded, in which case Data is not sent
+	URI string
+}
+
+// FileUploader is implemented by providers that can upload documents too
+// large to send inline. UploadFile returns the URI to reference the file by.
+type FileUploader interface {
+	UploadFile(ctx context.Context, doc Document) (string, error)
+}
+
+// LoadDocuments reads the document files at paths, detecting their types
+// and checking them against MaxDocumentBytes
+func LoadDocuments(paths []string) ([]Document, error) {
+	var docs []Document
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("document %s is a directory", path)
+		}
+		if info.Size() > MaxDocumentBytes {
+			return nil, fmt.Errorf("document %s exceeds the %dMB limit", path, MaxDocumentBytes>>20)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+
+		mimeType, err := documentType(path, data)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, Document{Name: filepath.Base(path), MIMEType: mimeType, Data: data})
+	}
+	return docs, nil
+}
+
+func documentType(path string, data []byte) (string, error) {
+	detected, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	byExtension, known := documentTypes[strings.ToLower(filepath.Ext(path))]
+	switch {
+	case detected == "application/pdf":
+		return detected, nil
+	case known && byExtension != "application/pdf" && strings.HasPrefix(detected, "text/"):
+		return byExtension, nil
+	case !known && detected == "text/plain":
+		return detected, nil
+	}
+	return "", fmt.Errorf("document %s has unsupported type %s (supported: PDF and text files such as TXT, Markdown, HTML, CSV, XML, RTF)", path, detected)
+}
+
+func (doc Document) part() *genai.Part {
+	if doc.URI != "" {
+		return &genai.Part{FileData: &genai.FileData{FileURI: doc.URI, MIMEType: doc.MIMEType}}
+	}
+	return &genai.Part{InlineData: &genai.Blob{MIMEType: doc.MIMEType, Data: doc.Data}}
+}
+
+// uploadDocuments uploads the documents over InlineDocumentBytes so every
+// query can reference them instead of resending them
+func (c *Client) uploadDocuments(ctx context.Context) error {
+	for i, doc := range c.opts.Documents {
+		if len(doc.Data) <= InlineDocumentBytes || doc.URI != "" {
+			continue
+		}
+		uploader, ok := c.provider.(FileUploader)
+		if !ok {
+			return fmt.Errorf("document %s is over %dMB and the %s provider cannot upload files", doc.Name, InlineDocumentBytes>>20, c.provider.Name())
+		}
+		uri, err := uploader.UploadFile(ctx, doc)
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", doc.Name, err)
+		}
+		slog.Info("Uploaded document", "name", doc.Name, "bytes", len(doc.Data), "uri", uri)
+		c.opts.Documents[i].URI = uri
+	}
+	return nil
+}
+
+// documentsKey identifies the attached documents in cache keys
+func (c *Client) documentsKey() string {
+	if len(c.opts.Documents) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, doc := range c.opts.Documents {
+		h.Write(doc.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// UploadFile uploads doc through the Files API and waits until Gemini has
+// processed it. Uploaded files are deleted by the API after 48 hours.
+func (p *GeminiProvider) UploadFile(ctx context.Context, doc Document) (string, error) {
+	file, err := p.client.Files.Upload(ctx, bytes.NewReader(doc.Data), &genai.UploadFileConfig{
+		MIMEType:    doc.MIMEType,
+		DisplayName: doc.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+	for file.State == genai.FileStateProcessing {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(documentPollInterval):
+		}
+		file, err = p.client.Files.Get(ctx, file.Name, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to check upload: %w", err)
+		}
+	}
+	if file.State == genai.FileStateFailed {
+		if file.Error != nil && file.Error.Message != "" {
+			return "", fmt.Errorf("processing failed: %s", file.Error.Message)
+		}
+		return "", fmt.Errorf("processing failed")
+	}
+	return file.URI, nil
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/genai"
+)
+
+// EmbeddingModel is the model used for embeddings
+const EmbeddingModel = "gemini-embedding-001"
+
+// Texts sent in one embedding request
+const maxEmbedBatch = 100
+
+// Embedding tasks, which tune vectors for the side of a retrieval they are on
+const (
+	EmbedDocument = "RETRIEVAL_DOCUMENT"
+	EmbedQuery    = "RETRIEVAL_QUERY"
+)
+
+// ErrEmbeddingsUnsupported is returned by Embed when the provider has no
+// embeddings API
+var ErrEmbeddingsUnsupported = errors.New("embeddings are only supported by the gemini provider")
+
+// Embedder is implemented by providers that can turn text into vectors
+type Embedder interface {
+	EmbedContent(ctx context.Context, model string, texts []string, task string) ([][]float32, error)
+}
+
+func (p *GeminiProvider) EmbedContent(ctx context.Context, model string, texts []string, task string) ([][]float32, error) {
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	response, err := p.client.Models.EmbedContent(ctx, model, contents, &genai.EmbedContentConfig{TaskType: task})
+	if err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(response.Embeddings))
+	for i, embedding := range response.Embeddings {
+		vectors[i] = embedding.Values
+	}
+	return vectors, nil
+}
+
+// Embed returns a vector for each of texts, computed with EmbeddingModel for
+// task, one of EmbedDocument or EmbedQuery. Long inputs are split into
+// batches.
+func (c *Client) Embed(ctx context.Context, texts []string, task string) ([][]float32, error) {
+	embedder, ok := c.provider.(Embedder)
+	if !ok {
+		return nil, ErrEmbeddingsUnsupported
+	}
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		batch := texts[start:min(start+maxEmbedBatch, len(texts))]
+		var embedded [][]float32
+		err := c.opts.Retry.do(ctx, func(attempt int) error {
+			c.logEmbedRequest(ctx, attempt, batch)
+			var err error
+			embedded, err = embedder.EmbedContent(ctx, EmbeddingModel, batch, task)
+			if c.opts.OnResponse != nil {
+				c.opts.OnResponse(ctx, Response{ID: RequestID(ctx), Kind: "embed", Attempt: attempt, Model: EmbeddingModel, Err: err})
+			}
+			return err
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded) != len(batch) {
+			return nil, fmt.Errorf("embedding returned %d vectors for %d texts", len(embedded), len(batch))
+		}
+		vectors = append(vectors, embedded...)
+	}
+	return vectors, nil
+}
+
+// logEmbedRequest reports an embedding request to Options.OnRequest, with
+// each text as a content
+func (c *Client) logEmbedRequest(ctx context.Context, attempt int, texts []string) {
+	if c.opts.OnRequest == nil {
+		return
+	}
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	c.opts.OnRequest(ctx, Request{
+		ID:       RequestID(ctx),
+		Kind:     "embed",
+		Attempt:  attempt,
+		Model:    EmbeddingModel,
+		Contents: contents,
+	})
+}
+
+// CosineSimilarity compares two embeddings, returning 0 when their
+// lengths differ
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
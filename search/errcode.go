@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// ErrorCode classifies why a call failed, so callers can branch on the kind
+// of failure instead of matching error messages
+type ErrorCode string
+
+const (
+	CodeAuth        ErrorCode = "auth"
+	CodeTimeout     ErrorCode = "timeout"
+	CodeRateLimited ErrorCode = "rate_limited"
+	CodeEmpty       ErrorCode = "empty_response"
+	CodeBlocked     ErrorCode = "safety_blocked"
+	CodeInterrupted ErrorCode = "interrupted"
+	CodeNetwork     ErrorCode = "network"
+	CodeBudget      ErrorCode = "retry_budget_exhausted"
+	CodeAPI         ErrorCode = "api_error"
+	CodeUnknown     ErrorCode = "unknown"
+)
+
+// ErrNoAPIKey is wrapped by provider errors when no API key is configured
+var ErrNoAPIKey = errors.New("API key is not set")
+
+// Code returns the ErrorCode for err, or "" if err is nil
+func Code(err error) ErrorCode {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNoAPIKey):
+		return CodeAuth
+	case errors.Is(err, ErrRetryBudgetExhausted):
+		return CodeBudget
+	case errors.Is(err, ErrQueryTimeout), errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, context.Canceled):
+		return CodeInterrupted
+	case errors.Is(err, errEmptyResponse):
+		return CodeEmpty
+	}
+
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return CodeBlocked
+	}
+
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusUnauthorized, apiErr.Code == http.StatusForbidden:
+			return CodeAuth
+		case apiErr.Code == http.StatusTooManyRequests:
+			return CodeRateLimited
+		// Gemini rejects a bad key with 400 rather than 401
+		case apiErr.Code == http.StatusBadRequest && strings.Contains(apiErr.Message, "API key"):
+			return CodeAuth
+		}
+		return CodeAPI
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return CodeNetwork
+	}
+	return CodeUnknown
+}
+
+// fail marks r as failed with message for display and the code of err,
+// and returns err
+func (r *Result) fail(message string, err error) error {
+	r.Success = false
+	r.Error = message
+	r.ErrorCode = Code(err)
+	return err
+}
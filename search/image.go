@@ -0,0 +1,98 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// MaxImageBytes bounds the combined size of the images attached to a
+// query. Gemini rejects requests over 20MB, and inline images grow by a
+// third when base64 encoded.
+const MaxImageBytes = 15 << 20
+
+// Image types Gemini accepts, with the extensions used when content
+// sniffing cannot tell
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+}
+
+// Image is a picture attached to search queries
+type Image struct {
+	// File the image was read from, for logs
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// LoadImages reads the image files at paths, detecting their types and
+// checking them against MaxImageBytes
+func LoadImages(paths []string) ([]Image, error) {
+	var images []Image
+	total := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("image %s is a directory", path)
+		}
+		if total+int(info.Size()) > MaxImageBytes {
+			return nil, fmt.Errorf("images exceed the %dMB limit at %s", MaxImageBytes>>20, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		total += len(data)
+
+		mimeType, err := imageType(path, data)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, Image{Name: filepath.Base(path), MIMEType: mimeType, Data: data})
+	}
+	return images, nil
+}
+
+func imageType(path string, data []byte) (string, error) {
+	detected, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	for _, known := range imageTypes {
+		if detected == known {
+			return detected, nil
+		}
+	}
+	// HEIC and HEIF are not sniffed by net/http
+	if byExtension, ok := imageTypes[strings.ToLower(filepath.Ext(path))]; ok && detected == "application/octet-stream" {
+		return byExtension, nil
+	}
+	return "", fmt.Errorf("image %s has unsupported type %s (supported: PNG, JPEG, WebP, HEIC, HEIF)", path, detected)
+}
+
+func (img Image) part() *genai.Part {
+	return &genai.Part{InlineData: &genai.Blob{MIMEType: img.MIMEType, Data: img.Data}}
+}
+
+// imagesKey identifies the attached images in cache keys
+func (c *Client) imagesKey() string {
+	if len(c.opts.Images) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, img := range c.opts.Images {
+		h.Write(img.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageNames maps the language codes with a known name to the English
+// name used in instructions
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"hi": "Hindi",
+	"id": "Indonesian",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"vi": "Vietnamese",
+	"zh": "Chinese",
+}
+
+// LanguageName returns the English name of a language code such as "es" or
+// "pt-BR". Unknown codes are returned as given, so a name like "Catalan"
+// also works.
+func LanguageName(code string) string {
+	base, region, _ := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-")
+	name, ok := languageNames[strings.ToLower(base)]
+	if !ok {
+		return code
+	}
+	if region != "" {
+		return fmt.Sprintf("%s (%s)", name, strings.ToUpper(region))
+	}
+	return name
+}
+
+// withLanguage adds the Options.Language requirement to an instruction for
+// text the user reads
+func (c *Client) withLanguage(instruction string) string {
+	if c.opts.Language == "" {
+		return instruction
+	}
+	return instruction + fmt.Sprintf("\n\nWrite your entire answer in %s, whatever the language of the query or the sources.", LanguageName(c.opts.Language))
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// MaxToolRounds bounds the turns in which the model may call local tools
+// before it has to answer
+const MaxToolRounds = 5
+
+// LocalTool is a function the model may call during a search, declared
+// with Options.LocalTools. Run receives the arguments the model chose and
+// returns the text sent back to it.
+type LocalTool struct {
+	Declaration *genai.FunctionDeclaration
+	Run         func(ctx context.Context, args map[string]any) (string, error)
+}
+
+// ToolCall records one call the model made to a local tool
+type ToolCall struct {
+	Name     string         `json:"name"`
+	Args     map[string]any `json:"args,omitempty"`
+	Output   string         `json:"output,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Duration time.Duration  `json:"duration"`
+}
+
+// wantsTools reports whether a response asks for local tool calls
+func (c *Client) wantsTools(response *genai.GenerateContentResponse) bool {
+	return len(c.opts.LocalTools) > 0 && response != nil && len(response.FunctionCalls()) > 0
+}
+
+// callTools runs the local tools response asks for and sends their output
+// back, until the model answers in text or MaxToolRounds is used up. It
+// returns the final response, the conversation with the tool turns added,
+// and a result holding the calls and the usage of every response but the
+// last.
+func (c *Client) callTools(ctx context.Context, content []*genai.Content, genConfig *genai.GenerateContentConfig, response *genai.GenerateContentResponse) (*genai.GenerateContentResponse, []*genai.Content, *Result, error) {
+	extra := &Result{}
+	content = append([]*genai.Content{}, content...)
+	for round := 1; c.wantsTools(response); round++ {
+		if round > MaxToolRounds {
+			return nil, content, extra, fmt.Errorf("model still calling tools after %d rounds", MaxToolRounds)
+		}
+		extra.addResponseUsage(c.opts.Model, response.UsageMetadata)
+
+		var parts []*genai.Part
+		for _, call := range response.FunctionCalls() {
+			record := c.runTool(ctx, call)
+			extra.ToolCalls = append(extra.ToolCalls, record)
+			output := map[string]any{"output": record.Output}
+			if record.Error != "" {
+				output = map[string]any{"error": record.Error, "output": record.Output}
+			}
+			parts = append(parts, &genai.Part{FunctionResponse: &genai.FunctionResponse{ID: call.ID, Name: call.Name, Response: output}})
+		}
+		content = append(content, toolCallContent(response), &genai.Content{Role: "user", Parts: parts})
+
+		err := c.opts.Retry.do(ctx, func(attempt int) error {
+			c.logRequest(ctx, "tools", attempt, content, genConfig)
+			var err error
+			response, err = c.provider.GenerateContent(ctx, c.opts.Model, content, genConfig)
+			c.logResponse(ctx, "tools", attempt, response, err)
+			if err != nil {
+				return err
+			}
+			if response.Text() == "" && !c.wantsTools(response) {
+				if blocked := blockedBy(response); blocked != nil {
+					return blocked
+				}
+				return errEmptyResponse
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			return nil, content, extra, fmt.Errorf("failed to answer after tool calls: %w", err)
+		}
+	}
+	return response, content, extra, nil
+}
+
+// runTool calls the local tool named by call. Unknown tools and failures
+// are reported back to the model rather than ending the search.
+func (c *Client) runTool(ctx context.Context, call *genai.FunctionCall) ToolCall {
+	record := ToolCall{Name: call.Name, Args: call.Args}
+	start := time.Now()
+
+	for _, tool := range c.opts.LocalTools {
+		if tool.Declaration.Name != call.Name {
+			continue
+		}
+		slog.InfoContext(ctx, "Calling local tool", "tool", call.Name, "args", call.Args)
+		output, err := tool.Run(ctx, call.Args)
+		record.Output = output
+		if err != nil {
+			slog.InfoContext(ctx, "Local tool failed", "tool", call.Name, "error", err)
+			record.Error = err.Error()
+		}
+		record.Duration = time.Since(start)
+		return record
+	}
+	record.Error = fmt.Sprintf("unknown tool %q", call.Name)
+	return record
+}
+
+// functionCallParts returns the parts of a response that call functions,
+// with the thought signatures the model needs to see again
+func functionCallParts(response *genai.GenerateContentResponse) []*genai.Part {
+	var parts []*genai.Part
+	if len(response.Candidates) == 0 || response.Candidates[0].Content == nil {
+		return nil
+	}
+	for _, part := range response.Candidates[0].Content.Parts {
+		if part != nil && part.FunctionCall != nil {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// toolCallContent is the model turn holding a response's function calls
+func toolCallContent(response *genai.GenerateContentResponse) *genai.Content {
+	if len(response.Candidates) > 0 && response.Candidates[0].Content != nil {
+		return response.Candidates[0].Content
+	}
+	var parts []*genai.Part
+	for _, call := range response.FunctionCalls() {
+		parts = append(parts, &genai.Part{FunctionCall: call})
+	}
+	return &genai.Content{Role: "model", Parts: parts}
+}
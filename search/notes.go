@@ -0,0 +1,320 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// DefaultNoteChunks is the number of note excerpts sent with each query
+// when Options.NoteChunks is 0
+const DefaultNoteChunks = 5
+
+// Characters in a note chunk; paragraphs are kept whole when they fit
+const noteChunkChars = 2000
+
+// Note file types, by extension
+var noteTypes = map[string]string{
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".txt":      "text/plain",
+	".pdf":      "application/pdf",
+}
+
+const noteInstruction = "\n\nThe query may be followed by excerpts from the user's own notes in <notes> tags. Combine what is relevant in them with what you find on the web, cite a note by its file name in brackets, such as [plans/q3.md], and ignore excerpts that do not bear on the query. Prefer the web for anything that may have changed since the notes were written."
+
+const noteExtractPrompt = "Transcribe the text of this document as plain Markdown, keeping headings and lists. Do not summarize or add anything."
+
+// NoteMatch is a note excerpt an answer was given as context
+type NoteMatch struct {
+	Source     string  `json:"source"`
+	Similarity float64 `json:"similarity"`
+}
+
+type noteChunk struct {
+	Source string    `json:"source"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// LoadNotes reads the Markdown, text and PDF files at paths, walking
+// directories. Files found in a directory are named by their path inside it.
+func LoadNotes(paths []string) ([]Document, error) {
+	var notes []Document
+	add := func(path, name string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to read note: %w", err)
+		}
+		if info.Size() > MaxDocumentBytes {
+			return fmt.Errorf("note %s exceeds the %dMB limit", path, MaxDocumentBytes>>20)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read note: %w", err)
+		}
+		notes = append(notes, Document{Name: name, MIMEType: noteTypes[strings.ToLower(filepath.Ext(path))], Data: data})
+		return nil
+	}
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notes: %w", err)
+		}
+		if !info.IsDir() {
+			if _, ok := noteTypes[strings.ToLower(filepath.Ext(root))]; !ok {
+				return nil, fmt.Errorf("note %s is not a Markdown, text or PDF file", root)
+			}
+			if err := add(root, filepath.Base(root)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, ok := noteTypes[strings.ToLower(filepath.Ext(path))]; !ok {
+				return nil
+			}
+			name, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			return add(path, filepath.ToSlash(name))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notes: %w", err)
+		}
+	}
+	if len(notes) == 0 {
+		return nil, fmt.Errorf("no Markdown, text or PDF files found in %s", strings.Join(paths, ", "))
+	}
+	return notes, nil
+}
+
+// indexNotes splits Options.Notes into chunks and embeds them. Chunks are
+// kept in Options.NoteCacheDir by file content, so unchanged files are not
+// embedded again.
+func (c *Client) indexNotes(ctx context.Context) error {
+	if _, ok := c.provider.(Embedder); !ok {
+		return ErrEmbeddingsUnsupported
+	}
+	key := sha256.New()
+	for _, note := range c.opts.Notes {
+		sum := sha256.Sum256(append([]byte(EmbeddingModel+"\x00"), note.Data...))
+		key.Write(sum[:])
+		cachePath := ""
+		if c.opts.NoteCacheDir != "" {
+			cachePath = filepath.Join(c.opts.NoteCacheDir, hex.EncodeToString(sum[:])+".json")
+		}
+
+		if chunks, ok := readNoteChunks(cachePath); ok {
+			for i := range chunks {
+				chunks[i].Source = note.Name
+			}
+			c.notes = append(c.notes, chunks...)
+			continue
+		}
+
+		text, err := c.noteText(ctx, note)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", note.Name, err)
+		}
+		chunks := chunkNote(note.Name, text)
+		if len(chunks) == 0 {
+			continue
+		}
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Text
+		}
+		vectors, err := c.Embed(ctx, texts, EmbedDocument)
+		if err != nil {
+			return fmt.Errorf("failed to embed %s: %w", note.Name, err)
+		}
+		for i := range chunks {
+			chunks[i].Vector = vectors[i]
+		}
+		slog.Info("Indexed note", "name", note.Name, "chunks", len(chunks))
+		writeNoteChunks(cachePath, chunks)
+		c.notes = append(c.notes, chunks...)
+	}
+	c.notesKey = hex.EncodeToString(key.Sum(nil))
+	return nil
+}
+
+// noteText returns the text of a note, asking the model to transcribe PDFs
+func (c *Client) noteText(ctx context.Context, note Document) (string, error) {
+	if note.MIMEType != "application/pdf" {
+		return string(note.Data), nil
+	}
+	if len(note.Data) > InlineDocumentBytes {
+		uploader, ok := c.provider.(FileUploader)
+		if !ok {
+			return "", fmt.Errorf("PDF is over %dMB and the %s provider cannot upload files", InlineDocumentBytes>>20, c.provider.Name())
+		}
+		uri, err := uploader.UploadFile(ctx, note)
+		if err != nil {
+			return "", err
+		}
+		note.URI = uri
+	}
+	content := []*genai.Content{{
+		Role:  "user",
+		Parts: []*genai.Part{note.part(), {Text: noteExtractPrompt}},
+	}}
+	var noThinking int32
+	response, err := c.generate(ctx, "extract", content, &genai.GenerateContentConfig{
+		SafetySettings: c.opts.SafetySettings,
+		ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: &noThinking},
+	})
+	if err != nil {
+		return "", err
+	}
+	return response.Text(), nil
+}
+
+// chunkNote splits text at blank lines into chunks of up to
+// noteChunkChars, cutting paragraphs that are longer on their own
+func chunkNote(source, text string) []noteChunk {
+	var chunks []noteChunk
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, noteChunk{Source: source, Text: current.String()})
+			current.Reset()
+		}
+	}
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+len(paragraph)+2 > noteChunkChars {
+			flush()
+		}
+		for runes := []rune(paragraph); len(runes) > noteChunkChars; runes = []rune(paragraph) {
+			flush()
+			current.WriteString(string(runes[:noteChunkChars]))
+			flush()
+			paragraph = string(runes[noteChunkChars:])
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	flush()
+	return chunks
+}
+
+func readNoteChunks(path string) ([]noteChunk, bool) {
+	if path == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var chunks []noteChunk
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return nil, false
+	}
+	return chunks, true
+}
+
+// writeNoteChunks caches the chunks of a note; failures only cost a new
+// embedding next time
+func writeNoteChunks(path string, chunks []noteChunk) {
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(chunks)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		slog.Info("Failed to cache note embeddings", "error", err)
+	}
+}
+
+// noteContext returns the note chunks closest to query as a part to send
+// with it. Retrieval failures are logged and the search goes ahead without
+// notes.
+func (c *Client) noteContext(ctx context.Context, query string) (*genai.Part, []NoteMatch) {
+	if len(c.notes) == 0 {
+		return nil, nil
+	}
+	vectors, err := c.Embed(ctx, []string{query}, EmbedQuery)
+	if err != nil {
+		slog.InfoContext(ctx, "Note retrieval failed", "query", query, "error", err)
+		return nil, nil
+	}
+
+	type scored struct {
+		chunk      *noteChunk
+		similarity float64
+	}
+	ranked := make([]scored, len(c.notes))
+	for i := range c.notes {
+		ranked[i] = scored{&c.notes[i], CosineSimilarity(vectors[0], c.notes[i].Vector)}
+	}
+	slices.SortFunc(ranked, func(a, b scored) int {
+		return cmp.Compare(b.similarity, a.similarity)
+	})
+	limit := c.opts.NoteChunks
+	if limit <= 0 {
+		limit = DefaultNoteChunks
+	}
+	ranked = ranked[:min(limit, len(ranked))]
+
+	var b strings.Builder
+	b.WriteString("<notes>\n")
+	matches := make([]NoteMatch, len(ranked))
+	for i, r := range ranked {
+		fmt.Fprintf(&b, "<note source=%q>\n%s\n</note>\n", r.chunk.Source, r.chunk.Text)
+		matches[i] = NoteMatch{Source: r.chunk.Source, Similarity: r.similarity}
+	}
+	b.WriteString("</notes>")
+	return &genai.Part{Text: b.String()}, matches
+}
+
+// withNotes adds the notes relevant to query to the final turn of content
+func (c *Client) withNotes(ctx context.Context, content []*genai.Content, query string, result *Result) {
+	part, matches := c.noteContext(ctx, query)
+	if part == nil {
+		return
+	}
+	last := content[len(content)-1]
+	last.Parts = append(last.Parts, part)
+	result.Notes = matches
+}
+
+func (c *Client) noteInstruction() string {
+	if len(c.notes) == 0 {
+		return ""
+	}
+	return noteInstruction
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// OllamaProvider calls a local Ollama server. Ollama has no built-in web
+// search, so answers come from the model alone and carry no sources.
+type OllamaProvider struct {
+	httpClient *http.Client
+	host       string
+}
+
+// NewOllamaProvider reads OLLAMA_HOST, defaulting to http://localhost:11434
+func NewOllamaProvider(httpClient *http.Client) *OllamaProvider {
+	host := os.Getenv("OLLAMA_HOST")
+	if host == "" {
+		host = "http://localhost:11434"
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return &OllamaProvider{httpClient: httpClient, host: strings.TrimSuffix(host, "/")}
+}
+
+func (p *OllamaProvider) Name() string { return ProviderOllama }
+
+type ollamaRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Format   any            `json:"format,omitempty"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int32 `json:"prompt_eval_count"`
+	EvalCount       int32 `json:"eval_count"`
+}
+
+func (p *OllamaProvider) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	req := ollamaRequest{Model: model}
+	if config != nil {
+		if system := contentText(config.SystemInstruction); system != "" {
+			req.Messages = append(req.Messages, chatMessage{Role: "system", Content: system})
+		}
+		if config.MaxOutputTokens > 0 {
+			req.Options = map[string]any{"num_predict": config.MaxOutputTokens}
+		}
+	}
+	req.Messages = append(req.Messages, chatMessages(contents)...)
+	if schema := responseJSONSchema(config); schema != nil {
+		req.Format = schema
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, p.httpClient, p.host+"/api/chat", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return textResponse(resp.Message.Content, nil, resp.PromptEvalCount, resp.EvalCount, 0), nil
+}
+
+// GenerateContentStream returns the whole response as a single chunk
+func (p *OllamaProvider) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return singleResponseStream(func() (*genai.GenerateContentResponse, error) {
+		return p.GenerateContent(ctx, model, contents, config)
+	})
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// OpenAIProvider calls the OpenAI Responses API, using its web search tool
+// for grounded searches
+type OpenAIProvider struct {
+	httpClient *http.Client
+	apiKey     string
+	baseURL    string
+}
+
+// NewOpenAIProvider reads OPENAI_API_KEY and, optionally, OPENAI_BASE_URL
+func NewOpenAIProvider(httpClient *http.Client) (*OpenAIProvider, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: set OPENAI_API_KEY", ErrNoAPIKey)
+	}
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAIProvider{httpClient: httpClient, apiKey: apiKey, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (p *OpenAIProvider) Name() string { return ProviderOpenAI }
+
+type openAIRequest struct {
+	Model        string           `json:"model"`
+	Instructions string           `json:"instructions,omitempty"`
+	Input        []chatMessage    `json:"input"`
+	Tools        []map[string]any `json:"tools,omitempty"`
+	Text         map[string]any   `json:"text,omitempty"`
+	MaxTokens    int32            `json:"max_output_tokens,omitempty"`
+}
+
+type openAIResponse struct {
+	Output []struct {
+		Type    string `json:"type"`
+		Content []struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			Annotations []struct {
+				Type  string `json:"type"`
+				URL   string `json:"url"`
+				Title string `json:"title"`
+			} `json:"annotations"`
+		} `json:"content"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens         int32 `json:"input_tokens"`
+		OutputTokens        int32 `json:"output_tokens"`
+		OutputTokensDetails struct {
+			ReasoningTokens int32 `json:"reasoning_tokens"`
+		} `json:"output_tokens_details"`
+	} `json:"usage"`
+}
+
+func (p *OpenAIProvider) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	req := openAIRequest{
+		Model: model,
+		Input: chatMessages(contents),
+	}
+	if config != nil {
+		req.Instructions = contentText(config.SystemInstruction)
+		req.MaxTokens = config.MaxOutputTokens
+	}
+	if usesSearch(config) {
+		req.Tools = []map[string]any{{"type": "web_search"}}
+	}
+	if schema := responseJSONSchema(config); schema != nil {
+		req.Text = map[string]any{"format": map[string]any{
+			"type":   "json_schema",
+			"name":   "response",
+			"schema": schema,
+		}}
+	}
+
+	var resp openAIResponse
+	headers := map[string]string{"Authorization": "Bearer " + p.apiKey}
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/responses", headers, req, &resp); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	var sources []Source
+	for _, output := range resp.Output {
+		if output.Type != "message" {
+			continue
+		}
+		for _, content := range output.Content {
+			if content.Type != "output_text" {
+				continue
+			}
+			text.WriteString(content.Text)
+			for _, annotation := range content.Annotations {
+				if annotation.Type == "url_citation" && annotation.URL != "" {
+					sources = addSource(sources, annotation.URL, annotation.Title)
+				}
+			}
+		}
+	}
+
+	// Reasoning tokens are included in OpenAI's output count
+	reasoning := resp.Usage.OutputTokensDetails.ReasoningTokens
+	return textResponse(text.String(), sources, resp.Usage.InputTokens, resp.Usage.OutputTokens-reasoning, reasoning), nil
+}
+
+// GenerateContentStream returns the whole response as a single chunk
+func (p *OpenAIProvider) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return singleResponseStream(func() (*genai.GenerateContentResponse, error) {
+		return p.GenerateContent(ctx, model, contents, config)
+	})
+}
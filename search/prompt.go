@@ -0,0 +1,46 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PromptData holds the variables available to custom prompt templates
+type PromptData struct {
+	// Today's date as YYYY-MM-DD
+	Date  string
+	Query string
+	Model string
+}
+
+// parsePrompt compiles a custom prompt and runs it once, so template
+// mistakes surface from NewClient rather than in the middle of a search
+func parsePrompt(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s prompt: %w", name, err)
+	}
+	if err := tmpl.Execute(io.Discard, PromptData{}); err != nil {
+		return nil, fmt.Errorf("invalid %s prompt: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// renderPrompt expands tmpl for query, or returns fallback when no custom
+// prompt is set
+func (c *Client) renderPrompt(tmpl *template.Template, fallback, query string) string {
+	if tmpl == nil {
+		return fallback
+	}
+	var b strings.Builder
+	data := PromptData{Date: time.Now().Format(time.DateOnly), Query: query, Model: c.opts.Model}
+	if err := tmpl.Execute(&b, data); err != nil {
+		slog.Error("Failed to render custom prompt, using the default", "prompt", tmpl.Name(), "error", err)
+		return fallback
+	}
+	return b.String()
+}
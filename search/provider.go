@@ -0,0 +1,352 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+	"google.golang.org/genai"
+)
+
+// Provider sends generation requests to a model backend. Requests and
+// responses use the genai types; providers other than Gemini translate them
+// to and from their own APIs.
+type Provider interface {
+	Name() string
+	GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+	GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error]
+}
+
+// Provider names accepted by NewProvider
+const (
+	ProviderGemini    = "gemini"
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
+// ProviderDefaultModels is the model used for each provider when none is set
+var ProviderDefaultModels = map[string]string{
+	ProviderGemini:    DefaultModel,
+	ProviderOpenAI:    "gpt-4.1",
+	ProviderAnthropic: "claude-sonnet-4-5",
+	ProviderOllama:    "llama3.1",
+}
+
+// DefaultModelFor returns the default model for a provider name
+func DefaultModelFor(provider string) string {
+	if model, ok := ProviderDefaultModels[provider]; ok {
+		return model
+	}
+	return DefaultModel
+}
+
+// NewProvider creates the named provider. Credentials and endpoints are read
+// from the environment; httpClient may be nil.
+func NewProvider(ctx context.Context, name string, httpClient *http.Client) (Provider, error) {
+	var provider Provider
+	var err error
+	switch name {
+	case ProviderGemini, "":
+		provider, err = NewGeminiProvider(ctx, httpClient)
+	case ProviderOpenAI:
+		provider, err = NewOpenAIProvider(httpClient)
+	case ProviderAnthropic:
+		provider, err = NewAnthropicProvider(httpClient)
+	case ProviderOllama:
+		provider = NewOllamaProvider(httpClient)
+	default:
+		return nil, fmt.Errorf("unknown provider %q (known: gemini, openai, anthropic, ollama)", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// GeminiProvider calls the Gemini API through the genai client
+type GeminiProvider struct {
+	client *genai.Client
+}
+
+// NewGeminiProvider creates a genai client. Credentials are read from the
+// environment as described in the genai docs.
+func NewGeminiProvider(ctx context.Context, httpClient *http.Client) (*GeminiProvider, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		if os.Getenv("GOOGLE_API_KEY") == "" && os.Getenv("GEMINI_API_KEY") == "" {
+			return nil, fmt.Errorf("failed to create client: %w: set GOOGLE_API_KEY or GEMINI_API_KEY", ErrNoAPIKey)
+		}
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return &GeminiProvider{client: client}, nil
+}
+
+// Region used for Vertex AI when none is given or set in the environment
+const DefaultVertexLocation = "us-central1"
+
+// NewVertexProvider creates a genai client for Vertex AI, authenticated with
+// Application Default Credentials. An empty project or location falls back
+// to GOOGLE_CLOUD_PROJECT and GOOGLE_CLOUD_LOCATION, then the location to
+// DefaultVertexLocation.
+func NewVertexProvider(ctx context.Context, project, location string, httpClient *http.Client) (*GeminiProvider, error) {
+	if location == "" && os.Getenv("GOOGLE_CLOUD_LOCATION") == "" && os.Getenv("GOOGLE_CLOUD_REGION") == "" {
+		location = DefaultVertexLocation
+	}
+	config := &genai.ClientConfig{
+		Backend:  genai.BackendVertexAI,
+		Project:  project,
+		Location: location,
+	}
+	// genai only adds credentials to the HTTP client it builds itself
+	if httpClient != nil {
+		authed, err := vertexHTTPClient(ctx, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		config.HTTPClient = authed
+	}
+	client, err := genai.NewClient(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vertex AI client: %w", err)
+	}
+	return &GeminiProvider{client: client}, nil
+}
+
+// vertexHTTPClient wraps the transport of base with Application Default
+// Credentials, billing requests to the credentials' quota project if set
+func vertexHTTPClient(ctx context.Context, base *http.Client) (*http.Client, error) {
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+		Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Application Default Credentials: %w", err)
+	}
+	quotaProject, err := creds.QuotaProjectID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota project: %w", err)
+	}
+	headers := http.Header{}
+	if quotaProject != "" {
+		headers.Set("X-Goog-User-Project", quotaProject)
+	}
+	transport := base.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return httptransport.NewClient(&httptransport.Options{
+		Credentials:      creds,
+		Headers:          headers,
+		BaseRoundTripper: transport,
+	})
+}
+
+// CheckAPIKey verifies a Gemini API key by looking up model, which costs
+// no tokens
+func CheckAPIKey(ctx context.Context, httpClient *http.Client, apiKey, model string) error {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:     apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	_, err = client.Models.Get(ctx, model, nil)
+	return err
+}
+
+func (p *GeminiProvider) Name() string { return ProviderGemini }
+
+func (p *GeminiProvider) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	return p.client.Models.GenerateContent(ctx, model, contents, config)
+}
+
+func (p *GeminiProvider) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return p.client.Models.GenerateContentStream(ctx, model, contents, config)
+}
+
+// singleResponseStream adapts a non-streaming call for providers that return
+// the whole response at once
+func singleResponseStream(generate func() (*genai.GenerateContentResponse, error)) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		yield(generate())
+	}
+}
+
+// chatMessage is a role and text pair shared by the chat-style APIs
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatMessages flattens genai contents into text messages, mapping the
+// genai "model" role to "assistant"
+func chatMessages(contents []*genai.Content) []chatMessage {
+	var messages []chatMessage
+	for _, content := range contents {
+		role := content.Role
+		if role == "model" {
+			role = "assistant"
+		}
+		if role == "" {
+			role = "user"
+		}
+		messages = append(messages, chatMessage{Role: role, Content: contentText(content)})
+	}
+	return messages
+}
+
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range content.Parts {
+		if part != nil && !part.Thought {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
+
+func usesSearch(config *genai.GenerateContentConfig) bool {
+	if config == nil {
+		return false
+	}
+	for _, tool := range config.Tools {
+		if tool != nil && tool.GoogleSearch != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// responseJSONSchema returns the JSON Schema requested by config, if any
+func responseJSONSchema(config *genai.GenerateContentConfig) any {
+	if config == nil || config.ResponseMIMEType != "application/json" {
+		return nil
+	}
+	if config.ResponseJsonSchema != nil {
+		return config.ResponseJsonSchema
+	}
+	if config.ResponseSchema != nil {
+		return jsonSchemaFrom(config.ResponseSchema)
+	}
+	return map[string]any{"type": "object"}
+}
+
+// jsonSchemaFrom converts the subset of genai.Schema used in this package
+// to a JSON Schema document
+func jsonSchemaFrom(schema *genai.Schema) map[string]any {
+	out := map[string]any{"type": strings.ToLower(string(schema.Type))}
+	if len(schema.Enum) > 0 {
+		out["enum"] = schema.Enum
+	}
+	if schema.Items != nil {
+		out["items"] = jsonSchemaFrom(schema.Items)
+	}
+	if len(schema.Properties) > 0 {
+		properties := map[string]any{}
+		for name, property := range schema.Properties {
+			properties[name] = jsonSchemaFrom(property)
+		}
+		out["properties"] = properties
+	}
+	if len(schema.Required) > 0 {
+		out["required"] = schema.Required
+	}
+	return out
+}
+
+// textResponse builds a genai response from a provider's answer, so the
+// rest of the package can treat every provider like Gemini
+func textResponse(text string, sources []Source, promptTokens, outputTokens, thinkingTokens int32) *genai.GenerateContentResponse {
+	candidate := &genai.Candidate{
+		Content: &genai.Content{Role: "model", Parts: []*genai.Part{{Text: text}}},
+	}
+	if len(sources) > 0 {
+		metadata := &genai.GroundingMetadata{}
+		for _, source := range sources {
+			metadata.GroundingChunks = append(metadata.GroundingChunks, &genai.GroundingChunk{
+				Web: &genai.GroundingChunkWeb{URI: source.URI, Title: source.Title, Domain: source.Domain},
+			})
+		}
+		candidate.GroundingMetadata = metadata
+	}
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{candidate},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     promptTokens,
+			CandidatesTokenCount: outputTokens,
+			ThoughtsTokenCount:   thinkingTokens,
+		},
+	}
+}
+
+// addSource appends a web source unless its URI is already listed
+func addSource(sources []Source, uri, title string) []Source {
+	for _, source := range sources {
+		if source.URI == uri {
+			return sources
+		}
+	}
+	domain := ""
+	if u, err := url.Parse(uri); err == nil {
+		domain = u.Hostname()
+	}
+	if title == "" {
+		title = domain
+	}
+	return append(sources, Source{Title: title, URI: uri, Domain: domain})
+}
+
+// postJSON sends body to endpoint and decodes the JSON reply into out.
+// Error statuses are returned as genai.APIError so retries treat every
+// provider alike.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return genai.APIError{Code: resp.StatusCode, Status: resp.Status, Message: strings.TrimSpace(string(data))}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package search
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/genai"
+)
+
+// Request describes one API call, as passed to Options.OnRequest
+type Request struct {
+	// Correlation ID shared by a search and its retries and summary
+	ID      string
+	Kind    string
+	Attempt int
+	Model   string
+	// Exact user content and config sent to the API
+	Contents []*genai.Content
+	Config   *genai.GenerateContentConfig
+}
+
+// Response describes the outcome of one API call, as passed to
+// Options.OnResponse
+type Response struct {
+	ID      string
+	Kind    string
+	Attempt int
+	Model   string
+	// Text of the response, empty when the call failed
+	Text  string
+	Usage *genai.GenerateContentResponseUsageMetadata
+	Err   error
+}
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random correlation ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a context whose searches use id as their correlation ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ensureRequestID returns ctx carrying a request ID, creating one if needed
+func ensureRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestID(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewRequestID()
+	return WithRequestID(ctx, id), id
+}
+
+// SearchRequest returns the request Search would send for query, without
+// sending it
+func (c *Client) SearchRequest(query string) Request {
+	return Request{
+		Kind:     "search",
+		Attempt:  1,
+		Model:    c.opts.Model,
+		Contents: c.searchContent(query),
+		Config:   c.searchConfig(query),
+	}
+}
+
+func (c *Client) logRequest(ctx context.Context, kind string, attempt int, contents []*genai.Content, config *genai.GenerateContentConfig) {
+	if c.opts.OnRequest == nil {
+		return
+	}
+	c.opts.OnRequest(ctx, Request{
+		ID:       RequestID(ctx),
+		Kind:     kind,
+		Attempt:  attempt,
+		Model:    c.opts.Model,
+		Contents: contents,
+		Config:   config,
+	})
+}
+
+func (c *Client) logResponse(ctx context.Context, kind string, attempt int, response *genai.GenerateContentResponse, err error) {
+	if c.opts.OnResponse == nil {
+		return
+	}
+	logged := Response{
+		ID:      RequestID(ctx),
+		Kind:    kind,
+		Attempt: attempt,
+		Model:   c.opts.Model,
+		Err:     err,
+	}
+	if response != nil {
+		logged.Text = response.Text()
+		logged.Usage = response.UsageMetadata
+	}
+	c.opts.OnResponse(ctx, logged)
+}
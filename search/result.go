@@ -0,0 +1,116 @@
+package search
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Result is the outcome of a single search, plus its optional summary and
+// classification
+type Result struct {
+	ID           string        `json:"id,omitempty"`
+	Query        string        `json:"query"`
+	Response     string        `json:"response"`
+	Summary      string        `json:"summary,omitempty"`
+	Success      bool          `json:"success"`
+	Error        string        `json:"error,omitempty"`
+	ErrorCode    ErrorCode     `json:"error_code,omitempty"`
+	Duration     time.Duration `json:"duration"`
+	Timestamp    time.Time     `json:"timestamp"`
+	PromptTokens int32         `json:"prompt_tokens,omitempty"`
+	// Prompt tokens read from a context cache, included in PromptTokens
+	CachedTokens   int32    `json:"cached_tokens,omitempty"`
+	OutputTokens   int32    `json:"output_tokens,omitempty"`
+	ThinkingTokens int32    `json:"thinking_tokens,omitempty"`
+	CostUSD        float64  `json:"cost_usd,omitempty"`
+	Timings        Timings  `json:"timings"`
+	Sources        []Source `json:"sources,omitempty"`
+	Cached         bool     `json:"cached,omitempty"`
+	// Follow-up requests that completed a response cut off at the output
+	// token limit
+	Continuations int `json:"continuations,omitempty"`
+	// Calls the model made to Options.LocalTools
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Reasoning summaries, only requested with Options.IncludeThoughts
+	Thoughts string `json:"thoughts,omitempty"`
+
+	// JSON matching the schema passed to AddStructured
+	Structured json.RawMessage `json:"structured,omitempty"`
+
+	CitationWarnings []string `json:"citation_warnings,omitempty"`
+	SummaryFallback  bool     `json:"summary_fallback,omitempty"`
+
+	// The rephrased query that produced the response, with Options.AutoRewrite
+	RewrittenQuery string `json:"rewritten_query,omitempty"`
+
+	// Labels given with the query, carried through to the output
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// How the query was routed to the model that answered it, when models
+	// are chosen by complexity
+	Routing *Routing `json:"routing,omitempty"`
+
+	Category           string  `json:"category,omitempty"`
+	CategoryConfidence float64 `json:"category_confidence,omitempty"`
+
+	// Sub-question searches a Deep report was written from
+	Steps []Result `json:"steps,omitempty"`
+
+	// Candidate answers from Samples, the 1-based number of the one chosen
+	// (0 when the answer merges them), and why
+	Samples         []Result `json:"samples,omitempty"`
+	SelectedSample  int      `json:"selected_sample,omitempty"`
+	SelectionReason string   `json:"selection_reason,omitempty"`
+
+	// Claim-by-claim fact check, with AddVerification
+	Verification *Verification `json:"verification,omitempty"`
+
+	// How far the answer can be trusted, from 0 to 1, and the points it may
+	// be wrong about, with AddConfidence
+	Confidence *float64 `json:"confidence,omitempty"`
+	Caveats    []string `json:"caveats,omitempty"`
+
+	// Follow-up queries proposed by AddSuggestions
+	SuggestedQueries []string `json:"suggested_queries,omitempty"`
+
+	// Excerpts of Options.Notes sent with the query
+	Notes []NoteMatch `json:"notes,omitempty"`
+}
+
+// Timings breaks a query's duration down by phase
+type Timings struct {
+	Construction time.Duration `json:"construction"`
+	Generation   time.Duration `json:"generation"`
+	Summary      time.Duration `json:"summary,omitempty"`
+}
+
+func (r *Result) setUsage(model string, usage *genai.GenerateContentResponseUsageMetadata) {
+	if usage == nil {
+		return
+	}
+	r.PromptTokens = usage.PromptTokenCount
+	r.CachedTokens = usage.CachedContentTokenCount
+	r.OutputTokens = usage.CandidatesTokenCount
+	r.ThinkingTokens = usage.ThoughtsTokenCount
+	// Prompt tokens include the cached ones, which cost less
+	r.CostUSD, _ = EstimateCost(model, r.PromptTokens-r.CachedTokens, r.OutputTokens, r.ThinkingTokens)
+	r.CostUSD += estimateCachedCost(model, r.CachedTokens)
+}
+
+// addUsage adds the tokens and cost of other to r
+func (r *Result) addUsage(other *Result) {
+	r.PromptTokens += other.PromptTokens
+	r.CachedTokens += other.CachedTokens
+	r.OutputTokens += other.OutputTokens
+	r.ThinkingTokens += other.ThinkingTokens
+	r.CostUSD += other.CostUSD
+}
+
+// addResponseUsage adds the usage of one more call to r
+func (r *Result) addResponseUsage(model string, usage *genai.GenerateContentResponseUsageMetadata) {
+	var call Result
+	call.setUsage(model, usage)
+	r.addUsage(&call)
+}
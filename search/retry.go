@@ -0,0 +1,155 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+var errEmptyResponse = errors.New("empty response")
+
+// RetryPolicy controls how many times an API call is attempted and how long
+// to wait between attempts. Delays grow exponentially from BaseDelay, capped
+// at MaxDelay, with up to Jitter added as a fraction of the delay.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Jitter    float64
+	// Sleep waits between attempts, sleepContext if nil. Tests can swap in
+	// a fake to record delays without waiting.
+	Sleep func(context.Context, time.Duration) error
+	// Random returns values in [0, 1) for jitter, math/rand if nil
+	Random func() float64
+	// Shared limit on retries across calls, unlimited if nil
+	Budget *RetryBudget
+}
+
+// DefaultRetryPolicy tries twice, waiting about 3 seconds between attempts
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:  2,
+		BaseDelay: 3 * time.Second,
+		MaxDelay:  30 * time.Second,
+		Jitter:    0.2,
+	}
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// delay returns the wait before the given retry (1 for the first retry).
+// Jitter only lengthens the wait, and MaxDelay caps the result.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+
+	if p.Jitter > 0 {
+		random := p.Random
+		if random == nil {
+			random = rand.Float64
+		}
+		d += time.Duration(random() * p.Jitter * float64(d))
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// Backoff returns how long do waits in total when every attempt fails,
+// leaving out jitter
+func (p RetryPolicy) Backoff() time.Duration {
+	p.Jitter = 0
+	var total time.Duration
+	for retry := 1; retry < p.Attempts; retry++ {
+		total += p.delay(retry)
+	}
+	return total
+}
+
+// isRetryable reports whether a failed call is worth repeating: rate limits
+// and server errors (429, 5xx), empty responses, and network failures.
+// Other API errors such as bad requests or auth failures fail immediately.
+func isRetryable(err error) bool {
+	if errors.Is(err, errEmptyResponse) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// attemptTimedOut reports whether err is the HTTP client timing out a single
+// request, which matches context.DeadlineExceeded while ctx itself is fine
+func attemptTimedOut(ctx context.Context, err error) bool {
+	var netErr net.Error
+	return ctx.Err() == nil && errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// do runs fn until it succeeds, fails with an error that is not retryable,
+// or the attempts are used up. onRetry, if
+// non-nil, is called before each retry with the upcoming attempt number and
+// the delay that will be waited. The last error from fn is returned.
+func (p RetryPolicy) do(ctx context.Context, fn func(attempt int) error, onRetry func(attempt int, delay time.Duration)) error {
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	if err := p.Budget.check(); err != nil {
+		return err
+	}
+
+	var err error
+	for attempt := 1; attempt <= p.Attempts; attempt++ {
+		if err = fn(attempt); err == nil {
+			return nil
+		}
+
+		retryable := isRetryable(err) || attemptTimedOut(ctx, err)
+		slog.InfoContext(ctx, "API call attempt failed", "attempt", attempt, "attempts", p.Attempts, "retryable", retryable, "error", err)
+		if !retryable || attempt == p.Attempts {
+			break
+		}
+		if !p.Budget.take() {
+			return fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+		}
+
+		delay := p.delay(attempt)
+		recordRetry(ctx, attempt+1, err)
+		if onRetry != nil {
+			onRetry(attempt+1, delay)
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,244 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// fakeSleep records the delays it is asked to wait without waiting
+type fakeSleep struct {
+	delays []time.Duration
+	// Called after each recorded delay, if set
+	after func()
+}
+
+func (f *fakeSleep) sleep(ctx context.Context, d time.Duration) error {
+	f.delays = append(f.delays, d)
+	if f.after != nil {
+		f.after()
+	}
+	return ctx.Err()
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		random float64
+		want   []time.Duration
+	}{
+		{
+			name:   "doubles per attempt",
+			policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:   "clamped at max delay",
+			policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:   "no max delay",
+			policy: RetryPolicy{BaseDelay: time.Second},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:   "least jitter",
+			policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5},
+			random: 0,
+			want:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:   "partial jitter",
+			policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5},
+			random: 0.75,
+			want:   []time.Duration{1375 * time.Millisecond, 2750 * time.Millisecond},
+		},
+		{
+			name:   "jitter clamped at max delay",
+			policy: RetryPolicy{BaseDelay: 4 * time.Second, MaxDelay: 5 * time.Second, Jitter: 0.5},
+			random: 0.999,
+			want:   []time.Duration{5 * time.Second, 5 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.Random = func() float64 { return tt.random }
+			var got []time.Duration
+			for retry := 1; retry <= len(tt.want); retry++ {
+				got = append(got, tt.policy.delay(retry))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("delays = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryJitterBounds(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.2}
+	for _, r := range []float64{0, 0.1, 0.25, 0.5, 0.75, 0.9, 0.9999} {
+		policy.Random = func() float64 { return r }
+		for retry := 1; retry <= 4; retry++ {
+			base := time.Second << (retry - 1)
+			ceiling := base + time.Duration(policy.Jitter*float64(base))
+			if d := policy.delay(retry); d < base || d > ceiling {
+				t.Errorf("random %v, retry %d: delay %s outside [%s, %s]", r, retry, d, base, ceiling)
+			}
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 0},
+		{attempts: 2, want: time.Second},
+		{attempts: 4, want: 7 * time.Second},
+		// 1+2+4+5+5 once the cap is reached
+		{attempts: 6, want: 17 * time.Second},
+	}
+	for _, tt := range tests {
+		policy := RetryPolicy{Attempts: tt.attempts, BaseDelay: time.Second, MaxDelay: 5 * time.Second, Jitter: 0.5}
+		if got := policy.Backoff(); got != tt.want {
+			t.Errorf("attempts %d: backoff = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// timeoutError is how the HTTP client reports an attempt that ran out of time
+type timeoutError struct{}
+
+func (timeoutError) Error() string     { return "Client.Timeout exceeded" }
+func (timeoutError) Timeout() bool     { return true }
+func (timeoutError) Temporary() bool   { return true }
+func (timeoutError) Is(err error) bool { return err == context.DeadlineExceeded }
+
+func TestRetryDo(t *testing.T) {
+	badRequest := genai.APIError{Code: 400, Message: "bad request"}
+	rateLimited := genai.APIError{Code: 429, Message: "rate limited"}
+
+	tests := []struct {
+		name string
+		// Errors returned by successive attempts, nil for success
+		errs       []error
+		attempts   int
+		wantCalls  int
+		wantDelays []time.Duration
+		// Message of the returned error, empty for success
+		wantErr string
+	}{
+		{name: "first attempt succeeds", errs: []error{nil}, attempts: 3, wantCalls: 1},
+		{
+			name:       "retries until success",
+			errs:       []error{errEmptyResponse, rateLimited, nil},
+			attempts:   3,
+			wantCalls:  3,
+			wantDelays: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:       "attempts used up",
+			errs:       []error{errEmptyResponse, errEmptyResponse, errEmptyResponse},
+			attempts:   3,
+			wantCalls:  3,
+			wantDelays: []time.Duration{time.Second, 2 * time.Second},
+			wantErr:    "empty response",
+		},
+		{name: "bad request", errs: []error{badRequest}, attempts: 3, wantCalls: 1, wantErr: badRequest.Error()},
+		{name: "plain error", errs: []error{errors.New("boom")}, attempts: 3, wantCalls: 1, wantErr: "boom"},
+		{name: "cancelled call", errs: []error{fmt.Errorf("search: %w", context.Canceled)}, attempts: 3, wantCalls: 1, wantErr: "search: context canceled"},
+		{
+			name:       "attempt timed out",
+			errs:       []error{fmt.Errorf("doRequest: %w", timeoutError{}), nil},
+			attempts:   3,
+			wantCalls:  2,
+			wantDelays: []time.Duration{time.Second},
+		},
+		{name: "retryable after a retry", errs: []error{rateLimited, badRequest}, attempts: 3, wantCalls: 2, wantDelays: []time.Duration{time.Second}, wantErr: badRequest.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeSleep{}
+			policy := RetryPolicy{Attempts: tt.attempts, BaseDelay: time.Second, MaxDelay: time.Minute, Sleep: fake.sleep}
+
+			calls := 0
+			var retries []int
+			err := policy.do(context.Background(), func(attempt int) error {
+				calls++
+				if attempt != calls {
+					t.Errorf("attempt = %d, want %d", attempt, calls)
+				}
+				return tt.errs[attempt-1]
+			}, func(attempt int, delay time.Duration) {
+				retries = append(retries, attempt)
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !slices.Equal(fake.delays, tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", fake.delays, tt.wantDelays)
+			}
+			if len(retries) != len(tt.wantDelays) {
+				t.Errorf("onRetry called for attempts %v, want %d calls", retries, len(tt.wantDelays))
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("err = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("err = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The context is cancelled while waiting before the second attempt
+	fake := &fakeSleep{after: cancel}
+	policy := RetryPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute, Sleep: fake.sleep}
+
+	calls := 0
+	err := policy.do(ctx, func(int) error {
+		calls++
+		return errEmptyResponse
+	}, nil)
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if len(fake.delays) != 1 {
+		t.Errorf("delays = %v, want one", fake.delays)
+	}
+	if !errors.Is(err, errEmptyResponse) {
+		t.Errorf("err = %v, want the last error from the call", err)
+	}
+}
+
+func TestRetryBudgetStopsRetries(t *testing.T) {
+	fake := &fakeSleep{}
+	policy := RetryPolicy{Attempts: 5, BaseDelay: time.Second, Sleep: fake.sleep, Budget: NewRetryBudget(1)}
+
+	calls := 0
+	err := policy.do(context.Background(), func(int) error {
+		calls++
+		return errEmptyResponse
+	}, nil)
+
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("err = %v, want %v", err, ErrRetryBudgetExhausted)
+	}
+}
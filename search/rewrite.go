@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/rewrite.txt
+var rewriteInstructionText string
+
+// rewriteQuery asks the model for a rephrasing of a query that came back empty
+func (c *Client) rewriteQuery(ctx context.Context, query string) (string, *genai.GenerateContentResponseUsageMetadata, error) {
+	content := []*genai.Content{{
+		Role:  "user",
+		Parts: []*genai.Part{{Text: "Query: " + query}},
+	}}
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: rewriteInstructionText}}},
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	response, err := c.generate(ctx, "rewrite", content, genConfig)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to rewrite query: %w", err)
+	}
+	rewritten, _, _ := strings.Cut(strings.TrimSpace(response.Text()), "\n")
+	rewritten = strings.Trim(strings.TrimSpace(rewritten), `"`)
+	if rewritten == "" || rewritten == query {
+		return "", nil, fmt.Errorf("rewrite did not change the query")
+	}
+	return rewritten, response.UsageMetadata, nil
+}
+
+// withRewrite retries an empty search once with a rewritten query when
+// Options.AutoRewrite is set. run performs the retry on a client that does
+// not rewrite again. The returned result keeps the original query and
+// records the rewrite; ok is false when no retry was made.
+func (c *Client) withRewrite(ctx context.Context, query string, run func(*Client, string) (*Result, error)) (result *Result, ok bool, err error) {
+	if !c.opts.AutoRewrite {
+		return nil, false, nil
+	}
+	rewritten, usage, rewriteErr := c.rewriteQuery(ctx, query)
+	if rewriteErr != nil {
+		slog.InfoContext(ctx, "Query rewrite failed", "query", query, "error", rewriteErr)
+		return nil, false, nil
+	}
+	slog.InfoContext(ctx, "Retrying empty search with rewritten query", "query", query, "rewritten", rewritten)
+
+	retry := *c
+	retry.opts.AutoRewrite = false
+	result, err = run(&retry, rewritten)
+	result.Query = query
+	result.RewrittenQuery = rewritten
+	result.addResponseUsage(c.opts.Model, usage)
+	return result, true, err
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/route.txt
+var routeInstructionText string
+
+// Query complexities used to route queries between models
+const (
+	ComplexitySimple  = "simple"
+	ComplexityComplex = "complex"
+)
+
+// Ways a query's complexity was rated
+const (
+	RouteByModel     = "model"
+	RouteByHeuristic = "heuristic"
+)
+
+// Routing records how a query was rated and the model chosen to answer it
+type Routing struct {
+	Model      string `json:"model"`
+	Complexity string `json:"complexity"`
+	Method     string `json:"method"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+var complexitySchema = &genai.Schema{
+	Type: genai.TypeArray,
+	Items: &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"index":      {Type: genai.TypeInteger},
+			"complexity": {Type: genai.TypeString, Enum: []string{ComplexitySimple, ComplexityComplex}},
+			"reason":     {Type: genai.TypeString},
+		},
+		Required: []string{"index", "complexity", "reason"},
+	},
+}
+
+// RateComplexity rates all queries simple or complex with a single model
+// call, with thinking disabled to keep it cheap. The returned slice is
+// indexed like queries; queries the model skipped are rated by
+// HeuristicComplexity. Model is left for the caller to choose.
+func (c *Client) RateComplexity(ctx context.Context, queries []string) ([]Routing, error) {
+	var b strings.Builder
+	for i, query := range queries {
+		fmt.Fprintf(&b, "%d. %s\n", i, query)
+	}
+	content := []*genai.Content{{
+		Role:  "user",
+		Parts: []*genai.Part{{Text: b.String()}},
+	}}
+
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: routeInstructionText}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    complexitySchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	var response *genai.GenerateContentResponse
+	err := c.opts.Retry.do(ctx, func(attempt int) error {
+		c.logRequest(ctx, "route", attempt, content, genConfig)
+
+		var err error
+		response, err = c.provider.GenerateContent(ctx, c.opts.Model, content, genConfig)
+		c.logResponse(ctx, "route", attempt, response, err)
+		if err != nil {
+			return err
+		}
+		if response.Text() == "" {
+			return errEmptyResponse
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rate query complexity: %w", err)
+	}
+
+	var ratings []struct {
+		Index      int    `json:"index"`
+		Complexity string `json:"complexity"`
+		Reason     string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(response.Text()), &ratings); err != nil {
+		return nil, fmt.Errorf("failed to parse complexity ratings: %w", err)
+	}
+
+	routes := make([]Routing, len(queries))
+	for _, rating := range ratings {
+		if rating.Index >= 0 && rating.Index < len(queries) && (rating.Complexity == ComplexitySimple || rating.Complexity == ComplexityComplex) {
+			routes[rating.Index] = Routing{Complexity: rating.Complexity, Method: RouteByModel, Reason: rating.Reason}
+		}
+	}
+	for i, route := range routes {
+		if route.Complexity == "" {
+			routes[i] = HeuristicComplexity(queries[i])
+		}
+	}
+	return routes, nil
+}
+
+// Wording that asks for analysis rather than a lookup
+var complexPattern = regexp.MustCompile(`(?i)\b(why|compare|comparison|versus|vs\.?|trade-?offs?|pros and cons|differences? between|analy[sz]e|analysis|evaluate|implications?|impact of|strategy|architecture|design|explain how|how does|should i|best way)\b`)
+
+// Queries longer than this many words are rated complex
+const complexQueryWords = 25
+
+// HeuristicComplexity rates a query by its length and wording, without a
+// model call
+func HeuristicComplexity(query string) Routing {
+	route := Routing{Complexity: ComplexitySimple, Method: RouteByHeuristic}
+	switch words := len(strings.Fields(query)); {
+	case words > complexQueryWords:
+		route.Complexity = ComplexityComplex
+		route.Reason = fmt.Sprintf("%d words", words)
+	case strings.Count(query, "?") > 1:
+		route.Complexity = ComplexityComplex
+		route.Reason = "several questions"
+	default:
+		if match := complexPattern.FindString(query); match != "" {
+			route.Complexity = ComplexityComplex
+			route.Reason = fmt.Sprintf("asks %q", strings.ToLower(match))
+		}
+	}
+	return route
+}
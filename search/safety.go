@@ -0,0 +1,148 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Safety presets accepted by SafetySettings
+const (
+	SafetyDefault   = "default"
+	SafetyBlockNone = "block_none"
+	SafetyStrict    = "strict"
+)
+
+// SafetyCategories maps the short category names used in flags and the
+// config file to Gemini harm categories
+var SafetyCategories = map[string]genai.HarmCategory{
+	"harassment":        genai.HarmCategoryHarassment,
+	"hate_speech":       genai.HarmCategoryHateSpeech,
+	"sexually_explicit": genai.HarmCategorySexuallyExplicit,
+	"dangerous_content": genai.HarmCategoryDangerousContent,
+	"civic_integrity":   genai.HarmCategoryCivicIntegrity,
+}
+
+// SafetyThresholds maps short threshold names to Gemini block thresholds
+var SafetyThresholds = map[string]genai.HarmBlockThreshold{
+	"block_none":             genai.HarmBlockThresholdBlockNone,
+	"block_only_high":        genai.HarmBlockThresholdBlockOnlyHigh,
+	"block_medium_and_above": genai.HarmBlockThresholdBlockMediumAndAbove,
+	"block_low_and_above":    genai.HarmBlockThresholdBlockLowAndAbove,
+	"off":                    genai.HarmBlockThresholdOff,
+}
+
+// SafetySettings builds the settings for a preset, with overrides mapping
+// category names to threshold names on top. The default preset leaves
+// categories without an override to the API's defaults.
+func SafetySettings(preset string, overrides map[string]string) ([]*genai.SafetySetting, error) {
+	thresholds := map[string]string{}
+	switch preset {
+	case SafetyDefault, "":
+	case SafetyBlockNone, SafetyStrict:
+		threshold := "block_none"
+		if preset == SafetyStrict {
+			threshold = "block_low_and_above"
+		}
+		for name := range SafetyCategories {
+			thresholds[name] = threshold
+		}
+	default:
+		return nil, fmt.Errorf("unknown safety preset %q (known: %s, %s, %s)", preset, SafetyBlockNone, SafetyDefault, SafetyStrict)
+	}
+	for name, threshold := range overrides {
+		if _, ok := SafetyCategories[name]; !ok {
+			return nil, fmt.Errorf("unknown safety category %q (known: %s)", name, strings.Join(sortedNames(SafetyCategories), ", "))
+		}
+		if _, ok := SafetyThresholds[threshold]; !ok {
+			return nil, fmt.Errorf("unknown safety threshold %q (known: %s)", threshold, strings.Join(sortedNames(SafetyThresholds), ", "))
+		}
+		thresholds[name] = threshold
+	}
+
+	var settings []*genai.SafetySetting
+	for _, name := range sortedNames(thresholds) {
+		settings = append(settings, &genai.SafetySetting{
+			Category:  SafetyCategories[name],
+			Threshold: SafetyThresholds[thresholds[name]],
+		})
+	}
+	return settings, nil
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// safetyKey identifies the safety settings in cache keys
+func (c *Client) safetyKey() string {
+	parts := make([]string, len(c.opts.SafetySettings))
+	for i, setting := range c.opts.SafetySettings {
+		parts[i] = fmt.Sprintf("%s=%s", setting.Category, setting.Threshold)
+	}
+	return strings.Join(parts, ",")
+}
+
+// BlockedError reports a response withheld by safety filters
+type BlockedError struct {
+	// Set when the prompt itself was rejected rather than the answer
+	Prompt bool
+	// The block or finish reason, such as SAFETY or PROHIBITED_CONTENT
+	Reason string
+	// Short names of the categories that triggered the block, where known
+	Categories []string
+}
+
+func (e *BlockedError) Error() string {
+	subject := "response"
+	if e.Prompt {
+		subject = "prompt"
+	}
+	if len(e.Categories) == 0 {
+		return fmt.Sprintf("%s blocked by safety filters (%s)", subject, e.Reason)
+	}
+	return fmt.Sprintf("%s blocked by safety filters: %s", subject, strings.Join(e.Categories, ", "))
+}
+
+// message is the Result.Error for a blocked search
+func (e *BlockedError) message() string {
+	if len(e.Categories) == 0 {
+		return fmt.Sprintf("Blocked by safety filter (%s)", e.Reason)
+	}
+	return "Blocked by safety filter: " + strings.Join(e.Categories, ", ")
+}
+
+// blockedBy returns a BlockedError if response was withheld, or nil
+func blockedBy(response *genai.GenerateContentResponse) *BlockedError {
+	if feedback := response.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return &BlockedError{Prompt: true, Reason: string(feedback.BlockReason), Categories: blockedCategories(feedback.SafetyRatings)}
+	}
+	if len(response.Candidates) == 0 {
+		return nil
+	}
+	candidate := response.Candidates[0]
+	switch candidate.FinishReason {
+	case genai.FinishReasonSafety, genai.FinishReasonProhibitedContent, genai.FinishReasonBlocklist, genai.FinishReasonSPII:
+		return &BlockedError{Reason: string(candidate.FinishReason), Categories: blockedCategories(candidate.SafetyRatings)}
+	}
+	return nil
+}
+
+func blockedCategories(ratings []*genai.SafetyRating) []string {
+	var names []string
+	for _, rating := range ratings {
+		if !rating.Blocked {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(string(rating.Category), "HARM_CATEGORY_"))
+		names = append(names, name)
+	}
+	return names
+}
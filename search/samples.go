@@ -0,0 +1,142 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/select.txt
+var selectInstructionText string
+
+var selectSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"choice": {Type: genai.TypeInteger, Description: "Number of the best candidate, or 0 for a merged answer"},
+		"answer": {Type: genai.TypeString, Description: "The merged answer when choice is 0, otherwise empty"},
+		"reason": {Type: genai.TypeString},
+	},
+	Required: []string{"choice", "reason"},
+}
+
+// Samples searches query n times concurrently, with at most workers in
+// flight, and has the model choose the best answer or merge them. The
+// returned result holds that answer and, in Samples, every candidate.
+// Token counts and cost cover every call.
+func (c *Client) Samples(ctx context.Context, query string, n, workers int) (*Result, error) {
+	ctx, id := ensureRequestID(ctx)
+	startTime := time.Now()
+	result := &Result{ID: id, Query: query, Timestamp: startTime}
+
+	// A cached answer would come back n times
+	uncached := *c
+	uncached.opts.Cache = nil
+	queries := make([]string, n)
+	for i := range queries {
+		queries[i] = query
+	}
+	result.Samples = uncached.searchAll(ctx, queries, workers, func(int, *Result) {})
+
+	var candidates []int
+	for i, sample := range result.Samples {
+		result.addUsage(&sample)
+		if sample.Success {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		result.Duration = time.Since(startTime)
+		err := result.fail("Every sample failed", fmt.Errorf("all %d samples failed", n))
+		result.ErrorCode = result.Samples[0].ErrorCode
+		return result, err
+	}
+
+	if len(candidates) == 1 {
+		result.useSample(candidates[0])
+		result.SelectionReason = "only one sample succeeded"
+	} else if err := c.selectSample(ctx, query, candidates, result); err != nil {
+		// Any successful sample beats failing the query
+		slog.ErrorContext(ctx, "Answer selection failed, using the first sample", "error", err)
+		result.useSample(candidates[0])
+		result.SelectionReason = "selection failed, first successful sample used"
+	}
+	result.Duration = time.Since(startTime)
+	result.Timings.Generation = result.Duration
+	result.Success = true
+	return result, nil
+}
+
+// useSample copies the answer of sample i into the result
+func (r *Result) useSample(i int) {
+	sample := r.Samples[i]
+	r.SelectedSample = i + 1
+	r.Response = sample.Response
+	r.Sources = sample.Sources
+	r.Thoughts = sample.Thoughts
+	r.CitationWarnings = sample.CitationWarnings
+}
+
+// selectSample asks the model for the best of the successful samples at
+// candidates, or for a merge of them
+func (c *Client) selectSample(ctx context.Context, query string, candidates []int, result *Result) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Question: %s\n\n", query)
+	for n, i := range candidates {
+		sample := result.Samples[i]
+		fmt.Fprintf(&b, "## Candidate %d\n\n%s\n\n", n+1, strings.TrimSpace(sample.Response))
+		for _, source := range sample.Sources {
+			fmt.Fprintf(&b, "- %s - %s\n", source.Title, source.URI)
+		}
+		b.WriteString("\n")
+	}
+	content := []*genai.Content{{Role: "user", Parts: []*genai.Part{{Text: b.String()}}}}
+
+	budget := c.opts.ThinkingBudget
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: c.withLanguage(selectInstructionText)}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    selectSchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &budget,
+		},
+	}
+
+	response, err := c.generate(ctx, "select", content, genConfig)
+	if err != nil {
+		return fmt.Errorf("failed to select an answer: %w", err)
+	}
+	result.addResponseUsage(c.opts.Model, response.UsageMetadata)
+
+	var selection struct {
+		Choice int    `json:"choice"`
+		Answer string `json:"answer"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(response.Text()), &selection); err != nil {
+		return fmt.Errorf("answer selection is not valid JSON: %w", err)
+	}
+	switch {
+	case selection.Choice >= 1 && selection.Choice <= len(candidates):
+		result.useSample(candidates[selection.Choice-1])
+	case selection.Choice == 0 && strings.TrimSpace(selection.Answer) != "":
+		// A merge draws on every candidate's sources
+		result.Response = strings.TrimSpace(selection.Answer)
+		for _, i := range candidates {
+			for _, source := range result.Samples[i].Sources {
+				result.Sources = addSource(result.Sources, source.URI, source.Title)
+			}
+		}
+	default:
+		return fmt.Errorf("answer selection chose candidate %d of %d", selection.Choice, len(candidates))
+	}
+	result.SelectionReason = strings.TrimSpace(selection.Reason)
+	slog.InfoContext(ctx, "Selected answer", "query", query, "sample", result.SelectedSample, "reason", result.SelectionReason)
+	return nil
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// ValidateSchema checks a decoded JSON value against a decoded JSON Schema.
+// It covers the keywords used for structured output: type, properties,
+// required, items, and enum. Other keywords are ignored.
+func ValidateSchema(schema, value any) error {
+	return validateSchema(schema, value, "$")
+}
+
+func validateSchema(schema, value any, path string) error {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		// true, or a schema we do not understand, accepts anything
+		return nil
+	}
+
+	if types := schemaTypes(s["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonType(value))
+		}
+	}
+
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of the allowed values", path, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if required, ok := s["required"].([]any); ok {
+			for _, name := range required {
+				key, _ := name.(string)
+				if _, present := v[key]; !present {
+					return fmt.Errorf("%s: missing required property %q", path, key)
+				}
+			}
+		}
+		if properties, ok := s["properties"].(map[string]any); ok {
+			for key, property := range properties {
+				if field, present := v[key]; present {
+					if err := validateSchema(property, field, path+"."+key); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	case []any:
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(t string, value any) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == t
+	}
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
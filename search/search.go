@@ -0,0 +1,710 @@
+// Package search runs grounded web searches and summaries against the
+// Gemini API. It backs the search CLI and can be embedded in other programs.
+package search
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/system.txt
+var systemInstructionText string
+
+//go:embed prompts/summary.txt
+var summaryInstructionText string
+
+//go:embed prompts/summary_fallback.txt
+var summaryFallbackInstructionText string
+
+// DefaultModel is used when Options.Model is empty
+const DefaultModel = "gemini-2.5-flash"
+
+// KnownModels lists the models the CLI accepts without -model-allow-any
+var KnownModels = []string{
+	"gemini-2.5-flash",
+	"gemini-2.5-pro",
+	"gemini-2.5-flash-lite",
+	"gemini-2.0-flash",
+	"gemini-2.0-flash-lite",
+}
+
+// IsKnownModel reports whether name is in KnownModels
+func IsKnownModel(name string) bool {
+	for _, known := range KnownModels {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultThinkingBudget is the thinking budget set by DefaultOptions
+const DefaultThinkingBudget int32 = 512
+
+// Options configures a Client
+type Options struct {
+	// Model name, the provider's default from ProviderDefaultModels if empty
+	Model string
+	// Thinking budget in tokens, 0 disables thinking and -1 lets the model decide
+	ThinkingBudget int32
+	// Ask for summaries of the model's reasoning, returned in Result.Thoughts
+	// and streamed as EventThought
+	IncludeThoughts bool
+	// Ask for numbered footnote citations and verify them against grounding metadata
+	InlineCitations bool
+	// Retry a failed summary once with a simpler prompt and shorter input
+	SummaryFallback bool
+	// Retry behavior for every API call, DefaultRetryPolicy if Attempts is 0
+	Retry RetryPolicy
+	// HTTP client used for API requests, the genai default if nil
+	HTTPClient *http.Client
+	// Model backend, a GeminiProvider using HTTPClient if nil
+	Provider Provider
+	// Replaces the embedded search system prompt. Both prompts are Go
+	// templates that can use the fields of PromptData, such as {{.Date}}.
+	SystemPrompt string
+	// Replaces the embedded summary prompt
+	SummaryPrompt string
+	// Called before every API request, for logging or auditing
+	OnRequest func(ctx context.Context, req Request)
+	// Called after every API request with what came back
+	OnResponse func(ctx context.Context, resp Response)
+	// Retry a search that stays empty after retries once more, with the
+	// query rephrased by the model
+	AutoRewrite bool
+	// Language code or name the answers and summaries are written in, such
+	// as "es"; the model's choice if empty
+	Language string
+	// Gemini safety thresholds for every request, the API defaults if empty.
+	// See SafetySettings.
+	SafetySettings []*genai.SafetySetting
+	// Attached to every search query, for questions about the pictures
+	Images []Image
+	// Attached to every search query as context. Large documents are
+	// uploaded by NewClient and referenced by URI.
+	Documents []Document
+	// Answer style, one of Styles; the system prompt's default if empty
+	Style string
+	// Word limit the answer is asked to stay under, also capping its output
+	// tokens; 0 for no limit
+	MaxWords int
+	// Domains searches should use or avoid. Sources outside the filter are
+	// dropped from results.
+	Sites SiteFilter
+	// Limit for each search or summary including its retries, 0 for none
+	QueryTimeout time.Duration
+	// Serves repeated searches without calling the API, nil disables caching.
+	// Conversation turns with history are never cached.
+	Cache Cache
+	// Keep the search system prompt and attachments in a Gemini context
+	// cache for this long, so each search sends only the query; 0 disables
+	ContextCacheTTL time.Duration
+	// Follow-up requests allowed when a response is cut off at the output
+	// token limit, each asking the model to continue; 0 disables
+	MaxContinuations int
+	// Tools the model may call, from Tools; empty answers from the model's
+	// own knowledge
+	Tools []string
+	// Functions the model may call during a search, such as local commands
+	LocalTools []LocalTool
+	// Local notes from LoadNotes, chunked and embedded by NewClient. The
+	// chunks closest to each query are sent with it.
+	Notes []Document
+	// Note chunks sent with each query, DefaultNoteChunks if 0
+	NoteChunks int
+	// Directory note embeddings are kept in between runs, none if empty
+	NoteCacheDir string
+}
+
+// DefaultOptions returns the options used by the CLI when no flags are set
+func DefaultOptions() Options {
+	return Options{
+		Model:            DefaultModel,
+		ThinkingBudget:   DefaultThinkingBudget,
+		Retry:            DefaultRetryPolicy(),
+		MaxContinuations: DefaultMaxContinuations,
+		Tools:            DefaultTools,
+	}
+}
+
+// Client performs searches and summaries with a fixed set of Options
+type Client struct {
+	provider Provider
+	opts     Options
+
+	// Parsed custom prompts, nil when the embedded ones are used
+	systemPrompt  *template.Template
+	summaryPrompt *template.Template
+
+	// Context cache searches reference instead of sending the prompt
+	contextCache string
+
+	// Embedded chunks of Options.Notes, and a hash of the notes for cache keys
+	notes    []noteChunk
+	notesKey string
+
+	// Set by WithCacheRefresh to skip cache lookups
+	refreshCache bool
+}
+
+// NewClient validates the prompts in use and creates a client for
+// opts.Provider, or for Gemini with credentials read from the environment as
+// described in the genai docs.
+func NewClient(ctx context.Context, opts Options) (*Client, error) {
+	if opts.Retry.Attempts == 0 {
+		opts.Retry = DefaultRetryPolicy()
+	}
+	if err := opts.validatePrompts(); err != nil {
+		return nil, err
+	}
+	client := &Client{opts: opts}
+	if opts.SystemPrompt != "" {
+		tmpl, err := parsePrompt("system", opts.SystemPrompt)
+		if err != nil {
+			return nil, err
+		}
+		client.systemPrompt = tmpl
+	}
+	if opts.SummaryPrompt != "" {
+		tmpl, err := parsePrompt("summary", opts.SummaryPrompt)
+		if err != nil {
+			return nil, err
+		}
+		client.summaryPrompt = tmpl
+	}
+
+	provider := opts.Provider
+	if provider == nil {
+		gemini, err := NewGeminiProvider(ctx, opts.HTTPClient)
+		if err != nil {
+			return nil, err
+		}
+		provider = gemini
+	}
+	if client.opts.Model == "" {
+		client.opts.Model = DefaultModelFor(provider.Name())
+	}
+	client.provider = provider
+	if len(opts.Documents) > 0 {
+		// Uploads set URIs, which must not leak into the caller's slice
+		client.opts.Documents = append([]Document{}, opts.Documents...)
+		if err := client.uploadDocuments(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Notes) > 0 {
+		if err := client.indexNotes(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ContextCacheTTL > 0 {
+		client.createContextCache(ctx)
+	}
+	return client, nil
+}
+
+// WithThinkingBudget returns a copy of the client that uses budget
+func (c *Client) WithThinkingBudget(budget int32) *Client {
+	clone := *c
+	clone.opts.ThinkingBudget = budget
+	return &clone
+}
+
+// WithModel returns a copy of the client that sends requests to model
+func (c *Client) WithModel(model string) *Client {
+	clone := *c
+	// Context caches belong to one model
+	if model != c.opts.Model {
+		clone.contextCache = ""
+	}
+	clone.opts.Model = model
+	return &clone
+}
+
+// WithoutCache returns a copy of the client that always calls the API
+func (c *Client) WithoutCache() *Client {
+	clone := *c
+	clone.opts.Cache = nil
+	return &clone
+}
+
+// WithCacheRefresh returns a copy of the client that always calls the API
+// but still stores the results, replacing what the cache held
+func (c *Client) WithCacheRefresh() *Client {
+	clone := *c
+	clone.refreshCache = true
+	return &clone
+}
+
+// Options returns the options the client was created with
+func (c *Client) Options() Options {
+	return c.opts
+}
+
+// validatePrompts makes sure every prompt the client will use has content,
+// so a missing or emptied prompt file fails loudly instead of silently degrading
+func (o Options) validatePrompts() error {
+	system, summary := systemInstructionText, summaryInstructionText
+	if o.SystemPrompt != "" {
+		system = o.SystemPrompt
+	}
+	if o.SummaryPrompt != "" {
+		summary = o.SummaryPrompt
+	}
+	prompts := []struct {
+		name string
+		text string
+		used bool
+	}{
+		{"system", system, true},
+		{"summary", summary, true},
+		{"summary fallback", summaryFallbackInstructionText, o.SummaryFallback},
+		{"citations", citationInstructionText, o.InlineCitations},
+		{"classify", classifyInstructionText, true},
+		{"route", routeInstructionText, true},
+		{"structure", structureInstructionText, true},
+		{"plan", planInstructionText, true},
+		{"synthesize", synthesizeInstructionText, true},
+		{"select", selectInstructionText, true},
+		{"claims", claimsInstructionText, true},
+		{"verify", verifyInstructionText, true},
+		{"confidence", confidenceInstructionText, true},
+		{"suggest", suggestInstructionText, true},
+		{"url", urlInstructionText, true},
+		{"rewrite", rewriteInstructionText, o.AutoRewrite},
+	}
+
+	for _, p := range prompts {
+		if p.used && strings.TrimSpace(p.text) == "" {
+			return fmt.Errorf("%s prompt is empty", p.name)
+		}
+	}
+	return nil
+}
+
+func (c *Client) systemInstruction(query string) *genai.Content {
+	text := c.renderPrompt(c.systemPrompt, systemInstructionText, query)
+	if c.opts.InlineCitations {
+		text += "\n\n" + citationInstructionText
+	}
+	text += c.opts.Sites.instruction()
+	text += c.toolInstruction()
+	text += c.noteInstruction()
+	text += c.styleInstruction()
+	text = c.withLanguage(text)
+	return &genai.Content{
+		Parts: []*genai.Part{{
+			Text: text,
+		}},
+	}
+}
+
+func (c *Client) searchConfig(query string) *genai.GenerateContentConfig {
+	budget := c.opts.ThinkingBudget
+	config := &genai.GenerateContentConfig{
+		SafetySettings:  c.opts.SafetySettings,
+		MaxOutputTokens: c.maxOutputTokens(),
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget:  &budget,
+			IncludeThoughts: c.opts.IncludeThoughts,
+		},
+	}
+	// The cache holds the system prompt and tools, which the API then
+	// refuses in the request
+	if c.contextCache != "" {
+		config.CachedContent = c.contextCache
+	} else {
+		config.SystemInstruction = c.systemInstruction(query)
+		config.Tools = c.tools()
+	}
+	return config
+}
+
+// thoughtText returns the reasoning parts of a response, which Text skips
+func thoughtText(response *genai.GenerateContentResponse) string {
+	if len(response.Candidates) == 0 || response.Candidates[0].Content == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		if part != nil && part.Thought {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
+
+func (c *Client) searchContent(query string) []*genai.Content {
+	isoDateString := time.Now().Format(time.DateOnly)
+	parts := []*genai.Part{
+		{Text: fmt.Sprintf(`
+<query>
+%s
+</query>
+
+Time Context: today is %s
+
+`, query, isoDateString)},
+	}
+	if c.contextCache == "" {
+		parts = append(parts, c.attachmentParts()...)
+	}
+	return []*genai.Content{{
+		Role:  "user",
+		Parts: parts,
+	}}
+}
+
+// attachmentParts returns the images and documents sent with every search
+func (c *Client) attachmentParts() []*genai.Part {
+	var parts []*genai.Part
+	for _, img := range c.opts.Images {
+		parts = append(parts, img.part())
+	}
+	for _, doc := range c.opts.Documents {
+		parts = append(parts, doc.part())
+	}
+	return parts
+}
+
+// ErrQueryTimeout is wrapped by errors from calls that outlived Options.QueryTimeout
+var ErrQueryTimeout = errors.New("query timed out")
+
+// withQueryTimeout bounds ctx by Options.QueryTimeout, if set
+func (c *Client) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opts.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, c.opts.QueryTimeout, ErrQueryTimeout)
+}
+
+// timedOut reports whether ctx ended because of Options.QueryTimeout rather
+// than its parent being cancelled
+func timedOut(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrQueryTimeout)
+}
+
+// Search runs a grounded search for query. The returned result is never nil,
+// and describes the failure when err is non-nil.
+func (c *Client) Search(ctx context.Context, query string) (*Result, error) {
+	return c.search(ctx, query, nil)
+}
+
+// search sends query after any earlier conversation turns in history
+func (c *Client) search(ctx context.Context, query string, history []*genai.Content) (result *Result, err error) {
+	ctx, id := ensureRequestID(ctx)
+	ctx, span := c.startSpan(ctx, "search")
+	defer func(start time.Time) {
+		c.endSpan(ctx, span, "search", start, resultUsage(result), err)
+	}(time.Now())
+	if len(history) == 0 {
+		if result, ok := c.cached(query, id); ok {
+			slog.InfoContext(ctx, "Using cached search result", "query", query)
+			return result, nil
+		}
+	}
+
+	startTime := time.Now()
+	result = &Result{
+		ID:        id,
+		Query:     query,
+		Timestamp: startTime,
+	}
+
+	content := append(append([]*genai.Content{}, history...), c.searchContent(query)...)
+	c.withNotes(ctx, content, query, result)
+	genConfig := c.searchConfig(query)
+	result.Timings.Construction = time.Since(startTime)
+
+	slog.InfoContext(ctx, "Performing search", "query", query)
+
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+
+	var response *genai.GenerateContentResponse
+	err = c.opts.Retry.do(ctx, func(attempt int) error {
+		c.logRequest(ctx, "search", attempt, content, genConfig)
+
+		var err error
+		response, err = c.provider.GenerateContent(ctx, c.opts.Model, content, genConfig)
+		c.logResponse(ctx, "search", attempt, response, err)
+		if err != nil {
+			return err
+		}
+		if response.Text() == "" && !c.wantsTools(response) {
+			if blocked := blockedBy(response); blocked != nil {
+				return blocked
+			}
+			return errEmptyResponse
+		}
+		return nil
+	}, func(attempt int, delay time.Duration) {
+		slog.InfoContext(ctx, "Retrying search request", "query", query, "attempt", attempt, "delay", delay.Round(time.Millisecond))
+	})
+
+	var toolCalls *Result
+	if err == nil && c.wantsTools(response) {
+		response, content, toolCalls, err = c.callTools(ctx, content, genConfig, response)
+		result.ToolCalls = toolCalls.ToolCalls
+	}
+
+	result.Duration = time.Since(startTime)
+	result.Timings.Generation = result.Duration - result.Timings.Construction
+
+	if err != nil && timedOut(ctx) {
+		return result, result.fail(fmt.Sprintf("Timed out after %s", c.opts.QueryTimeout), fmt.Errorf("%w after %s", ErrQueryTimeout, c.opts.QueryTimeout))
+	}
+
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return result, result.fail(blocked.message(), err)
+	}
+
+	if errors.Is(err, errEmptyResponse) {
+		if retried, ok, err := c.withRewrite(ctx, query, func(retry *Client, rewritten string) (*Result, error) {
+			return retry.search(ctx, rewritten, history)
+		}); ok {
+			return retried, err
+		}
+		return result, result.fail("Empty response", fmt.Errorf("received %w after retries", errEmptyResponse))
+	}
+
+	if err != nil {
+		return result, result.fail("Search failed", fmt.Errorf("failed to generate content after retries: %w", err))
+	}
+
+	result.Response = response.Text()
+	result.Thoughts = thoughtText(response)
+	result.setUsage(c.opts.Model, response.UsageMetadata)
+	if toolCalls != nil {
+		result.addUsage(toolCalls)
+	}
+	result.Sources = c.filterSources(sourcesFrom(groundingMetadata(response)))
+	if truncated(response) {
+		text, extra := c.continueTruncated(ctx, content, genConfig, result.Response, func(string) {})
+		result.Response = text
+		result.addContinuation(extra)
+	}
+	if c.opts.InlineCitations {
+		appendix, warnings := verifyCitations(result.Response, result.Sources)
+		result.Response += appendix
+		result.CitationWarnings = warnings
+	}
+	result.Success = true
+	if len(history) == 0 {
+		c.storeCached(query, result)
+	}
+	return result, nil
+}
+
+// StreamEventType identifies the kind of StreamEvent
+type StreamEventType string
+
+const (
+	// EventChunk carries the next piece of response text
+	EventChunk StreamEventType = "chunk"
+	// EventThought carries the next piece of the model's reasoning, sent
+	// before the answer when Options.IncludeThoughts is set
+	EventThought StreamEventType = "thought"
+	// EventRetry signals that the stream failed and is being restarted;
+	// text streamed so far will be sent again from the beginning
+	EventRetry StreamEventType = "retry"
+)
+
+// StreamEvent is delivered to the SearchStream callback as the response arrives
+type StreamEvent struct {
+	Type    StreamEventType
+	Text    string
+	Attempt int
+}
+
+// SearchStream runs a grounded search for query, calling onEvent (if set) for
+// every chunk as it arrives. The returned result holds the full response.
+func (c *Client) SearchStream(ctx context.Context, query string, onEvent func(StreamEvent)) (*Result, error) {
+	return c.searchStream(ctx, query, nil, onEvent)
+}
+
+// searchStream streams query after any earlier conversation turns in history
+func (c *Client) searchStream(ctx context.Context, query string, history []*genai.Content, onEvent func(StreamEvent)) (result *Result, err error) {
+	if onEvent == nil {
+		onEvent = func(StreamEvent) {}
+	}
+
+	ctx, id := ensureRequestID(ctx)
+	ctx, span := c.startSpan(ctx, "stream")
+	defer func(start time.Time) {
+		c.endSpan(ctx, span, "stream", start, resultUsage(result), err)
+	}(time.Now())
+	if len(history) == 0 {
+		if result, ok := c.cached(query, id); ok {
+			slog.InfoContext(ctx, "Using cached search result", "query", query)
+			onEvent(StreamEvent{Type: EventChunk, Text: result.Response, Attempt: 1})
+			return result, nil
+		}
+	}
+
+	startTime := time.Now()
+	result = &Result{
+		ID:        id,
+		Query:     query,
+		Timestamp: startTime,
+	}
+
+	content := append(append([]*genai.Content{}, history...), c.searchContent(query)...)
+	c.withNotes(ctx, content, query, result)
+	genConfig := c.searchConfig(query)
+	result.Timings.Construction = time.Since(startTime)
+
+	slog.InfoContext(ctx, "Performing search", "query", query)
+
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+
+	var responseText, thoughts string
+	var usage *genai.GenerateContentResponseUsageMetadata
+	var grounding *genai.GroundingMetadata
+	var cutOff bool
+	var calls []*genai.Part
+
+	err = c.opts.Retry.do(ctx, func(attempt int) (err error) {
+		responseText = ""
+		thoughts = ""
+		grounding = nil
+		cutOff = false
+		calls = nil
+		var blocked *BlockedError
+
+		c.logRequest(ctx, "stream", attempt, content, genConfig)
+		defer func() {
+			c.logResponse(ctx, "stream", attempt, &genai.GenerateContentResponse{
+				Candidates:    []*genai.Candidate{{Content: genai.NewContentFromText(responseText, genai.RoleModel)}},
+				UsageMetadata: usage,
+			}, err)
+		}()
+		iterator := c.provider.GenerateContentStream(ctx, c.opts.Model, content, genConfig)
+
+		for response, err := range iterator {
+			if err != nil {
+				return err
+			}
+
+			if thought := thoughtText(response); thought != "" {
+				thoughts += thought
+				onEvent(StreamEvent{Type: EventThought, Text: thought, Attempt: attempt})
+			}
+			if len(response.Candidates) > 0 {
+				chunk := response.Text()
+				responseText += chunk
+				slog.DebugContext(ctx, "Received stream chunk", "attempt", attempt, "bytes", len(chunk))
+				onEvent(StreamEvent{Type: EventChunk, Text: chunk, Attempt: attempt})
+				if c.wantsTools(response) {
+					calls = append(calls, functionCallParts(response)...)
+				}
+			}
+			if response.UsageMetadata != nil {
+				usage = response.UsageMetadata
+			}
+			if metadata := groundingMetadata(response); metadata != nil {
+				grounding = metadata
+			}
+			if reason := blockedBy(response); reason != nil {
+				blocked = reason
+			}
+			if truncated(response) {
+				cutOff = true
+			}
+		}
+
+		if responseText == "" && blocked != nil {
+			return blocked
+		}
+		if responseText == "" && len(calls) == 0 {
+			return errEmptyResponse
+		}
+		return nil
+	}, func(attempt int, delay time.Duration) {
+		slog.InfoContext(ctx, "Retrying stream search request", "query", query, "attempt", attempt, "delay", delay.Round(time.Millisecond))
+		onEvent(StreamEvent{Type: EventRetry, Attempt: attempt})
+	})
+
+	// The answer after tool calls arrives in one piece
+	toolCalls := &Result{}
+	if err == nil && len(calls) > 0 {
+		var response *genai.GenerateContentResponse
+		response, content, toolCalls, err = c.callTools(ctx, content, genConfig, &genai.GenerateContentResponse{
+			Candidates:    []*genai.Candidate{{Content: &genai.Content{Role: "model", Parts: calls}}},
+			UsageMetadata: usage,
+		})
+		result.ToolCalls = toolCalls.ToolCalls
+		usage = nil
+		if err == nil {
+			chunk := response.Text()
+			responseText += chunk
+			onEvent(StreamEvent{Type: EventChunk, Text: chunk})
+			usage = response.UsageMetadata
+			if metadata := groundingMetadata(response); metadata != nil {
+				grounding = metadata
+			}
+			cutOff = truncated(response)
+		}
+	}
+
+	result.setUsage(c.opts.Model, usage)
+	result.addUsage(toolCalls)
+	result.Sources = c.filterSources(sourcesFrom(grounding))
+	if err == nil && cutOff {
+		var extra *Result
+		responseText, extra = c.continueTruncated(ctx, content, genConfig, responseText, func(chunk string) {
+			onEvent(StreamEvent{Type: EventChunk, Text: chunk})
+		})
+		result.addContinuation(extra)
+	}
+	if c.opts.InlineCitations && responseText != "" {
+		appendix, warnings := verifyCitations(responseText, result.Sources)
+		if appendix != "" {
+			onEvent(StreamEvent{Type: EventChunk, Text: appendix})
+		}
+		responseText += appendix
+		result.CitationWarnings = warnings
+	}
+
+	result.Duration = time.Since(startTime)
+	result.Timings.Generation = result.Duration - result.Timings.Construction
+
+	if err != nil && responseText == "" {
+		if timedOut(ctx) {
+			return result, result.fail(fmt.Sprintf("Timed out after %s", c.opts.QueryTimeout), fmt.Errorf("%w after %s", ErrQueryTimeout, c.opts.QueryTimeout))
+		}
+		var blocked *BlockedError
+		if errors.As(err, &blocked) {
+			return result, result.fail(blocked.message(), err)
+		}
+		if errors.Is(err, errEmptyResponse) {
+			if retried, ok, err := c.withRewrite(ctx, query, func(retry *Client, rewritten string) (*Result, error) {
+				onEvent(StreamEvent{Type: EventRetry})
+				return retry.searchStream(ctx, rewritten, history, onEvent)
+			}); ok {
+				return retried, err
+			}
+			return result, result.fail("Empty stream response", fmt.Errorf("received empty stream response after retries: %w", errEmptyResponse))
+		}
+		return result, result.fail("Stream search failed", fmt.Errorf("failed to stream content after retries: %w", err))
+	}
+
+	result.Response = responseText
+	result.Thoughts = thoughts
+	result.Success = true
+	if len(history) == 0 && err == nil {
+		c.storeCached(query, result)
+	}
+	return result, nil
+}
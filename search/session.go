@@ -0,0 +1,94 @@
+package search
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/genai"
+)
+
+// Turn is one question and answer in a Session
+type Turn struct {
+	Query    string `json:"query"`
+	Response string `json:"response"`
+}
+
+// Session keeps prior turns as conversation context, so follow-up queries
+// like "what about its performance?" resolve against earlier answers.
+// Only successful turns are remembered.
+type Session struct {
+	client *Client
+
+	mu      sync.Mutex
+	turns   []Turn
+	history []*genai.Content
+}
+
+// NewSession starts an empty conversation using the client's options
+func (c *Client) NewSession() *Session {
+	return &Session{client: c}
+}
+
+// ResumeSession starts a conversation that already contains turns, such as
+// ones saved from an earlier run
+func (c *Client) ResumeSession(turns []Turn) *Session {
+	s := &Session{client: c}
+	for _, turn := range turns {
+		s.addTurn(turn)
+	}
+	return s
+}
+
+// Search asks query with the conversation so far as context
+func (s *Session) Search(ctx context.Context, query string) (*Result, error) {
+	result, err := s.client.search(ctx, query, s.snapshot())
+	s.remember(result, err)
+	return result, err
+}
+
+// SearchStream is the streaming counterpart of Search
+func (s *Session) SearchStream(ctx context.Context, query string, onEvent func(StreamEvent)) (*Result, error) {
+	result, err := s.client.searchStream(ctx, query, s.snapshot(), onEvent)
+	s.remember(result, err)
+	return result, err
+}
+
+// Turns returns the remembered questions and answers, oldest first
+func (s *Session) Turns() []Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Turn(nil), s.turns...)
+}
+
+// Reset forgets all prior turns
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns = nil
+	s.history = nil
+}
+
+func (s *Session) snapshot() []*genai.Content {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*genai.Content(nil), s.history...)
+}
+
+func (s *Session) remember(result *Result, err error) {
+	if err != nil || result == nil || !result.Success {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addTurn(Turn{Query: result.Query, Response: result.Response})
+}
+
+// addTurn appends a turn; callers other than ResumeSession hold s.mu
+func (s *Session) addTurn(turn Turn) {
+	s.turns = append(s.turns, turn)
+	s.history = append(s.history,
+		&genai.Content{Role: "user", Parts: []*genai.Part{{Text: turn.Query}}},
+		&genai.Content{Role: "model", Parts: []*genai.Part{{Text: turn.Response}}},
+	)
+}
@@ -0,0 +1,130 @@
+package search
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// SiteFilter restricts the sources of a search by domain. A domain also
+// matches its subdomains, so "go.dev" covers "pkg.go.dev".
+type SiteFilter struct {
+	// Only sources from these domains are kept, any domain if empty
+	Include []string
+	// Sources from these domains are dropped, even if included
+	Exclude []string
+}
+
+// Add parses a comma-separated list of include:<domain> and
+// exclude:<domain> entries into f. A domain without a prefix is included.
+func (f *SiteFilter) Add(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, domain, ok := strings.Cut(entry, ":")
+		if !ok {
+			kind, domain = "include", entry
+		}
+		domain = normalizeDomain(domain)
+		if domain == "" || strings.ContainsAny(domain, "/ ") {
+			return fmt.Errorf("invalid domain in %q", entry)
+		}
+		switch kind {
+		case "include":
+			f.Include = append(f.Include, domain)
+		case "exclude":
+			f.Exclude = append(f.Exclude, domain)
+		default:
+			return fmt.Errorf("unknown site filter %q (expected include:<domain> or exclude:<domain>)", kind)
+		}
+	}
+	return nil
+}
+
+func (f SiteFilter) empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// instruction tells the model which sites to search, appended to the
+// search system prompt
+func (f SiteFilter) instruction() string {
+	var b strings.Builder
+	if len(f.Include) > 0 {
+		fmt.Fprintf(&b, "\n\nOnly use sources from these sites and their subdomains: %s. If they do not cover the question, say so instead of using other sites.", strings.Join(f.Include, ", "))
+	}
+	if len(f.Exclude) > 0 {
+		fmt.Fprintf(&b, "\n\nNever use sources from these sites or their subdomains: %s.", strings.Join(f.Exclude, ", "))
+	}
+	return b.String()
+}
+
+// allows reports whether a source from domain passes the filter
+func (f SiteFilter) allows(domain string) bool {
+	if domain == "" {
+		// Nothing to match an include list against
+		return len(f.Include) == 0
+	}
+	for _, site := range f.Exclude {
+		if matchesSite(domain, site) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, site := range f.Include {
+		if matchesSite(domain, site) {
+			return true
+		}
+	}
+	return false
+}
+
+// key identifies the filter in cache keys
+func (f SiteFilter) key() string {
+	return strings.Join(f.Include, ",") + ";" + strings.Join(f.Exclude, ",")
+}
+
+// filterSources drops the sources the site filter does not allow
+func (c *Client) filterSources(sources []Source) []Source {
+	sites := c.opts.Sites
+	if sites.empty() {
+		return sources
+	}
+	var kept []Source
+	for _, source := range sources {
+		if sites.allows(sourceDomain(source)) {
+			kept = append(kept, source)
+		}
+	}
+	if dropped := len(sources) - len(kept); dropped > 0 {
+		slog.Info("Dropped sources outside site filter", "dropped", dropped, "kept", len(kept))
+	}
+	return kept
+}
+
+// sourceDomain is the domain the grounding metadata reports for source, or
+// else the host of its URI. Gemini URIs are redirects, so the reported
+// domain is the one that names the actual site.
+func sourceDomain(source Source) string {
+	if source.Domain != "" {
+		return normalizeDomain(source.Domain)
+	}
+	u, err := url.Parse(source.URI)
+	if err != nil {
+		return ""
+	}
+	return normalizeDomain(u.Hostname())
+}
+
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return strings.TrimSuffix(strings.TrimPrefix(domain, "www."), ".")
+}
+
+func matchesSite(domain, site string) bool {
+	return domain == site || strings.HasSuffix(domain, "."+site)
+}
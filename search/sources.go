@@ -0,0 +1,57 @@
+package search
+
+import "google.golang.org/genai"
+
+// Source is a web page the model used to ground its answer
+type Source struct {
+	Title   string `json:"title"`
+	URI     string `json:"uri"`
+	Domain  string `json:"domain,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// sourcesFrom extracts web sources from grounding metadata, in the order the
+// model returned them. Each source's snippet is the first response segment
+// it supports.
+func sourcesFrom(metadata *genai.GroundingMetadata) []Source {
+	if metadata == nil {
+		return nil
+	}
+
+	var sources []Source
+	chunkToSource := map[int]int{}
+	for i, chunk := range metadata.GroundingChunks {
+		if chunk == nil || chunk.Web == nil {
+			continue
+		}
+		title := chunk.Web.Title
+		if title == "" {
+			title = chunk.Web.Domain
+		}
+		chunkToSource[i] = len(sources)
+		sources = append(sources, Source{
+			Title:  title,
+			URI:    chunk.Web.URI,
+			Domain: chunk.Web.Domain,
+		})
+	}
+
+	for _, support := range metadata.GroundingSupports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		for _, index := range support.GroundingChunkIndices {
+			if s, ok := chunkToSource[int(index)]; ok && sources[s].Snippet == "" {
+				sources[s].Snippet = support.Segment.Text
+			}
+		}
+	}
+	return sources
+}
+
+func groundingMetadata(response *genai.GenerateContentResponse) *genai.GroundingMetadata {
+	if response == nil || len(response.Candidates) == 0 || response.Candidates[0] == nil {
+		return nil
+	}
+	return response.Candidates[0].GroundingMetadata
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/structure.txt
+var structureInstructionText string
+
+// Structure converts a search response into JSON matching schema, a decoded
+// JSON Schema document. Grounded search cannot be combined with structured
+// output, so this is a second call without tools that only sees the response.
+// The returned JSON has been checked against the schema.
+func (c *Client) Structure(ctx context.Context, query, response string, schema any) (json.RawMessage, error) {
+	content := []*genai.Content{{
+		Role:  "user",
+		Parts: []*genai.Part{{Text: fmt.Sprintf("Query: %s\n\nSearch Answer:\n%s", query, response)}},
+	}}
+
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:     c.opts.SafetySettings,
+		SystemInstruction:  &genai.Content{Parts: []*genai.Part{{Text: structureInstructionText}}},
+		ResponseMIMEType:   "application/json",
+		ResponseJsonSchema: schema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	var result *genai.GenerateContentResponse
+	err := c.opts.Retry.do(ctx, func(attempt int) error {
+		c.logRequest(ctx, "structure", attempt, content, genConfig)
+
+		var err error
+		result, err = c.provider.GenerateContent(ctx, c.opts.Model, content, genConfig)
+		c.logResponse(ctx, "structure", attempt, result, err)
+		if err != nil {
+			return err
+		}
+		if result.Text() == "" {
+			return errEmptyResponse
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate structured output: %w", err)
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(result.Text()), &value); err != nil {
+		return nil, fmt.Errorf("structured output is not valid JSON: %w", err)
+	}
+	if err := ValidateSchema(schema, value); err != nil {
+		return nil, fmt.Errorf("structured output does not match schema: %w", err)
+	}
+	return json.RawMessage(result.Text()), nil
+}
+
+// AddStructured fills in r.Structured for a successful result, marking the
+// result failed if the structured output could not be produced
+func (c *Client) AddStructured(ctx context.Context, r *Result, schema any) {
+	if !r.Success {
+		return
+	}
+	ctx = WithRequestID(ctx, r.ID)
+
+	structured, err := c.Structure(ctx, r.Query, r.Response, schema)
+	if err != nil {
+		r.Success = false
+		r.Error = fmt.Sprintf("Structured output failed: %v", err)
+		return
+	}
+	r.Structured = structured
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Answer styles for Options.Style
+const (
+	StyleConcise  = "concise"
+	StyleDetailed = "detailed"
+	StyleBullet   = "bullet"
+	StyleELI5     = "eli5"
+)
+
+// Styles lists the accepted Options.Style values
+var Styles = []string{StyleConcise, StyleDetailed, StyleBullet, StyleELI5}
+
+var styleInstructions = map[string]string{
+	StyleConcise:  "Answer concisely: lead with the direct answer in one or two sentences, then add only the details that matter. Skip background and caveats the question did not ask for.",
+	StyleDetailed: "Answer in depth: cover background, the main points with supporting evidence, trade-offs and notable disagreements between sources, organized under headings.",
+	StyleBullet:   "Format the whole answer as a bulleted list of short, self-contained points, grouping related points under bold labels where that helps. Do not write paragraphs.",
+	StyleELI5:     "Explain the answer as you would to a curious ten-year-old: plain words, short sentences, and an everyday analogy. Define any technical term you cannot avoid.",
+}
+
+// Output tokens allowed per requested word. Words average about 1.3
+// tokens; the rest covers Markdown and citations.
+const tokensPerWord = 2
+
+// Extra output tokens for dynamic thinking, which counts toward
+// MaxOutputTokens but has no fixed budget
+const dynamicThinkingTokens = 8192
+
+// ValidateStyle checks style against Styles; "" leaves the style to the
+// prompt
+func ValidateStyle(style string) error {
+	if style == "" {
+		return nil
+	}
+	for _, known := range Styles {
+		if style == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown style %q (expected %s)", style, strings.Join(Styles, ", "))
+}
+
+// styleInstruction returns the directives for Options.Style and
+// Options.MaxWords, appended to the search system prompt
+func (c *Client) styleInstruction() string {
+	var b strings.Builder
+	if text, ok := styleInstructions[c.opts.Style]; ok {
+		b.WriteString("\n\n" + text)
+	}
+	if c.opts.MaxWords > 0 {
+		fmt.Fprintf(&b, "\n\nKeep the answer under %d words.", c.opts.MaxWords)
+	}
+	return b.String()
+}
+
+// maxOutputTokens caps a search response to fit Options.MaxWords, leaving
+// room for the thinking budget. It returns 0, for no cap, without MaxWords.
+func (c *Client) maxOutputTokens() int32 {
+	if c.opts.MaxWords <= 0 {
+		return 0
+	}
+	tokens := int32(c.opts.MaxWords) * tokensPerWord
+	switch budget := c.opts.ThinkingBudget; {
+	case budget > 0:
+		tokens += budget
+	case budget < 0:
+		tokens += dynamicThinkingTokens
+	}
+	return tokens
+}
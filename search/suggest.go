@@ -0,0 +1,73 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/suggest.txt
+var suggestInstructionText string
+
+// MaxSuggestions bounds the follow-up queries AddSuggestions proposes
+const MaxSuggestions = 5
+
+var suggestionsSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"queries": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+	},
+	Required: []string{"queries"},
+}
+
+// AddSuggestions asks the model for follow-up queries to a successful
+// result and stores them in SuggestedQueries. A failed call is logged and
+// leaves them unset.
+func (c *Client) AddSuggestions(ctx context.Context, r *Result) {
+	if !r.Success {
+		return
+	}
+	ctx = WithRequestID(ctx, r.ID)
+
+	content := []*genai.Content{{
+		Role: "user",
+		Parts: []*genai.Part{{Text: fmt.Sprintf("Query: %s\n\nMaximum queries: %d\n\nSearch Answer:\n%s",
+			r.Query, MaxSuggestions, r.Response)}},
+	}}
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: c.withLanguage(suggestInstructionText)}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    suggestionsSchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	response, err := c.generate(ctx, "suggest", content, genConfig)
+	if err != nil {
+		slog.InfoContext(ctx, "Suggestions failed", "query", r.Query, "error", err)
+		return
+	}
+	r.addResponseUsage(c.opts.Model, response.UsageMetadata)
+
+	var suggested struct {
+		Queries []string `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(response.Text()), &suggested); err != nil {
+		slog.InfoContext(ctx, "Suggestions are not valid JSON", "query", r.Query, "error", err)
+		return
+	}
+	r.SuggestedQueries = nil
+	for _, query := range suggested.Queries {
+		if query = strings.TrimSpace(query); query != "" && len(r.SuggestedQueries) < MaxSuggestions {
+			r.SuggestedQueries = append(r.SuggestedQueries, query)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// Maximum response length passed to the fallback summary prompt
+const fallbackSummaryInputLimit = 6000
+
+// SummaryFailedText replaces the summary of a result whose summary failed
+const SummaryFailedText = "Summary generation failed"
+
+// Summary is a short digest of a search response
+type Summary struct {
+	Text string
+	// Whether the simplified fallback prompt produced the summary
+	Fallback bool
+}
+
+// Summarize condenses a search response into a 1-3 sentence summary. When
+// the regular summary fails and Options.SummaryFallback is set, it retries
+// once with a simpler instruction and a truncated response.
+func (c *Client) Summarize(ctx context.Context, query, response string) (*Summary, error) {
+	instruction := c.renderPrompt(c.summaryPrompt, summaryInstructionText, query)
+	text, err := c.requestSummary(ctx, query, response, instruction)
+	if err == nil {
+		return &Summary{Text: text}, nil
+	}
+	if !c.opts.SummaryFallback {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Retrying summary with fallback prompt", "query", query, "error", err)
+	if len(response) > fallbackSummaryInputLimit {
+		cut := fallbackSummaryInputLimit
+		for cut > 0 && !utf8.RuneStart(response[cut]) {
+			cut--
+		}
+		response = response[:cut]
+	}
+	text, fallbackErr := c.requestSummary(ctx, query, response, summaryFallbackInstructionText)
+	if fallbackErr != nil {
+		return &Summary{Fallback: true}, fmt.Errorf("%w (fallback also failed: %v)", err, fallbackErr)
+	}
+	return &Summary{Text: text, Fallback: true}, nil
+}
+
+// AddSummary summarizes a successful result in place, recording how long it took
+func (c *Client) AddSummary(ctx context.Context, r *Result) {
+	if !r.Success {
+		return
+	}
+	ctx = WithRequestID(ctx, r.ID)
+
+	summaryStart := time.Now()
+	summary, err := c.Summarize(ctx, r.Query, r.Response)
+	r.Timings.Summary = time.Since(summaryStart)
+	r.SetSummary(summary, err)
+}
+
+// SetSummary applies the outcome of Summarize to the result
+func (r *Result) SetSummary(summary *Summary, err error) {
+	if summary != nil {
+		r.SummaryFallback = summary.Fallback
+	}
+	if err != nil {
+		slog.Info("Summary generation failed", "request_id", r.ID, "query", r.Query, "error", err)
+		r.Summary = SummaryFailedText
+		return
+	}
+	r.Summary = summary.Text
+}
+
+func (c *Client) requestSummary(ctx context.Context, query, response, instruction string) (string, error) {
+	parts := []*genai.Part{
+		{Text: fmt.Sprintf("Query: %s\n\nSearch Results:\n%s", query, response)},
+	}
+	content := []*genai.Content{{
+		Role:  "user",
+		Parts: parts,
+	}}
+
+	budget := c.opts.ThinkingBudget
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: c.withLanguage(instruction)}}},
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &budget,
+		},
+	}
+
+	ctx, span := c.startSpan(ctx, "summary")
+	start := time.Now()
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+
+	var result *genai.GenerateContentResponse
+	err := c.opts.Retry.do(ctx, func(attempt int) error {
+		c.logRequest(ctx, "summary", attempt, content, genConfig)
+
+		var err error
+		result, err = c.provider.GenerateContent(ctx, c.opts.Model, content, genConfig)
+		c.logResponse(ctx, "summary", attempt, result, err)
+		if err != nil {
+			return err
+		}
+		if result.Text() == "" {
+			return errEmptyResponse
+		}
+		return nil
+	}, nil)
+	var usage *genai.GenerateContentResponseUsageMetadata
+	if result != nil {
+		usage = result.UsageMetadata
+	}
+	c.endSpan(ctx, span, "summary", start, usage, err)
+
+	if err != nil && timedOut(ctx) {
+		return "", fmt.Errorf("summary %w after %s", ErrQueryTimeout, c.opts.QueryTimeout)
+	}
+	if errors.Is(err, errEmptyResponse) {
+		return "", fmt.Errorf("received empty summary after retries")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to generate summary after retries: %w", err)
+	}
+
+	return result.Text(), nil
+}
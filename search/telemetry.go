@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genai"
+)
+
+// Spans and metrics go to the global OpenTelemetry providers, which drop
+// them until the application installs an SDK
+const instrumentationName = "github.com/qiushiyan/gemini-search/search"
+
+var tracer = otel.Tracer(instrumentationName)
+
+var instruments struct {
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+	tokens   metric.Int64Counter
+	retries  metric.Int64Counter
+}
+
+func init() {
+	meter := otel.Meter(instrumentationName)
+	instruments.requests, _ = meter.Int64Counter("gosearch.requests",
+		metric.WithDescription("Searches and summaries by kind and outcome, ok or an error code"),
+		metric.WithUnit("{request}"))
+	instruments.duration, _ = meter.Float64Histogram("gosearch.request.duration",
+		metric.WithDescription("Duration of searches and summaries, including retries"),
+		metric.WithUnit("s"))
+	instruments.tokens, _ = meter.Int64Counter("gosearch.tokens",
+		metric.WithDescription("Tokens used by type: prompt, output or thinking"),
+		metric.WithUnit("{token}"))
+	instruments.retries, _ = meter.Int64Counter("gosearch.retries",
+		metric.WithDescription("API calls retried after a failed attempt"),
+		metric.WithUnit("{retry}"))
+}
+
+// startSpan starts the span for one search or summary of kind
+func (c *Client) startSpan(ctx context.Context, kind string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "gosearch."+kind, trace.WithAttributes(
+		attribute.String("gosearch.provider", c.provider.Name()),
+		attribute.String("gosearch.model", c.opts.Model),
+		attribute.String("gosearch.request_id", RequestID(ctx)),
+	))
+}
+
+// endSpan ends a span from startSpan and records the call's metrics. usage
+// is nil when no tokens were spent, such as for a cached result.
+func (c *Client) endSpan(ctx context.Context, span trace.Span, kind string, start time.Time, usage *genai.GenerateContentResponseUsageMetadata, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = string(Code(err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.String("gosearch.outcome", outcome))
+
+	attrs := []attribute.KeyValue{
+		attribute.String("kind", kind),
+		attribute.String("model", c.opts.Model),
+	}
+	instruments.requests.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("outcome", outcome))...))
+	instruments.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	if usage != nil {
+		for _, count := range []struct {
+			kind   string
+			tokens int32
+		}{
+			{"prompt", usage.PromptTokenCount},
+			{"output", usage.CandidatesTokenCount},
+			{"thinking", usage.ThoughtsTokenCount},
+		} {
+			instruments.tokens.Add(ctx, int64(count.tokens), metric.WithAttributes(append(attrs, attribute.String("type", count.kind))...))
+			span.SetAttributes(attribute.Int("gosearch.tokens."+count.kind, int(count.tokens)))
+		}
+	}
+	span.End()
+}
+
+// recordRetry notes on the current span that attempt is about to be made
+// after err
+func recordRetry(ctx context.Context, attempt int, err error) {
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+		attribute.Int("attempt", attempt),
+		attribute.String("error", err.Error()),
+	))
+	instruments.retries.Add(ctx, 1)
+}
+
+// resultUsage returns the tokens r spent, or nil if it was served from cache
+func resultUsage(r *Result) *genai.GenerateContentResponseUsageMetadata {
+	if r == nil || r.Cached {
+		return nil
+	}
+	return &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     r.PromptTokens,
+		CandidatesTokenCount: r.OutputTokens,
+		ThoughtsTokenCount:   r.ThinkingTokens,
+	}
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Tools for Options.Tools
+const (
+	ToolSearch = "search"
+	ToolURL    = "url"
+	ToolCode   = "code"
+)
+
+// Tools lists the accepted Options.Tools values
+var Tools = []string{ToolSearch, ToolURL, ToolCode}
+
+// DefaultTools are the Options.Tools of DefaultOptions
+var DefaultTools = []string{ToolSearch, ToolURL}
+
+var toolDefinitions = map[string]*genai.Tool{
+	ToolSearch: {GoogleSearch: &genai.GoogleSearch{}},
+	ToolURL:    {URLContext: &genai.URLContext{}},
+	ToolCode:   {CodeExecution: &genai.ToolCodeExecution{}},
+}
+
+const noSearchInstruction = "\n\nWeb search is not available for this answer. Answer from your own knowledge, and say so where the information may be out of date."
+
+// ValidateTools checks tools against Tools
+func ValidateTools(tools []string) error {
+	for _, tool := range tools {
+		if _, ok := toolDefinitions[tool]; !ok {
+			return fmt.Errorf("unknown tool %q (expected %s or none)", tool, strings.Join(Tools, ", "))
+		}
+	}
+	return nil
+}
+
+// tools returns the definitions of Options.Tools, in the order of Tools,
+// followed by the declarations of Options.LocalTools
+func (c *Client) tools() []*genai.Tool {
+	var defs []*genai.Tool
+	for _, name := range Tools {
+		for _, tool := range c.opts.Tools {
+			if tool == name {
+				defs = append(defs, toolDefinitions[name])
+				break
+			}
+		}
+	}
+	if len(c.opts.LocalTools) > 0 {
+		functions := &genai.Tool{}
+		for _, tool := range c.opts.LocalTools {
+			functions.FunctionDeclarations = append(functions.FunctionDeclarations, tool.Declaration)
+		}
+		defs = append(defs, functions)
+	}
+	return defs
+}
+
+// toolsKey is Options.Tools in the order of Tools, for cache keys
+func (c *Client) toolsKey() string {
+	var names []string
+	for _, name := range Tools {
+		for _, tool := range c.opts.Tools {
+			if tool == name {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// toolInstruction tells the model to answer without searching when the
+// search tool is off
+func (c *Client) toolInstruction() string {
+	for _, tool := range c.opts.Tools {
+		if tool == ToolSearch {
+			return ""
+		}
+	}
+	return noSearchInstruction
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/url.txt
+var urlInstructionText string
+
+var urlTools = []*genai.Tool{
+	{URLContext: &genai.URLContext{}},
+}
+
+// SummarizeURLs fetches the pages at urls with the URL context tool and
+// summarizes them. The result has the same shape as a search, with the
+// joined URLs as its query and the pages that were read as its sources.
+func (c *Client) SummarizeURLs(ctx context.Context, urls []string) (*Result, error) {
+	ctx, id := ensureRequestID(ctx)
+	startTime := time.Now()
+	result := &Result{
+		ID:        id,
+		Query:     strings.Join(urls, " "),
+		Timestamp: startTime,
+	}
+
+	var b strings.Builder
+	b.WriteString("Summarize these pages:\n")
+	for _, u := range urls {
+		fmt.Fprintf(&b, "- %s\n", u)
+	}
+	content := []*genai.Content{{Role: "user", Parts: []*genai.Part{{Text: b.String()}}}}
+
+	budget := c.opts.ThinkingBudget
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: c.withLanguage(urlInstructionText)}}},
+		Tools:             urlTools,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &budget,
+		},
+	}
+	result.Timings.Construction = time.Since(startTime)
+
+	slog.InfoContext(ctx, "Summarizing URLs", "urls", urls)
+	response, err := c.generate(ctx, "url", content, genConfig)
+	result.Duration = time.Since(startTime)
+	result.Timings.Generation = result.Duration - result.Timings.Construction
+	if err != nil {
+		message := "URL summary failed"
+		if errors.Is(err, ErrQueryTimeout) {
+			message = fmt.Sprintf("Timed out after %s", c.opts.QueryTimeout)
+		}
+		return result, result.fail(message, fmt.Errorf("failed to summarize URLs: %w", err))
+	}
+	result.setUsage(c.opts.Model, response.UsageMetadata)
+
+	sources, read := urlSources(response, urls)
+	if !read {
+		return result, result.fail("No page could be retrieved", fmt.Errorf("none of the %d URLs could be retrieved", len(urls)))
+	}
+	result.Response = strings.TrimSpace(response.Text())
+	result.Sources = sources
+	result.Success = true
+	return result, nil
+}
+
+// urlSources lists the pages the model read, falling back to the requested
+// URLs for providers that report no retrieval metadata. It reports false
+// when metadata shows that no page was retrieved.
+func urlSources(response *genai.GenerateContentResponse, urls []string) ([]Source, bool) {
+	var candidate *genai.Candidate
+	if len(response.Candidates) > 0 {
+		candidate = response.Candidates[0]
+	}
+	if candidate == nil || candidate.URLContextMetadata == nil || len(candidate.URLContextMetadata.URLMetadata) == 0 {
+		var sources []Source
+		for _, u := range urls {
+			sources = addSource(sources, u, "")
+		}
+		return sources, true
+	}
+
+	var sources []Source
+	for _, metadata := range candidate.URLContextMetadata.URLMetadata {
+		if metadata.URLRetrievalStatus != genai.URLRetrievalStatusSuccess {
+			slog.Info("URL could not be retrieved", "url", metadata.RetrievedURL, "status", metadata.URLRetrievalStatus)
+			continue
+		}
+		sources = addSource(sources, metadata.RetrievedURL, "")
+	}
+	for _, source := range sourcesFrom(candidate.GroundingMetadata) {
+		sources = addSource(sources, source.URI, source.Title)
+	}
+	return sources, len(sources) > 0
+}
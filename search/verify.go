@@ -0,0 +1,227 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+//go:embed prompts/claims.txt
+var claimsInstructionText string
+
+//go:embed prompts/verify.txt
+var verifyInstructionText string
+
+// MaxVerifyClaims bounds the claims AddVerification checks, each of which
+// is a grounded search
+const MaxVerifyClaims = 8
+
+// Verdict is how well the evidence found for a claim supports it
+type Verdict string
+
+const (
+	VerdictSupported   Verdict = "supported"
+	VerdictUnsupported Verdict = "unsupported"
+	VerdictUncertain   Verdict = "uncertain"
+)
+
+// Verification is the claim-by-claim fact check of an answer
+type Verification struct {
+	Claims []Claim `json:"claims"`
+	// Why the check could not be finished, when it failed
+	Error string `json:"error,omitempty"`
+}
+
+// Claim is a factual statement from an answer with the verdict on it and
+// the sources of the evidence it was judged on
+type Claim struct {
+	Text        string   `json:"claim"`
+	Verdict     Verdict  `json:"verdict"`
+	Confidence  float64  `json:"confidence"`
+	Explanation string   `json:"explanation,omitempty"`
+	Sources     []Source `json:"sources,omitempty"`
+}
+
+// Unsupported counts the claims the evidence contradicts
+func (v *Verification) Unsupported() int {
+	n := 0
+	for _, claim := range v.Claims {
+		if claim.Verdict == VerdictUnsupported {
+			n++
+		}
+	}
+	return n
+}
+
+var claimsSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"claims": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+	},
+	Required: []string{"claims"},
+}
+
+var verdictsSchema = &genai.Schema{
+	Type: genai.TypeArray,
+	Items: &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"claim":       {Type: genai.TypeInteger},
+			"verdict":     {Type: genai.TypeString, Enum: []string{string(VerdictSupported), string(VerdictUnsupported), string(VerdictUncertain)}},
+			"confidence":  {Type: genai.TypeNumber},
+			"explanation": {Type: genai.TypeString},
+		},
+		Required: []string{"claim", "verdict", "confidence", "explanation"},
+	},
+}
+
+// AddVerification fact-checks a successful result: it extracts the
+// answer's factual claims, searches for each with at most workers in
+// flight, and judges every claim against what its search found. The
+// tokens and cost of these calls are added to the result. A failed check
+// is recorded in Verification.Error without failing the result.
+func (c *Client) AddVerification(ctx context.Context, r *Result, workers int) {
+	if !r.Success {
+		return
+	}
+	ctx = WithRequestID(ctx, r.ID)
+
+	verification, err := c.verify(ctx, r, workers)
+	if err != nil {
+		slog.InfoContext(ctx, "Verification failed", "query", r.Query, "error", err)
+		verification.Error = err.Error()
+	}
+	r.Verification = verification
+}
+
+func (c *Client) verify(ctx context.Context, r *Result, workers int) (*Verification, error) {
+	verification := &Verification{}
+	claims, err := c.extractClaims(ctx, r)
+	if err != nil || len(claims) == 0 {
+		return verification, err
+	}
+	slog.InfoContext(ctx, "Verifying claims", "query", r.Query, "claims", len(claims))
+
+	queries := make([]string, len(claims))
+	for i, claim := range claims {
+		queries[i] = "Find evidence for or against this claim: " + claim
+	}
+	evidence := c.searchAll(ctx, queries, workers, func(int, *Result) {})
+	for i, claim := range claims {
+		r.addUsage(&evidence[i])
+		verification.Claims = append(verification.Claims, Claim{
+			Text:    claim,
+			Verdict: VerdictUncertain,
+			Sources: evidence[i].Sources,
+		})
+		if !evidence[i].Success {
+			verification.Claims[i].Explanation = "No evidence: " + evidence[i].Error
+		}
+	}
+	return verification, c.judgeClaims(ctx, r, evidence, verification)
+}
+
+// extractClaims asks the model for the checkable claims of r's answer
+func (c *Client) extractClaims(ctx context.Context, r *Result) ([]string, error) {
+	content := []*genai.Content{{
+		Role: "user",
+		Parts: []*genai.Part{{Text: fmt.Sprintf("Query: %s\n\nMaximum claims: %d\n\nSearch Answer:\n%s",
+			r.Query, MaxVerifyClaims, r.Response)}},
+	}}
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: claimsInstructionText}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    claimsSchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	response, err := c.generate(ctx, "claims", content, genConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract claims: %w", err)
+	}
+	r.addResponseUsage(c.opts.Model, response.UsageMetadata)
+
+	var extracted struct {
+		Claims []string `json:"claims"`
+	}
+	if err := json.Unmarshal([]byte(response.Text()), &extracted); err != nil {
+		return nil, fmt.Errorf("claims are not valid JSON: %w", err)
+	}
+	var claims []string
+	for _, claim := range extracted.Claims {
+		if claim = strings.TrimSpace(claim); claim != "" {
+			claims = append(claims, claim)
+		}
+	}
+	if len(claims) > MaxVerifyClaims {
+		claims = claims[:MaxVerifyClaims]
+	}
+	return claims, nil
+}
+
+// judgeClaims fills in the verdicts of verification's claims from the
+// searches in evidence, with a single call. Claims without evidence stay
+// uncertain.
+func (c *Client) judgeClaims(ctx context.Context, r *Result, evidence []Result, verification *Verification) error {
+	var b strings.Builder
+	for i, claim := range verification.Claims {
+		fmt.Fprintf(&b, "## Claim %d\n\n%s\n\n### Evidence\n\n", i+1, claim.Text)
+		if !evidence[i].Success {
+			b.WriteString("No evidence was found.\n\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(evidence[i].Response))
+		for _, source := range evidence[i].Sources {
+			fmt.Fprintf(&b, "- %s - %s\n", source.Title, source.URI)
+		}
+		b.WriteString("\n")
+	}
+	content := []*genai.Content{{Role: "user", Parts: []*genai.Part{{Text: b.String()}}}}
+
+	var noThinking int32
+	genConfig := &genai.GenerateContentConfig{
+		SafetySettings:    c.opts.SafetySettings,
+		SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: c.withLanguage(verifyInstructionText)}}},
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    verdictsSchema,
+		ThinkingConfig: &genai.ThinkingConfig{
+			ThinkingBudget: &noThinking,
+		},
+	}
+
+	response, err := c.generate(ctx, "verify", content, genConfig)
+	if err != nil {
+		return fmt.Errorf("failed to judge claims: %w", err)
+	}
+	r.addResponseUsage(c.opts.Model, response.UsageMetadata)
+
+	var verdicts []struct {
+		Claim       int     `json:"claim"`
+		Verdict     Verdict `json:"verdict"`
+		Confidence  float64 `json:"confidence"`
+		Explanation string  `json:"explanation"`
+	}
+	if err := json.Unmarshal([]byte(response.Text()), &verdicts); err != nil {
+		return fmt.Errorf("claim verdicts are not valid JSON: %w", err)
+	}
+	for _, v := range verdicts {
+		i := v.Claim - 1
+		if i < 0 || i >= len(verification.Claims) || !evidence[i].Success {
+			continue
+		}
+		claim := &verification.Claims[i]
+		claim.Verdict = v.Verdict
+		claim.Confidence = v.Confidence
+		claim.Explanation = strings.TrimSpace(v.Explanation)
+	}
+	return nil
+}
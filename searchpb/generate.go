@@ -0,0 +1,5 @@
+// Package searchpb holds the gRPC API served with -grpc, generated from
+// search.proto
+package searchpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative search.proto
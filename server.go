@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// searchRequest is the body accepted by POST /search and POST /search/stream
+type searchRequest struct {
+	Query   string   `json:"query"`
+	Queries []string `json:"queries"`
+	// Overrides the flag defaults when set
+	IncludeSummary *bool `json:"include_summary"`
+}
+
+type server struct {
+	config *Config
+	client *search.Client
+}
+
+// runServer serves the search API on addr until the listener fails
+func runServer(config *Config, client *search.Client) error {
+	s := &server{config: config, client: client}
+
+	slog.Info("Starting search server", "addr", config.serve)
+	fmt.Fprintf(os.Stderr, "Serving search API on %s\n", config.serve)
+	return http.ListenAndServe(config.serve, s.routes())
+}
+
+// routes is the search API, shared by -serve and the daemon
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search", s.handleSearchGet)
+	mux.HandleFunc("POST /search", s.handleSearchPost)
+	mux.HandleFunc("POST /search/stream", s.handleSearchStream)
+	return mux
+}
+
+func (s *server) handleSearchGet(w http.ResponseWriter, r *http.Request) {
+	queries := r.URL.Query()["q"]
+	req := searchRequest{}
+	if len(queries) == 1 {
+		req.Query = queries[0]
+	} else {
+		req.Queries = queries
+	}
+	if value := r.URL.Query().Get("include_summary"); value != "" {
+		include := value == "true" || value == "1"
+		req.IncludeSummary = &include
+	}
+	s.search(w, r, req)
+}
+
+func (s *server) handleSearchPost(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeSearchRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err, "Invalid request")
+		return
+	}
+	s.search(w, r, req)
+}
+
+func decodeSearchRequest(r *http.Request) (searchRequest, error) {
+	var req searchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, fmt.Errorf("failed to decode request body: %w", err)
+	}
+	return req, nil
+}
+
+// includeSummary applies the CLI's smart default: on for several queries
+func (s *server) includeSummary(req searchRequest) bool {
+	if req.IncludeSummary != nil {
+		return *req.IncludeSummary
+	}
+	if s.config.includeSummaryExplicit {
+		return s.config.includeSummary
+	}
+	return len(req.Queries) > 1
+}
+
+// search answers with the same JSON as -json: a single result for one
+// query, or the multi-query result for several
+func (s *server) search(w http.ResponseWriter, r *http.Request, req searchRequest) {
+	if req.Query == "" && len(req.Queries) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("query is required"), "Invalid request")
+		return
+	}
+	if req.Query != "" && len(req.Queries) > 0 {
+		writeError(w, http.StatusBadRequest, errors.New("use either query or queries, not both"), "Invalid request")
+		return
+	}
+
+	config := *s.config
+	config.includeSummary = s.includeSummary(req)
+
+	if len(req.Queries) > 0 {
+		multiResult, err := processMultipleQueries(r.Context(), req.Queries, &config, s.client, nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err, "Multi-query search failed")
+			return
+		}
+		writeJSON(w, http.StatusOK, multiResult)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.timeout)
+	defer cancel()
+	ctx = search.WithRequestID(ctx, search.NewRequestID())
+
+	var waitClassification func() []search.Classification
+	if config.classify {
+		waitClassification = startClassification(ctx, []string{req.Query}, s.client)
+	}
+
+	result, err := s.client.Search(ctx, req.Query)
+	if waitClassification != nil {
+		if classifications := waitClassification(); len(classifications) == 1 && result != nil {
+			result.SetClassification(classifications[0])
+		}
+	}
+	if err != nil {
+		slog.Info("Search failed", "query", req.Query, "error", err)
+		history.record(config.model, result)
+		writeJSON(w, http.StatusBadGateway, result)
+		return
+	}
+	if config.includeSummary {
+		s.client.AddSummary(ctx, result)
+	}
+	if config.schema != nil {
+		s.client.AddStructured(ctx, result, config.schema)
+	}
+	history.record(config.model, result)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleSearchStream sends the response as server-sent events: "chunk" and
+// "retry" events while it arrives, then a final "result" event carrying the
+// same JSON as -json
+func (s *server) handleSearchStream(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeSearchRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err, "Invalid request")
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("query is required"), "Invalid request")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported by this connection"), "Stream search failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data any) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			slog.Error("Failed to encode stream event", "event", event, "error", err)
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.timeout)
+	defer cancel()
+	ctx = search.WithRequestID(ctx, search.NewRequestID())
+
+	result, err := s.client.SearchStream(ctx, req.Query, func(event search.StreamEvent) {
+		switch event.Type {
+		case search.EventChunk:
+			send("chunk", map[string]string{"text": event.Text})
+		case search.EventRetry:
+			send("retry", map[string]int{"attempt": event.Attempt})
+		}
+	})
+	if err == nil && s.includeSummary(req) {
+		s.client.AddSummary(ctx, result)
+	}
+	history.record(s.config.model, result)
+	send("result", result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error, context string) {
+	writeJSON(w, status, ErrorOutput{
+		Success: false,
+		Error:   err.Error(),
+		Context: context,
+	})
+}
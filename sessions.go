@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func validateSessionName(name string) error {
+	if !sessionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid session name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// sessionInfo summarizes one named session for "sessions list"
+type sessionInfo struct {
+	Name     string    `json:"name"`
+	Searches int       `json:"searches"`
+	Started  time.Time `json:"started"`
+	Updated  time.Time `json:"updated"`
+	CostUSD  float64   `json:"cost_usd"`
+}
+
+func (h *historyStore) sessions() ([]sessionInfo, error) {
+	rows, err := h.db.Query(`SELECT session, COUNT(*), MIN(created_at), MAX(created_at), SUM(cost_usd)
+		FROM searches WHERE session != '' GROUP BY session ORDER BY MAX(created_at) DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []sessionInfo
+	for rows.Next() {
+		var s sessionInfo
+		// Aggregates lose the column type, so the driver returns text
+		var started, updated string
+		if err := rows.Scan(&s.Name, &s.Searches, &started, &updated, &s.CostUSD); err != nil {
+			return nil, fmt.Errorf("failed to read sessions: %w", err)
+		}
+		s.Started, _ = parseHistoryTime(started)
+		s.Updated, _ = parseHistoryTime(updated)
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// parseHistoryTime parses a timestamp as the sqlite driver stores it,
+// formatted by time.Time.String
+func parseHistoryTime(value string) (time.Time, error) {
+	return time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", value)
+}
+
+// sessionEntries returns the searches recorded under name, oldest first
+func (h *historyStore) sessionEntries(name string) ([]historyEntry, error) {
+	return h.query("WHERE session = ? ORDER BY created_at", name)
+}
+
+// sessionConversation returns the successful turns of the named session,
+// oldest first, and the ID of its latest turn
+func (h *historyStore) sessionConversation(name string) ([]search.Turn, string, error) {
+	entries, err := h.sessionEntries(name)
+	if err != nil {
+		return nil, "", err
+	}
+	var turns []search.Turn
+	var lastID string
+	for _, e := range entries {
+		if !e.Success {
+			continue
+		}
+		turns = append(turns, search.Turn{Query: e.Query, Response: e.Response})
+		lastID = e.ID
+	}
+	if len(turns) == 0 {
+		return nil, "", fmt.Errorf("no session named %q with successful searches", name)
+	}
+	return turns, lastID, nil
+}
+
+// runSessionsCommand handles "sessions list" and "sessions show <name>"
+func runSessionsCommand(args []string) error {
+	fs := flag.NewFlagSet("sessions", flag.ExitOnError)
+	dbPath := fs.String("history-db", "", "History database (default ~/.local/share/go-search/history.db)")
+	outputJSON := fs.Bool("json", false, "Output in JSON format")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s sessions [options] list | show <name>\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	store, err := openHistory(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	rest := fs.Args()
+	switch {
+	case len(rest) == 0 || rest[0] == "list" && len(rest) == 1:
+		sessions, err := store.sessions()
+		if err != nil {
+			return err
+		}
+		return listSessions(sessions, *outputJSON)
+	case rest[0] == "show" && len(rest) == 2:
+		entries, err := store.sessionEntries(rest[1])
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no session named %q", rest[1])
+		}
+		return showSession(entries, *outputJSON)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown sessions command %q", strings.Join(rest, " "))
+	}
+}
+
+func listSessions(sessions []sessionInfo, outputJSON bool) error {
+	if outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sessions)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSEARCHES\tSTARTED\tUPDATED\tCOST")
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t$%.4f\n", s.Name, s.Searches, s.Started.Local().Format("2006-01-02 15:04"),
+			s.Updated.Local().Format("2006-01-02 15:04"), s.CostUSD)
+	}
+	return w.Flush()
+}
+
+func showSession(entries []historyEntry, outputJSON bool) error {
+	if outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := showHistoryEntry(e, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
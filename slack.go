@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Slack's limits on the text of a section block and on the blocks of a
+// message
+const (
+	slackSectionChars = 3000
+	slackMaxBlocks    = 50
+	slackHeaderChars  = 150
+)
+
+// Sources listed under each answer in a Slack message
+const slackMaxSources = 5
+
+// slackMessage is the body of a Slack incoming webhook post. The detail of
+// an answer goes in an attachment, which Slack collapses behind "Show
+// more" when it is long.
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Blocks      []slackBlock      `json:"blocks"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackAttachment struct {
+	Color    string   `json:"color,omitempty"`
+	Text     string   `json:"text"`
+	MrkdwnIn []string `json:"mrkdwn_in"`
+}
+
+// deliverSlack posts payload to the -slack-webhook incoming webhook, if
+// set. Like -webhook, failures are reported on stderr but never change the
+// exit status.
+func deliverSlack(ctx context.Context, config *Config, payload any) {
+	if config.slackWebhook == "" {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	if err := postWebhook(ctx, config.slackWebhook, "", slackPayload(payload)); err != nil {
+		slog.Error("Slack delivery failed", "error", err)
+		fmt.Fprintf(os.Stderr, "Warning: Slack delivery failed: %v\n", err)
+	}
+}
+
+// slackPayload formats a result of any kind of run as a Slack message
+func slackPayload(payload any) *slackMessage {
+	switch p := payload.(type) {
+	case *search.Result:
+		return slackResult(p)
+	case *MultiSearchResult:
+		succeeded := 0
+		for _, result := range p.Results {
+			if result.Success {
+				succeeded++
+			}
+		}
+		title := fmt.Sprintf("%d/%d queries succeeded in %s", succeeded, len(p.Results), p.TotalTime.Round(time.Second))
+		runs := make([]slackRun, len(p.Results))
+		for i := range p.Results {
+			runs[i] = slackRun{label: p.Results[i].Query, result: &p.Results[i]}
+		}
+		return slackRuns(title, runs)
+	case *SweepResult:
+		runs := make([]slackRun, len(p.Runs))
+		for i, run := range p.Runs {
+			runs[i] = slackRun{label: fmt.Sprintf("Thinking budget %d", run.ThinkingBudget), result: &p.Runs[i].Result}
+		}
+		return slackRuns("Thinking sweep: "+p.Query, runs)
+	case *CompareResult:
+		runs := make([]slackRun, len(p.Runs))
+		for i, run := range p.Runs {
+			runs[i] = slackRun{label: run.Model, result: &p.Runs[i].Result}
+		}
+		return slackRuns("Comparison: "+p.Query, runs)
+	}
+	return &slackMessage{Text: "go-search finished"}
+}
+
+// slackResult shows one answer: its summary, or the start of the answer
+// without one, its sources, and the full answer as collapsible detail
+func slackResult(r *search.Result) *slackMessage {
+	message := &slackMessage{
+		Text:   shorten(r.Query),
+		Blocks: []slackBlock{slackHeader(r.Query)},
+	}
+	if !r.Success {
+		message.Blocks = append(message.Blocks, slackSection(":x: "+r.Error))
+		return message
+	}
+
+	lead := r.Summary
+	if lead == "" {
+		lead = r.Response
+	}
+	message.Blocks = append(message.Blocks, slackSection(slackMarkdown(lead)))
+	if sources := slackSources(r.Sources); sources != nil {
+		message.Blocks = append(message.Blocks, *sources)
+	}
+	if r.Summary != "" {
+		message.Attachments = []slackAttachment{{
+			Color:    "#4285f4",
+			Text:     truncateRunes(slackMarkdown(r.Response), slackSectionChars),
+			MrkdwnIn: []string{"text"},
+		}}
+	}
+	return message
+}
+
+// slackRun is one query, budget or model of a run with several results
+type slackRun struct {
+	label  string
+	result *search.Result
+}
+
+// slackRuns lists each run with its summary or error, as long as the
+// message has room for it
+func slackRuns(title string, runs []slackRun) *slackMessage {
+	message := &slackMessage{
+		Text:   shorten(title),
+		Blocks: []slackBlock{slackHeader(title)},
+	}
+	for i, run := range runs {
+		// Each run takes a section, a sources line and a divider
+		if len(message.Blocks)+3 > slackMaxBlocks-1 {
+			message.Blocks = append(message.Blocks, slackContext(fmt.Sprintf("…and %d more", len(runs)-i)))
+			break
+		}
+		if !run.result.Success {
+			message.Blocks = append(message.Blocks, slackSection(fmt.Sprintf(":x: *%s*\n%s", slackEscape(run.label), slackEscape(run.result.Error))))
+		} else {
+			text := run.result.Summary
+			if text == "" {
+				text = run.result.Response
+			}
+			message.Blocks = append(message.Blocks, slackSection(fmt.Sprintf("*%s*\n%s", slackEscape(run.label), slackMarkdown(text))))
+			if sources := slackSources(run.result.Sources); sources != nil {
+				message.Blocks = append(message.Blocks, *sources)
+			}
+		}
+		if i < len(runs)-1 {
+			message.Blocks = append(message.Blocks, slackBlock{Type: "divider"})
+		}
+	}
+	return message
+}
+
+func slackHeader(text string) slackBlock {
+	return slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: truncateRunes(strings.Join(strings.Fields(text), " "), slackHeaderChars)}}
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncateRunes(text, slackSectionChars)}}
+}
+
+func slackContext(text string) slackBlock {
+	return slackBlock{Type: "context", Elements: []*slackText{{Type: "mrkdwn", Text: text}}}
+}
+
+// slackSources links the first sources of an answer in a context line
+func slackSources(sources []search.Source) *slackBlock {
+	if !showSources || len(sources) == 0 {
+		return nil
+	}
+	var links []string
+	for i, source := range sources {
+		if i == slackMaxSources {
+			links = append(links, fmt.Sprintf("+%d more", len(sources)-i))
+			break
+		}
+		links = append(links, fmt.Sprintf("<%s|%s>", source.URI, slackEscape(source.Title)))
+	}
+	block := slackContext(strings.Join(links, " · "))
+	return &block
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// slackMarkdown converts the Markdown the model writes to Slack's mrkdwn:
+// headings and bold become *bold*, italics _italic_, and links <url|text>
+func slackMarkdown(text string) string {
+	lines := strings.Split(slackEscape(text), "\n")
+	for i, line := range lines {
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			line = "**" + strings.ReplaceAll(m[2], "**", "") + "**"
+		}
+		line = mdItalic.ReplaceAllString(line, "${1}_${2}_")
+		line = mdBold.ReplaceAllString(line, "*$1*")
+		line = mdLink.ReplaceAllString(line, "<$2|$1>")
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// BatchStats aggregates the results of a multi-query run. Durations and
+// response lengths cover the queries that succeeded.
+type BatchStats struct {
+	MeanDuration   time.Duration `json:"mean_duration"`
+	MedianDuration time.Duration `json:"median_duration"`
+	Fastest        *QueryTime    `json:"fastest,omitempty"`
+	Slowest        *QueryTime    `json:"slowest,omitempty"`
+	// Response length in characters
+	MeanResponseChars   int   `json:"mean_response_chars"`
+	MedianResponseChars int   `json:"median_response_chars"`
+	TotalTokens         int32 `json:"total_tokens"`
+	// Failed queries by error code
+	Failures map[search.ErrorCode]int `json:"failures,omitempty"`
+}
+
+type QueryTime struct {
+	Query    string        `json:"query"`
+	Duration time.Duration `json:"duration"`
+}
+
+// batchStats computes the statistics of results, counting tokens once per
+// search as the batch totals do
+func batchStats(m *MultiSearchResult) BatchStats {
+	stats := BatchStats{TotalTokens: m.PromptTokens + m.OutputTokens + m.ThinkingTokens}
+	var durations []time.Duration
+	var lengths []int
+	for _, r := range m.Results {
+		if !r.Success {
+			if stats.Failures == nil {
+				stats.Failures = make(map[search.ErrorCode]int)
+			}
+			code := r.ErrorCode
+			if code == "" {
+				code = search.CodeUnknown
+			}
+			stats.Failures[code]++
+			continue
+		}
+		durations = append(durations, r.Duration)
+		lengths = append(lengths, len([]rune(r.Response)))
+		if stats.Fastest == nil || r.Duration < stats.Fastest.Duration {
+			stats.Fastest = &QueryTime{Query: r.Query, Duration: r.Duration}
+		}
+		if stats.Slowest == nil || r.Duration > stats.Slowest.Duration {
+			stats.Slowest = &QueryTime{Query: r.Query, Duration: r.Duration}
+		}
+	}
+	stats.MeanDuration, stats.MedianDuration = meanMedian(durations)
+	stats.MeanResponseChars, stats.MedianResponseChars = meanMedian(lengths)
+	return stats
+}
+
+// meanMedian returns 0s for no values; the median of an even count is the
+// mean of the middle two
+func meanMedian[T time.Duration | int](values []T) (T, T) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sorted := slices.Sorted(slices.Values(values))
+	var sum T
+	for _, v := range sorted {
+		sum += v
+	}
+	mid := len(sorted) / 2
+	median := sorted[mid]
+	if len(sorted)%2 == 0 {
+		median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sum / T(len(sorted)), median
+}
+
+// printStats ends text output of a batch with its STATISTICS section
+func printStats(stats BatchStats) {
+	fmt.Printf("## %s\n", labels.Statistics)
+	if stats.Fastest != nil {
+		fmt.Printf("Duration: mean %s, median %s\n", stats.MeanDuration.Round(time.Millisecond), stats.MedianDuration.Round(time.Millisecond))
+		fmt.Printf("Fastest: %s (%s)\n", stats.Fastest.Query, stats.Fastest.Duration.Round(time.Millisecond))
+		fmt.Printf("Slowest: %s (%s)\n", stats.Slowest.Query, stats.Slowest.Duration.Round(time.Millisecond))
+		fmt.Printf("Response length: mean %d, median %d characters\n", stats.MeanResponseChars, stats.MedianResponseChars)
+	}
+	if stats.TotalTokens > 0 {
+		fmt.Printf("Total tokens: %d\n", stats.TotalTokens)
+	}
+	if len(stats.Failures) > 0 {
+		fmt.Printf("Failures:")
+		for i, code := range slices.Sorted(maps.Keys(stats.Failures)) {
+			if i > 0 {
+				fmt.Printf(",")
+			}
+			fmt.Printf(" %s %d", code, stats.Failures[code])
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("\n")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// streamEmitter presents a streaming search as it happens: as text for
+// people, or as NDJSON events for programs with -stream -json
+type streamEmitter interface {
+	// begin is called before the search starts
+	begin(query, requestID string)
+	// event is called for each chunk, thought and retry of the stream
+	event(event search.StreamEvent)
+	// streamed is called once the response has finished streaming
+	streamed(result *search.Result)
+	// summary is called when a summary has been added to the result
+	summary(result *search.Result)
+	// done is called with the final result, after any summary
+	done(result *search.Result)
+}
+
+func newStreamEmitter(config *Config) streamEmitter {
+	switch config.format {
+	case formatJSON, formatJSONL:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		return &ndjsonEmitter{encoder: encoder}
+	}
+	return &textEmitter{quiet: config.quiet}
+}
+
+// textEmitter prints the response between a query header and a separator
+// line, with the reasoning dimmed before it. With -quiet only the response
+// is printed.
+type textEmitter struct {
+	thinking bool
+	quiet    bool
+}
+
+func (e *textEmitter) begin(query, _ string) {
+	if !e.quiet {
+		fmt.Printf("\n=== %s ===\n", query)
+	}
+}
+
+func (e *textEmitter) event(event search.StreamEvent) {
+	switch event.Type {
+	case search.EventThought:
+		if !e.thinking {
+			fmt.Print(dim("Thinking:\n"))
+			e.thinking = true
+		}
+		fmt.Print(dim(event.Text))
+	case search.EventChunk:
+		if e.thinking {
+			fmt.Print("\n\n")
+			e.thinking = false
+		}
+		fmt.Print(event.Text)
+	case search.EventRetry:
+		fmt.Fprintf(os.Stderr, "\n[Retrying...]\n")
+	}
+}
+
+func (e *textEmitter) streamed(result *search.Result) {
+	for _, warning := range result.CitationWarnings {
+		fmt.Fprintf(os.Stderr, "\nCitation warning: %s", warning)
+	}
+	if result.Success {
+		fmt.Println()
+		printSources(result.Sources)
+	}
+	if !e.quiet {
+		fmt.Printf("\n%s\n", "─────────────────────────────────────────────────────────────────────────────")
+	}
+}
+
+func (e *textEmitter) summary(result *search.Result) {
+	fmt.Printf("\n## %s\n%s\n", labels.Summary, answerText(result.Summary))
+}
+
+func (e *textEmitter) done(*search.Result) {}
+
+// ndjsonEvent is one line of -stream -json output
+type ndjsonEvent struct {
+	Type      string         `json:"type"`
+	Query     string         `json:"query,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Attempt   int            `json:"attempt,omitempty"`
+	Result    *search.Result `json:"result,omitempty"`
+}
+
+// ndjsonEmitter writes one JSON event per line: start, then chunk, thought
+// and retry events as they arrive, summary if one is generated, and done
+// with the full result
+type ndjsonEmitter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEmitter) emit(event ndjsonEvent) {
+	if err := e.encoder.Encode(event); err != nil {
+		slog.Error("Failed to write stream event", "type", event.Type, "error", err)
+	}
+}
+
+func (e *ndjsonEmitter) begin(query, requestID string) {
+	e.emit(ndjsonEvent{Type: "start", Query: query, RequestID: requestID})
+}
+
+func (e *ndjsonEmitter) event(event search.StreamEvent) {
+	switch event.Type {
+	case search.EventChunk, search.EventThought:
+		e.emit(ndjsonEvent{Type: string(event.Type), Text: event.Text})
+	case search.EventRetry:
+		e.emit(ndjsonEvent{Type: string(event.Type), Attempt: event.Attempt})
+	}
+}
+
+func (e *ndjsonEmitter) streamed(*search.Result) {}
+
+func (e *ndjsonEmitter) summary(result *search.Result) {
+	e.emit(ndjsonEvent{Type: "summary", Text: result.Summary})
+}
+
+func (e *ndjsonEmitter) done(result *search.Result) {
+	e.emit(ndjsonEvent{Type: "done", Result: result})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+type SweepRun struct {
+	ThinkingBudget int32         `json:"thinking_budget"`
+	Result         search.Result `json:"result"`
+}
+
+type SweepResult struct {
+	Query     string           `json:"query"`
+	Runs      []SweepRun       `json:"runs"`
+	TotalTime time.Duration    `json:"total_time"`
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`
+	ErrorCode search.ErrorCode `json:"error_code,omitempty"`
+}
+
+func runThinkingSweep(ctx context.Context, query string, config *Config, client *search.Client) *SweepResult {
+	startTime := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, config.timeout)
+	defer cancel()
+
+	runs := runConcurrently(ctx, len(config.sweepBudgets), config.workers, config.timeoutGrace, false, func(ctx context.Context, index int) SweepRun {
+		budget := config.sweepBudgets[index]
+		result, err := client.WithThinkingBudget(budget).Search(ctx, query)
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		slog.Info("Sweep run completed", "thinking_budget", budget, "success", result.Success, "duration", result.Duration)
+		return SweepRun{ThinkingBudget: budget, Result: *result}
+	}, func(index int, started bool) SweepRun {
+		return SweepRun{
+			ThinkingBudget: config.sweepBudgets[index],
+			Result:         cancelledResult(ctx, query, started),
+		}
+	})
+
+	successCount := 0
+	for _, run := range runs {
+		if run.Result.Success {
+			successCount++
+		}
+	}
+
+	sweep := &SweepResult{
+		Query:     query,
+		Runs:      runs,
+		TotalTime: time.Since(startTime),
+		Success:   successCount == len(runs),
+	}
+	if !sweep.Success {
+		sweep.Error = fmt.Sprintf("Completed %d/%d sweep runs successfully", successCount, len(runs))
+		results := make([]search.Result, len(runs))
+		for i, run := range runs {
+			results[i] = run.Result
+		}
+		sweep.ErrorCode = batchCode(results)
+	}
+	return sweep
+}
+
+func (textRenderer) sweep(s *SweepResult) error {
+	fmt.Printf("## THINKING BUDGET SWEEP\n")
+	fmt.Printf("Query: %s\n\n", s.Query)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BUDGET\tSTATUS\tLATENCY\tPROMPT\tTHINKING\tOUTPUT")
+	for _, run := range s.Runs {
+		status := "ok"
+		if !run.Result.Success {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\n",
+			run.ThinkingBudget,
+			status,
+			run.Result.Duration.Round(time.Millisecond),
+			run.Result.PromptTokens,
+			run.Result.ThinkingTokens,
+			run.Result.OutputTokens)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n## RESPONSES\n\n")
+
+	for _, run := range s.Runs {
+		fmt.Printf("=== thinking budget %d ===\n", run.ThinkingBudget)
+		if run.Result.Success {
+			fmt.Printf("%s\n", run.Result.Response)
+		} else {
+			fmt.Printf("Status: FAILED - %s\n", run.Result.Error)
+		}
+		fmt.Printf("\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A tag at the end of a queries file line, such as #lang=go
+var queryTagPattern = regexp.MustCompile(`^#([A-Za-z0-9_.-]+)=(\S*)$`)
+
+// parseTag splits a -tag key=value
+func parseTag(value string) (string, string, error) {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("tag must be key=value, got %q", value)
+	}
+	return key, strings.TrimSpace(val), nil
+}
+
+// noCacheDirective in a queries file line searches that query afresh
+const noCacheDirective = "!nocache"
+
+// splitNoCache removes the !nocache directive from a queries file line,
+// reporting whether it was there
+func splitNoCache(line string) (string, bool) {
+	fields := strings.Fields(line)
+	kept := slices.DeleteFunc(slices.Clone(fields), func(field string) bool { return field == noCacheDirective })
+	if len(kept) == len(fields) || len(kept) == 0 {
+		return line, false
+	}
+	return strings.Join(kept, " "), true
+}
+
+// splitQueryTags removes the #key=value tags that end a queries file line
+func splitQueryTags(line string) (string, map[string]string) {
+	fields := strings.Fields(line)
+	end := len(fields)
+	for end > 0 && queryTagPattern.MatchString(fields[end-1]) {
+		end--
+	}
+	if end == len(fields) || end == 0 {
+		return line, nil
+	}
+	tags := make(map[string]string)
+	for _, field := range fields[end:] {
+		match := queryTagPattern.FindStringSubmatch(field)
+		tags[match[1]] = match[2]
+	}
+	return strings.Join(fields[:end], " "), tags
+}
+
+// tagsFor returns the -tag tags merged with those given for query in a
+// queries file, which take precedence
+func (c *Config) tagsFor(query string) map[string]string {
+	if len(c.tags) == 0 && len(c.queryTags[query]) == 0 {
+		return nil
+	}
+	tags := maps.Clone(c.tags)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	maps.Copy(tags, c.queryTags[query])
+	return tags
+}
+
+// formatTags joins tags as key=value pairs sorted by key
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return strings.Join(pairs, ";")
+}
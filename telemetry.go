@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// How long exporting may take when the program exits
+const telemetryShutdownTimeout = 5 * time.Second
+
+// Set by setupTelemetry; flushes spans and metrics before exiting
+var shutdownTelemetry = func() {}
+
+// setupTelemetry exports traces and metrics over OTLP/HTTP when
+// OTEL_EXPORTER_OTLP_ENDPOINT is set. The exporters read the other standard
+// OTEL_* variables themselves, such as OTEL_EXPORTER_OTLP_HEADERS.
+func setupTelemetry(ctx context.Context) error {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return nil
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "go-search")),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+
+	traceExporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter), sdktrace.WithResource(res))
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)), sdkmetric.WithResource(res))
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	slog.Info("Exporting telemetry", "endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+
+	shutdownTelemetry = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryShutdownTimeout)
+		defer cancel()
+		if err := errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx)); err != nil {
+			slog.Error("Failed to export telemetry", "error", err)
+		}
+	}
+	return nil
+}
+
+// exit flushes telemetry and -output-file and exits with code
+func exit(code int) {
+	if err := closeOutputFile(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to finish output file: %v\n", err)
+	}
+	shutdownTelemetry()
+	os.Exit(code)
+}
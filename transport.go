@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Header names whose values should never appear in logs
+var sensitiveHeaders = []string{"authorization", "proxy-authorization", "cookie", "api-key", "token", "secret"}
+
+// headerTransport attaches user-supplied headers to every outgoing request
+type headerTransport struct {
+	headers http.Header
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range t.headers {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+func parseHeader(value string) (string, string, error) {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid header %q (expected key=value)", value)
+	}
+	if strings.ContainsAny(key, " \t\r\n:") {
+		return "", "", fmt.Errorf("invalid header name %q", key)
+	}
+	if strings.ContainsAny(val, "\r\n") {
+		return "", "", fmt.Errorf("invalid value for header %q", key)
+	}
+	return key, strings.TrimSpace(val), nil
+}
+
+func isSensitiveHeader(key string) bool {
+	lower := strings.ToLower(key)
+	for _, s := range sensitiveHeaders {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeaders returns a loggable copy of headers with sensitive values masked
+func redactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if isSensitiveHeader(key) {
+			redacted[key] = "[REDACTED]"
+		} else {
+			redacted[key] = strings.Join(values, ", ")
+		}
+	}
+	return redacted
+}
+
+// TLS versions accepted by -tls-min-version
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseProxy checks a -proxy URL. Go's transport speaks HTTP, HTTPS and
+// SOCKS5 proxies.
+func parseProxy(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", value)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q (expected http, https or socks5)", u.Scheme)
+}
+
+// loadCACerts returns the system roots plus the PEM certificates in path,
+// for proxies and firewalls that re-sign TLS traffic with their own CA
+func loadCACerts(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// newHTTPClient returns the client for API requests, or nil for the
+// default one when no header, proxy, TLS option, key rotation or
+// -attempt-timeout is set.
+// Without -proxy the HTTPS_PROXY, HTTP_PROXY and NO_PROXY variables apply.
+func newHTTPClient(config *Config) *http.Client {
+	if len(config.headers) == 0 && config.proxy == nil && config.caCerts == nil && config.tlsMinVersion == "" && config.keyRing == nil && config.attemptTimeout == 0 {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.proxy != nil {
+		transport.Proxy = http.ProxyURL(config.proxy)
+		slog.Info("Using proxy", "proxy", config.proxy.Redacted())
+	}
+	if config.caCerts != nil || config.tlsMinVersion != "" {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    config.caCerts,
+			MinVersion: tlsVersions[config.tlsMinVersion],
+		}
+	}
+
+	// Only the wait for the response is bounded, so a streamed answer can
+	// take as long as it needs once it has started
+	transport.ResponseHeaderTimeout = config.attemptTimeout
+
+	var base http.RoundTripper = transport
+	if config.keyRing != nil {
+		base = &keyTransport{keys: config.keyRing, base: base}
+	}
+	if len(config.headers) > 0 {
+		base = &headerTransport{headers: config.headers, base: base}
+	}
+	return &http.Client{Transport: base}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/qiushiyan/gemini-search/search"
+	"golang.org/x/term"
+)
+
+// queryStatus is one row of the live progress view
+type queryStatus struct {
+	query   string
+	started time.Time
+	result  *search.Result
+}
+
+// runTUI runs a multi-query batch with a live progress row per query, then
+// opens a pager over the results. The pager is skipped when stdin is not a
+// terminal or the run was interrupted.
+func runTUI(ctx context.Context, config *Config, client *search.Client) (*MultiSearchResult, error) {
+	rows := make([]queryStatus, len(config.queries))
+	for i, query := range config.queries {
+		rows[i].query = query
+	}
+
+	events := make(chan queryEvent, 2*len(config.queries))
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		showProgress(rows, events, stop)
+	}()
+
+	multiResult, err := processMultipleQueries(ctx, config.queries, config, client, events)
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Err() == nil && isTerminal(os.Stdin) {
+		if err := pageResults(multiResult.Results); err != nil {
+			return nil, err
+		}
+	}
+	successful := 0
+	for _, result := range multiResult.Results {
+		if result.Success {
+			successful++
+		}
+	}
+	fmt.Printf("🏁 COMPLETED: %d/%d queries in %s\n", successful, len(multiResult.Results), multiResult.TotalTime.Round(time.Millisecond))
+	return multiResult, nil
+}
+
+// showProgress redraws rows in place as events arrive until stop is closed
+func showProgress(rows []queryStatus, events <-chan queryEvent, stop <-chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	apply := func(event queryEvent) {
+		if event.Result == nil {
+			rows[event.Index].started = time.Now()
+		} else {
+			rows[event.Index].result = event.Result
+		}
+	}
+
+	drawn := false
+	for frame := 0; ; frame++ {
+		select {
+		case event := <-events:
+			apply(event)
+			continue
+		case <-ticker.C:
+		case <-stop:
+			for len(events) > 0 {
+				apply(<-events)
+			}
+			drawProgress(rows, frame, drawn)
+			fmt.Println()
+			return
+		}
+		drawProgress(rows, frame, drawn)
+		drawn = true
+	}
+}
+
+func drawProgress(rows []queryStatus, frame int, redraw bool) {
+	width := terminalWidth()
+	var b strings.Builder
+	if redraw {
+		// Move back to the first row to overwrite the previous frame
+		fmt.Fprintf(&b, "\033[%dA", len(rows))
+	}
+	for _, row := range rows {
+		var icon, detail string
+		switch {
+		case row.result != nil && row.result.Success:
+			icon, detail = "✓", row.result.Duration.Round(time.Millisecond).String()
+		case row.result != nil:
+			icon, detail = "✗", row.result.Error
+		case !row.started.IsZero():
+			icon, detail = spinnerFrames[frame%len(spinnerFrames)], time.Since(row.started).Round(100*time.Millisecond).String()
+		default:
+			icon, detail = "·", "waiting"
+		}
+		line := fmt.Sprintf("%s %s  %s", icon, truncateQuery(row.query, max(10, width/2)), detail)
+		fmt.Fprintf(&b, "\r\033[K%s\n", truncateQuery(line, width))
+	}
+	fmt.Print(b.String())
+}
+
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
+
+// pageResults shows one result at a time on the alternate screen. Keys:
+// j/k or arrows scroll, space/b page, n/p or Tab switch results, q quits.
+func pageResults(results []search.Result) error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to start pager: %w", err)
+	}
+	fmt.Print("\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		term.Restore(fd, state)
+	}()
+
+	current, offset := 0, 0
+	key := make([]byte, 8)
+	for {
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil || width <= 0 || height <= 2 {
+			width, height = 80, 24
+		}
+		lines := resultLines(&results[current], width)
+		view := height - 2
+		offset = max(0, min(offset, len(lines)-view))
+
+		var b strings.Builder
+		b.WriteString("\033[H\033[2J")
+		header := fmt.Sprintf("[%d/%d] %s", current+1, len(results), results[current].Query)
+		fmt.Fprintf(&b, "\033[7m%s\033[0m\r\n", padRight(truncateQuery(header, width), width))
+		for i := offset; i < offset+view && i < len(lines); i++ {
+			b.WriteString(lines[i] + "\r\n")
+		}
+		for i := len(lines) - offset; i < view; i++ {
+			b.WriteString("\r\n")
+		}
+		footer := fmt.Sprintf("j/k scroll  space/b page  n/p result  q quit  (%d/%d)", min(offset+view, len(lines)), len(lines))
+		fmt.Fprintf(&b, "\033[2m%s\033[0m", truncateQuery(footer, width))
+		fmt.Print(b.String())
+
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return nil
+		}
+		switch string(key[:n]) {
+		case "q", "\x1b", "\x03":
+			return nil
+		case "j", "\x1b[B", "\r":
+			offset++
+		case "k", "\x1b[A":
+			offset--
+		case " ", "\x1b[6~":
+			offset += view
+		case "b", "\x1b[5~":
+			offset -= view
+		case "g":
+			offset = 0
+		case "G":
+			offset = len(lines)
+		case "n", "\t", "\x1b[C":
+			current, offset = (current+1)%len(results), 0
+		case "p", "\x1b[Z", "\x1b[D":
+			current, offset = (current+len(results)-1)%len(results), 0
+		}
+		offset = max(0, offset)
+	}
+}
+
+// resultLines lays out a result for the pager, wrapped to width
+func resultLines(r *search.Result, width int) []string {
+	var text strings.Builder
+	switch {
+	case !r.Success:
+		fmt.Fprintf(&text, "FAILED: %s\n", r.Error)
+	default:
+		fmt.Fprintf(&text, "%s · %d prompt / %d output tokens\n\n", r.Duration.Round(time.Millisecond), r.PromptTokens, r.OutputTokens)
+		if r.Summary != "" {
+			fmt.Fprintf(&text, "## %s\n%s\n\n## %s\n", labels.Summary, r.Summary, labels.DetailedResponse)
+		}
+		text.WriteString(strings.TrimSpace(r.Response) + "\n")
+		if showSources && len(r.Sources) > 0 {
+			fmt.Fprintf(&text, "\n## %s\n", labels.Sources)
+			for i, source := range r.Sources {
+				fmt.Fprintf(&text, "%d. %s - %s\n", i+1, source.Title, source.URI)
+			}
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(text.String(), "\n"), "\n") {
+		lines = append(lines, wrapLine(line, width)...)
+	}
+	return lines
+}
+
+// wrapLine splits line into pieces of at most width runes, breaking at
+// spaces where possible
+func wrapLine(line string, width int) []string {
+	var lines []string
+	for utf8.RuneCountInString(line) > width {
+		runes := []rune(line)
+		cut := width
+		for i := width; i > width/2; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, string(runes[:cut]))
+		line = strings.TrimLeft(string(runes[cut:]), " ")
+	}
+	return append(lines, line)
+}
+
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
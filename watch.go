@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// runWatch searches query every config.watch until ctx is cancelled. The
+// first answer is printed in full; after that a run is only printed (and
+// sent to -webhook) when its answer is less similar to the last printed one
+// than config.watchThreshold.
+func runWatch(ctx context.Context, config *Config, client *search.Client) {
+	render := newRenderer(config)
+	var previous *search.Result
+
+	ticker := time.NewTicker(config.watch)
+	defer ticker.Stop()
+	for run := 1; ; run++ {
+		runCtx := search.WithRequestID(ctx, search.NewRequestID())
+		result, err := client.Search(runCtx, config.query)
+		if ctx.Err() != nil {
+			return
+		}
+		history.record(config.model, result)
+
+		switch {
+		case err != nil:
+			slog.Error("Watch search failed", "query", config.query, "run", run, "error", err)
+		case previous == nil:
+			render.result(result)
+			deliverWebhook(runCtx, config, result)
+			deliverSlack(runCtx, config, result)
+			previous = result
+		default:
+			similarity := responseSimilarity(previous.Response, result.Response)
+			slog.Info("Watch search completed", "run", run, "similarity", fmt.Sprintf("%.2f", similarity))
+			if similarity >= config.watchThreshold {
+				break
+			}
+			if config.format == formatText && config.quiet {
+				fmt.Fprintf(os.Stderr, "%s: answer changed (%.0f%% similar)\n", result.Timestamp.Format(time.DateTime), similarity*100)
+				fmt.Print(diffLines(previous.Response, result.Response))
+			} else if config.format == formatText {
+				fmt.Printf("\n=== %s: answer changed (%.0f%% similar) ===\n", result.Timestamp.Format(time.DateTime), similarity*100)
+				fmt.Print(diffLines(previous.Response, result.Response))
+				fmt.Printf("%s\n", "─────────────────────────────────────────────────────────────────────────────")
+			} else {
+				render.result(result)
+			}
+			deliverWebhook(runCtx, config, result)
+			deliverSlack(runCtx, config, result)
+			previous = result
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// responseSimilarity is the Dice coefficient of the word pairs in a and b:
+// 1 for the same wording, 0 when no two consecutive words are shared.
+// Rephrasing between runs keeps most pairs, new facts do not.
+func responseSimilarity(a, b string) float64 {
+	pairsA, pairsB := wordPairs(a), wordPairs(b)
+	total := 0
+	for _, n := range pairsA {
+		total += n
+	}
+	for _, n := range pairsB {
+		total += n
+	}
+	if total == 0 {
+		return 1
+	}
+
+	shared := 0
+	for pair, n := range pairsA {
+		shared += min(n, pairsB[pair])
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+func wordPairs(text string) map[string]int {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+	pairs := make(map[string]int)
+	for i := 1; i < len(words); i++ {
+		pairs[words[i-1]+" "+words[i]]++
+	}
+	return pairs
+}
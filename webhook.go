@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/qiushiyan/gemini-search/search"
+)
+
+// Header carrying the HMAC-SHA256 of the body when -webhook-secret is set
+const webhookSignatureHeader = "X-Go-Search-Signature"
+
+const (
+	webhookAttempts = 3
+	webhookTimeout  = 10 * time.Second
+	// Doubled for each later retry
+	webhookRetryDelay = time.Second
+)
+
+// deliverWebhook POSTs payload as JSON to -webhook, if set. Failures are
+// reported on stderr but never change the exit status of the search.
+func deliverWebhook(ctx context.Context, config *Config, payload any) {
+	if config.webhook == "" {
+		return
+	}
+	// Deliver what finished even if Ctrl-C cancelled the searches
+	ctx = context.WithoutCancel(ctx)
+
+	if err := postWebhook(ctx, config.webhook, config.webhookSecret, payload); err != nil {
+		slog.Error("Webhook delivery failed", "url", config.webhook, "error", err)
+		fmt.Fprintf(os.Stderr, "Warning: webhook delivery failed: %v\n", err)
+	}
+}
+
+func postWebhook(ctx context.Context, url, secret string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	var signature string
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		status, err := sendWebhook(ctx, client, url, body, signature)
+		if err == nil {
+			slog.Info("Webhook delivered", "url", url, "status", status, "attempt", attempt)
+			return nil
+		}
+		// Client errors other than rate limits will not succeed on retry
+		retryable := status == 0 || status == http.StatusTooManyRequests || status >= 500
+		if !retryable || attempt == webhookAttempts {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+
+		slog.Info("Retrying webhook delivery", "url", url, "attempt", attempt+1, "delay", delay, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// sendWebhook makes one delivery attempt, returning the response status or
+// 0 if no response arrived
+func sendWebhook(ctx context.Context, client *http.Client, url string, body []byte, signature string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-search")
+	if id := search.RequestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+	if signature != "" {
+		req.Header.Set(webhookSignatureHeader, signature)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}